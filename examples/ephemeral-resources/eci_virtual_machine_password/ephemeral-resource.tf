@@ -0,0 +1,14 @@
+ephemeral "eci_virtual_machine_password" "my_password" {
+  length=24
+}
+
+resource "eci_virtual_machine" "my_virtual_machine" {
+  name="my-vm-1"
+  instance_type_id="d0ba1aed-1414-4388-9c2a-9083ae3154d2"
+  always_on=false
+  username="elice"
+  password_wo=ephemeral.eci_virtual_machine_password.my_password.result
+  password_wo_version=1
+  on_init_script=""
+  dr=false
+}
@@ -6,10 +6,10 @@ terraform {
   }
 }
 
+# the access token is read from ECI_API_TOKEN or ~/.config/eci/credentials
 provider "eci" {
-  api_endpoint     = "https://portal.elice.cloud/api"
-  api_access_token = "u_Zb0eS2Orcu9Pv8EBfdf9D9aQiANHnqCsNt_Hy3TBIA"
-  zone_id          = "cb67250d-0050-44fa-9872-c8dd7fb9e614"
+  api_endpoint = "https://portal.elice.cloud/api"
+  zone_id      = "cb67250d-0050-44fa-9872-c8dd7fb9e614"
 }
 
 data "eci_block_storage_image" "ubuntu2204" {
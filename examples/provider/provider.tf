@@ -1,5 +1,18 @@
+# The access token is read from the ECI_API_TOKEN environment variable, or
+# else from the `default` profile of ~/.config/eci/credentials.
 provider "eci" {
   api_endpoint = "https://portal.elice.cloud/api"
-  api_access_token = "u_Zb0eS2Orcu9Pv8EBfdf9D9aQiANHnqCsNt_Hy3TBIA"
-  zone_id="cb67250d-0050-44fa-9872-c8dd7fb9e614"
-}
\ No newline at end of file
+  zone_id      = "cb67250d-0050-44fa-9872-c8dd7fb9e614"
+
+  # merged into the tags of every resource, which may override them
+  default_tags {
+    tags = {
+      "team" = "infra"
+    }
+  }
+
+  # tags written by other tooling, which are left alone
+  ignore_tags {
+    key_prefixes = ["billing:"]
+  }
+}
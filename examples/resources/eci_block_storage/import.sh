@@ -0,0 +1,2 @@
+# A block storage can be imported by its id
+terraform import eci_block_storage.my_block_storage 00000000-0000-0000-0000-000000000000
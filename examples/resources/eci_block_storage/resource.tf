@@ -7,4 +7,9 @@ resource "eci_block_storage" "my_block_storage" {
   tags = {
     "created-by": "terraform"
   }
+
+  # copying a large image may take longer than the default of 20 minutes
+  timeouts {
+    create = "1h"
+  }
 }
@@ -0,0 +1,2 @@
+# A block storage snapshot can be imported by its id
+terraform import eci_block_storage_snapshot.my_block_storage_snapshot 00000000-0000-0000-0000-000000000000
@@ -0,0 +1,2 @@
+# A network interface can be imported by its id
+terraform import eci_network_interface.my_network_interface 00000000-0000-0000-0000-000000000000
@@ -0,0 +1,2 @@
+# A public ip can be imported by its id
+terraform import eci_public_ip.my_public_ip 00000000-0000-0000-0000-000000000000
@@ -0,0 +1,2 @@
+# A subnet can be imported by its id
+terraform import eci_subnet.my_subnet 00000000-0000-0000-0000-000000000000
@@ -0,0 +1,2 @@
+# A virtual machine can be imported by its id
+terraform import eci_virtual_machine.my_virtual_machine 00000000-0000-0000-0000-000000000000
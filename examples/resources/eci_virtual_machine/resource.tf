@@ -3,9 +3,13 @@ resource "eci_virtual_machine" "my_virtual_machine" {
   instance_type_id="d0ba1aed-1414-4388-9c2a-9083ae3154d2"
   always_on=false
   username="elice"
-  password="secretpassword1!"
+  # The password is never stored in the state. Terraform before 1.11 takes
+  # password instead of password_wo and password_wo_version.
+  password_wo="secretpassword1!"
+  password_wo_version=1
   on_init_script="#!/bin/bash\necho 'Hello, Elice!' > /home/elice/hello.txt\nchmod 644 /home/elice/hello.txt"
   dr=false
+  power_state="running"
   tags = {
     "created-by": "terraform"
   }
@@ -0,0 +1,2 @@
+# A virtual machine allocation can be imported by its id
+terraform import eci_virtual_machine_allocation.my_vm_allocation 00000000-0000-0000-0000-000000000000
@@ -0,0 +1,2 @@
+# A virtual network can be imported by its id
+terraform import eci_virtual_network.my_virtual_network 00000000-0000-0000-0000-000000000000
@@ -6,9 +6,9 @@ terraform {
   }
 }
 
+# the access token is read from ECI_API_TOKEN or ~/.config/eci/credentials
 provider "eci" {
   api_endpoint = "https://portal.elice.cloud/api"
-  api_access_token = "ucGKWnD5OfS3PfQ79PR6dHmwRN3Ia18FpcxzIuBM6vX8" // replace it with your user access token
   zone_id="cb67250d-0050-44fa-9872-c8dd7fb9e614"
 }
 
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/google/uuid"
@@ -41,6 +43,9 @@ type ResourceBlockStorageDeleteResponse struct {
 }
 
 func (api *APIClient) PostBlockStorage(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
 	name string,
 	imageId *string,
 	snapshotId *string,
@@ -48,11 +53,18 @@ func (api *APIClient) PostBlockStorage(
 	dr bool,
 	tags map[string]string,
 ) (*ResourceBlockStoragePostResponse, error) {
+	organizationId, err := api.OrganizationId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetHeader(IdempotencyKeyHeader, idempotencyKey).
 		SetResult(&ResourceBlockStoragePostResponse{}).
 		SetBody(map[string]interface{}{
-			"zone_id":         api.ZoneId,
-			"organization_id": api.OrganizationId,
+			"zone_id":         zoneId,
+			"organization_id": organizationId,
 			"name":            name,
 			"image_id":        imageId,
 			"snapshot_id":     snapshotId,
@@ -65,8 +77,11 @@ func (api *APIClient) PostBlockStorage(
 	return handleAPIResponse[ResourceBlockStoragePostResponse](resp, err)
 }
 
-func (api *APIClient) GetBlockStorage(id string) (*ResourceBlockStorageGetResponse, error) {
+func (api *APIClient) GetBlockStorage(
+	ctx context.Context, id string,
+) (*ResourceBlockStorageGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceBlockStorageGetResponse{}).
 		Get(fmt.Sprintf("%s/user/resource/storage/block_storage/%s", api.pathPrefix, id))
 
@@ -74,20 +89,21 @@ func (api *APIClient) GetBlockStorage(id string) (*ResourceBlockStorageGetRespon
 }
 
 func (api *APIClient) GetBlockStorages(
+	ctx context.Context,
 	filterAttachedMachineId *string,
-) ([]ResourceBlockStorageGetResponse, error) {
+	filterNameIlike *string,
+) iter.Seq2[ResourceBlockStorageGetResponse, error] {
 	params := map[string]string{}
 	setStrIfNotNil(params, "filter_attached_machine_id", filterAttachedMachineId)
+	setStrIfNotNil(params, "filter_name_ilike", filterNameIlike)
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]ResourceBlockStorageGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/resource/storage/block_storage", api.pathPrefix))
-
-	return handleListAPIResponse[ResourceBlockStorageGetResponse](resp, err)
+	return paginate[ResourceBlockStorageGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/resource/storage/block_storage", api.pathPrefix), params,
+	)
 }
 
 func (api *APIClient) PatchBlockStorage(
+	ctx context.Context,
 	id string, namePtr *string, attachedMachineIdPtr **string, tagsPtr *map[string]string,
 ) (*ResourceBlockStoragePatchResponse, error) {
 	params := map[string]interface{}{}
@@ -103,6 +119,7 @@ func (api *APIClient) PatchBlockStorage(
 	}
 
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceBlockStoragePatchResponse{}).
 		SetBody(params).
 		Patch(fmt.Sprintf("%s/user/resource/storage/block_storage/%s", api.pathPrefix, id))
@@ -110,8 +127,11 @@ func (api *APIClient) PatchBlockStorage(
 	return handleAPIResponse[ResourceBlockStoragePatchResponse](resp, err)
 }
 
-func (api *APIClient) DeleteBlockStorage(id string) (*ResourceBlockStorageDeleteResponse, error) {
+func (api *APIClient) DeleteBlockStorage(
+	ctx context.Context, id string,
+) (*ResourceBlockStorageDeleteResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceBlockStorageDeleteResponse{}).
 		Delete(fmt.Sprintf("%s/user/resource/storage/block_storage/%s", api.pathPrefix, id))
 
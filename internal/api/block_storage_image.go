@@ -1,8 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
-	"strconv"
+	"iter"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,9 +21,11 @@ type ResourceBlockStorageImageGetResponse struct {
 }
 
 func (api *APIClient) GetBlockStorageImage(
+	ctx context.Context,
 	id string,
 ) (*ResourceBlockStorageImageGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceBlockStorageImageGetResponse{}).
 		Get(fmt.Sprintf("%s/user/infra/block_storage_image/%s", api.pathPrefix, id))
 
@@ -30,19 +33,13 @@ func (api *APIClient) GetBlockStorageImage(
 }
 
 func (api *APIClient) GetBlockStorageImages(
-	filterNameIlike *string, skip int, count int,
-) ([]ResourceBlockStorageImageGetResponse, error) {
-	params := map[string]string{
-		"filter_zone_id": api.ZoneId,
-		"skip":           strconv.FormatInt(int64(skip), 10),
-		"count":          strconv.FormatInt(int64(count), 10),
-	}
+	ctx context.Context,
+	filterNameIlike *string,
+) iter.Seq2[ResourceBlockStorageImageGetResponse, error] {
+	params := map[string]string{}
 	setStrIfNotNil(params, "filter_name_ilike", filterNameIlike)
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]ResourceBlockStorageImageGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/infra/block_storage_image", api.pathPrefix))
-
-	return handleListAPIResponse[ResourceBlockStorageImageGetResponse](resp, err)
+	return paginateInZone[ResourceBlockStorageImageGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/infra/block_storage_image", api.pathPrefix), params,
+	)
 }
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"strconv"
 	"time"
 
@@ -40,13 +42,23 @@ type ResourceBlockStorageSnapshotDeleteResponse struct {
 }
 
 func (api *APIClient) PostBlockStorageSnapshot(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
 	name string, blockStorageId string, tags map[string]string,
 ) (*ResourceBlockStoragePostResponse, error) {
+	organizationId, err := api.OrganizationId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetHeader(IdempotencyKeyHeader, idempotencyKey).
 		SetResult(&ResourceBlockStoragePostResponse{}).
 		SetBody(map[string]interface{}{
-			"zone_id":          api.ZoneId,
-			"organization_id":  api.OrganizationId,
+			"zone_id":          zoneId,
+			"organization_id":  organizationId,
 			"name":             name,
 			"block_storage_id": blockStorageId,
 			"tags":             tags,
@@ -57,9 +69,11 @@ func (api *APIClient) PostBlockStorageSnapshot(
 }
 
 func (api *APIClient) GetBlockStorageSnapshot(
+	ctx context.Context,
 	id string,
 ) (*ResourceBlockStorageSnapshotGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceBlockStorageSnapshotGetResponse{}).
 		Get(fmt.Sprintf("%s/user/resource/storage/block_storage/snapshot/%s", api.pathPrefix, id))
 
@@ -67,6 +81,7 @@ func (api *APIClient) GetBlockStorageSnapshot(
 }
 
 func (api *APIClient) GetBlockStorageSnapshots(
+	ctx context.Context,
 	filterZoneId *string,
 	filterOrganizationId *string,
 	filterNameIlike *string,
@@ -74,13 +89,8 @@ func (api *APIClient) GetBlockStorageSnapshots(
 	filterImageId *string,
 	filterStatus *string,
 	filterDr *bool,
-	skip int,
-	count int,
-) ([]ResourceBlockStorageSnapshotGetResponse, error) {
-	params := map[string]string{
-		"skip":  strconv.FormatInt(int64(skip), 10),
-		"count": strconv.FormatInt(int64(count), 10),
-	}
+) iter.Seq2[ResourceBlockStorageSnapshotGetResponse, error] {
+	params := map[string]string{}
 	setStrIfNotNil(params, "filter_zone_id", filterZoneId)
 	setStrIfNotNil(params, "filter_organization_id", filterOrganizationId)
 	setStrIfNotNil(params, "filter_name_ilike", filterNameIlike)
@@ -92,15 +102,16 @@ func (api *APIClient) GetBlockStorageSnapshots(
 		params["filter_dr"] = strconv.FormatBool(*filterDr)
 	}
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]ResourceBlockStorageSnapshotGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/resource/storage/block_storage/snapshot", api.pathPrefix))
-
-	return handleListAPIResponse[ResourceBlockStorageSnapshotGetResponse](resp, err)
+	return paginate[ResourceBlockStorageSnapshotGetResponse](
+		ctx,
+		api,
+		fmt.Sprintf("%s/user/resource/storage/block_storage/snapshot", api.pathPrefix),
+		params,
+	)
 }
 
 func (api *APIClient) PatchBlockStorageSnapshot(
+	ctx context.Context,
 	id string, namePtr *string, tagsPtr *map[string]string,
 ) (*ResourceBlockStoragePatchResponse, error) {
 	params := map[string]interface{}{}
@@ -108,6 +119,7 @@ func (api *APIClient) PatchBlockStorageSnapshot(
 	setIfNotNil(params, "tags", tagsPtr)
 
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceBlockStoragePatchResponse{}).
 		SetBody(params).
 		Patch(fmt.Sprintf("%s/user/resource/storage/block_storage/snapshot/%s", api.pathPrefix, id))
@@ -116,9 +128,11 @@ func (api *APIClient) PatchBlockStorageSnapshot(
 }
 
 func (api *APIClient) DeleteBlockStorageSnapshot(
+	ctx context.Context,
 	id string,
 ) (*ResourceBlockStorageDeleteResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceBlockStorageDeleteResponse{}).
 		Delete(fmt.Sprintf("%s/user/resource/storage/block_storage/snapshot/%s", api.pathPrefix, id))
 
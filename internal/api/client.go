@@ -1,34 +1,70 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/go-resty/resty/v2"
 )
 
 type APIClient struct {
-	restyClient    *resty.Client
-	token          string
-	baseURL        string
-	pathPrefix     string
-	OrganizationId string
-	ZoneId         string
+	restyClient *resty.Client
+	token       string
+	baseURL     string
+	pathPrefix  string
+
+	// zone is looked up on first use when selected by name, see ZoneId.
+	zoneMu sync.Mutex
+	zone   ZoneSelector
+
+	// organizationId is looked up on first use, see OrganizationId.
+	organizationMu sync.Mutex
+	organizationId string
+}
+
+// ZoneSelector selects the zone of a client: the zone of Id, or else the
+// zone named Name in the region named RegionName. Either name may be empty,
+// in which case any zone or region matches, but the names must select
+// exactly one zone.
+type ZoneSelector struct {
+	Id         string
+	Name       string
+	RegionName string
 }
 
 var _ error = &APIError{}
 
+// APIError is a well-formed error response of the portal. It wraps the
+// typed errors of errors.go, so callers should branch on it with errors.Is
+// and errors.As rather than on its fields.
 type APIError struct {
 	HttpCode int
 	Code     *string
 	Message  *string
-	Detail   *map[string]interface{}
+	Detail   json.RawMessage
+
+	kinds []error
+}
+
+func (e *APIError) Unwrap() []error {
+	return e.kinds
 }
 
 func (e *APIError) IsCode(code string) bool {
 	return e.Code != nil && *e.Code == code
 }
 
+// IsTransportError reports whether err did not come with a well-formed API
+// error (e.g. a dropped connection), in which case the request may or may not
+// have been processed.
+func IsTransportError(err error) bool {
+	var apiError *APIError
+	return err != nil && !errors.As(err, &apiError)
+}
+
 func getValue[T any](p *T) any {
 	if p != nil {
 		return *p
@@ -36,47 +72,99 @@ func getValue[T any](p *T) any {
 	return "<nil>"
 }
 
+func detailString(detail json.RawMessage) string {
+	if len(detail) == 0 {
+		return "<nil>"
+	}
+	return string(detail)
+}
+
 func (e *APIError) Error() string {
 	return fmt.Sprintf(
 		"code: %v, message: %v, http_code: %d, detail: %v",
-		getValue(e.Code), getValue(e.Message), e.HttpCode, getValue(e.Detail),
+		getValue(e.Code), getValue(e.Message), e.HttpCode, detailString(e.Detail),
 	)
 }
 
+// NewAPIClient returns a client of the portal. It does not contact the portal,
+// so it works offline until the first request.
 func NewAPIClient(
 	token string,
 	baseURL string,
 	pathPrefix string,
-	zoneId string,
-	debug bool,
-) (*APIClient, error) {
+	zone ZoneSelector,
+	maxRetries int,
+	requestsPerSecond float64,
+	maxConcurrentRequests int,
+) *APIClient {
 	client := resty.New().
-		SetDebug(debug).
 		SetBaseURL(baseURL).
 		SetHeader("Authorization", fmt.Sprintf("Bearer %s", token))
 
-	result, err := handleAPIResponse[OrganizationGetResponse](
-		client.R().
-			SetResult(&OrganizationGetResponse{}).
-			Get(fmt.Sprintf("%s/user/organization", pathPrefix)),
+	client = setRetryPolicy(client, maxRetries)
+	client.SetTransport(
+		newThrottledTransport(
+			newLoggingTransport(client.GetClient().Transport),
+			requestsPerSecond,
+			maxConcurrentRequests,
+		),
 	)
 
+	return &APIClient{
+		restyClient: client,
+		token:       token,
+		baseURL:     baseURL,
+		pathPrefix:  pathPrefix,
+		zone:        zone,
+	}
+}
+
+// ZoneId returns the id of the zone of the client, which resources are
+// created in unless they set their own. A zone selected by name is looked up
+// on the first call and cached; a failed lookup is retried on the next call.
+func (api *APIClient) ZoneId(ctx context.Context) (string, error) {
+	api.zoneMu.Lock()
+	defer api.zoneMu.Unlock()
+
+	if api.zone.Id != "" {
+		return api.zone.Id, nil
+	}
+
+	zoneId, err := resolveZone(ctx, api, api.zone.Name, api.zone.RegionName)
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("failed to select the zone: %w", err)
 	}
 
-	return &APIClient{
-		restyClient:    client,
-		token:          token,
-		baseURL:        baseURL,
-		pathPrefix:     pathPrefix,
-		OrganizationId: result.Id.String(),
-		ZoneId:         zoneId,
-	}, nil
+	api.zone.Id = zoneId
+	return zoneId, nil
+}
+
+// OrganizationId returns the id of the organization of the caller, which
+// resources are created in. It is looked up on the first call and cached; a
+// failed lookup is retried on the next call.
+func (api *APIClient) OrganizationId(ctx context.Context) (string, error) {
+	api.organizationMu.Lock()
+	defer api.organizationMu.Unlock()
+
+	if api.organizationId != "" {
+		return api.organizationId, nil
+	}
+
+	organization, err := api.GetOrganization(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the organization: %w", err)
+	}
+
+	api.organizationId = organization.Id.String()
+	return api.organizationId, nil
 }
 
 func makeAPIError(resp *resty.Response) (*APIError, error) {
-	var data map[string]interface{}
+	var data struct {
+		Code    string          `json:"code"`
+		Message string          `json:"message"`
+		Detail  json.RawMessage `json:"detail"`
+	}
 
 	err := json.Unmarshal(resp.Body(), &data)
 	if err != nil {
@@ -88,27 +176,24 @@ func makeAPIError(resp *resty.Response) (*APIError, error) {
 		)
 	}
 
-	code, _ := data["code"].(string)
-	message, _ := data["message"].(string)
-	maybeDetail, detailExists := data["detail"]
-
-	if detailExists {
-		switch detail := maybeDetail.(type) {
-		case map[string]interface{}:
-			return nil, &APIError{
-				HttpCode: resp.StatusCode(),
-				Code:     &code,
-				Message:  &message,
-				Detail:   &detail,
-			}
-		}
+	var detail json.RawMessage
+	if len(data.Detail) > 0 && string(data.Detail) != "null" {
+		detail = data.Detail
 	}
 
-	return nil, &APIError{
-		HttpCode: resp.StatusCode(),
+	return nil, NewAPIError(resp.StatusCode(), data.Code, data.Message, detail)
+}
+
+// NewAPIError builds an error as the portal responds it, wrapping the typed
+// errors matching its status and detail. Besides parsing responses, it lets
+// fakes of Client fail the same way the portal does.
+func NewAPIError(httpCode int, code string, message string, detail json.RawMessage) *APIError {
+	return &APIError{
+		HttpCode: httpCode,
 		Code:     &code,
 		Message:  &message,
-		Detail:   nil,
+		Detail:   detail,
+		kinds:    classifyAPIError(httpCode, code, detail),
 	}
 }
 
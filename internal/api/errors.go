@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Sentinel errors wrapped by APIError, to be checked with errors.Is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// ErrUnexpectedStatus is returned (wrapped in APIError) when the portal
+// rejects a request because a resource is not in a status that allows it,
+// e.g. deleting a block storage that is already deleted. It also matches
+// ErrConflict.
+type ErrUnexpectedStatus struct {
+	Resource string
+	Status   string
+}
+
+func (e *ErrUnexpectedStatus) Error() string {
+	return fmt.Sprintf("unexpected status of %s: %s", e.Resource, e.Status)
+}
+
+func (e *ErrUnexpectedStatus) Unwrap() error {
+	return ErrConflict
+}
+
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ErrValidation is returned (wrapped in APIError) when the portal rejects the
+// values of a request.
+type ErrValidation struct {
+	Fields []FieldError
+}
+
+func (e *ErrValidation) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s: %s", field.Field, field.Message))
+	}
+	return fmt.Sprintf("invalid request (%s)", strings.Join(fields, ", "))
+}
+
+// UnexpectedStatusCode is the code of the error that ErrUnexpectedStatus is
+// decoded from.
+const UnexpectedStatusCode = "unexpected_status"
+
+// resourceStatusDetail is an entry of the detail of an `unexpected_status`
+// error, keyed by the kind of resource, e.g.
+// `{"resource_allocation": {"status": "terminated", ...}}`.
+type resourceStatusDetail struct {
+	Status *string `json:"status"`
+}
+
+// validationDetail is the detail of a rejected request, either as a list of
+// locations and messages or as messages keyed by field.
+type validationDetail []struct {
+	Loc []any  `json:"loc"`
+	Msg string `json:"msg"`
+}
+
+// classifyAPIError returns the typed errors matching a failed response.
+func classifyAPIError(httpCode int, code string, detail json.RawMessage) []error {
+	switch httpCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return []error{ErrUnauthorized}
+
+	case http.StatusNotFound:
+		return []error{ErrNotFound}
+
+	case http.StatusConflict:
+		if code == UnexpectedStatusCode {
+			if kinds := decodeUnexpectedStatus(detail); len(kinds) > 0 {
+				return kinds
+			}
+		}
+		return []error{ErrConflict}
+
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		if fields := decodeValidation(detail); len(fields) > 0 {
+			return []error{&ErrValidation{Fields: fields}}
+		}
+	}
+
+	return nil
+}
+
+func decodeUnexpectedStatus(detail json.RawMessage) []error {
+	var resources map[string]json.RawMessage
+	if err := json.Unmarshal(detail, &resources); err != nil {
+		return nil
+	}
+
+	var kinds []error
+	for _, resource := range slices.Sorted(maps.Keys(resources)) {
+		var entry resourceStatusDetail
+		if err := json.Unmarshal(resources[resource], &entry); err != nil || entry.Status == nil {
+			continue
+		}
+		kinds = append(kinds, &ErrUnexpectedStatus{Resource: resource, Status: *entry.Status})
+	}
+
+	return kinds
+}
+
+func decodeValidation(detail json.RawMessage) []FieldError {
+	var list validationDetail
+	if err := json.Unmarshal(detail, &list); err == nil {
+		fields := make([]FieldError, 0, len(list))
+		for _, item := range list {
+			loc := make([]string, 0, len(item.Loc))
+			for _, part := range item.Loc {
+				loc = append(loc, fmt.Sprint(part))
+			}
+			fields = append(fields, FieldError{Field: strings.Join(loc, "."), Message: item.Msg})
+		}
+		return fields
+	}
+
+	var byField map[string]string
+	if err := json.Unmarshal(detail, &byField); err == nil {
+		fields := make([]FieldError, 0, len(byField))
+		for _, field := range slices.Sorted(maps.Keys(byField)) {
+			fields = append(fields, FieldError{Field: field, Message: byField[field]})
+		}
+		return fields
+	}
+
+	return nil
+}
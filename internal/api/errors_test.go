@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestNewAPIErrorKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		httpCode int
+		code     string
+		detail   string
+		is       []error
+		isNot    []error
+	}{
+		{
+			name:     "not found",
+			httpCode: http.StatusNotFound,
+			is:       []error{ErrNotFound},
+			isNot:    []error{ErrConflict, ErrUnauthorized},
+		},
+		{
+			name:     "unauthorized",
+			httpCode: http.StatusUnauthorized,
+			is:       []error{ErrUnauthorized},
+			isNot:    []error{ErrNotFound},
+		},
+		{
+			name:     "conflict without detail",
+			httpCode: http.StatusConflict,
+			code:     UnexpectedStatusCode,
+			is:       []error{ErrConflict},
+		},
+		{
+			name:     "unexpected status",
+			httpCode: http.StatusConflict,
+			code:     UnexpectedStatusCode,
+			detail:   `{"resource_allocation": {"status": "terminated"}}`,
+			is:       []error{ErrConflict},
+			isNot:    []error{ErrNotFound},
+		},
+		{
+			name:     "server error",
+			httpCode: http.StatusInternalServerError,
+			isNot:    []error{ErrNotFound, ErrConflict, ErrUnauthorized},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var detail json.RawMessage
+			if test.detail != "" {
+				detail = json.RawMessage(test.detail)
+			}
+			err := error(NewAPIError(test.httpCode, test.code, "message", detail))
+
+			for _, target := range test.is {
+				if !errors.Is(err, target) {
+					t.Errorf("errors.Is(%v, %v) = false, want true", err, target)
+				}
+			}
+			for _, target := range test.isNot {
+				if errors.Is(err, target) {
+					t.Errorf("errors.Is(%v, %v) = true, want false", err, target)
+				}
+			}
+		})
+	}
+}
+
+func TestNewAPIErrorUnexpectedStatus(t *testing.T) {
+	err := error(NewAPIError(
+		http.StatusConflict,
+		UnexpectedStatusCode,
+		"message",
+		json.RawMessage(`{"resource_allocation": {"status": "terminated", "id": "x"}, "note": 1}`),
+	))
+
+	var unexpectedStatus *ErrUnexpectedStatus
+	if !errors.As(err, &unexpectedStatus) {
+		t.Fatalf("errors.As(%v, *ErrUnexpectedStatus) = false, want true", err)
+	}
+
+	want := ErrUnexpectedStatus{Resource: "resource_allocation", Status: "terminated"}
+	if *unexpectedStatus != want {
+		t.Errorf("got %+v, want %+v", *unexpectedStatus, want)
+	}
+}
+
+func TestNewAPIErrorValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		detail string
+		want   []FieldError
+	}{
+		{
+			name:   "list of locations",
+			detail: `[{"loc": ["body", "size_gib"], "msg": "too small", "type": "value_error"}]`,
+			want:   []FieldError{{Field: "body.size_gib", Message: "too small"}},
+		},
+		{
+			name:   "messages by field",
+			detail: `{"name": "required", "cidr": "invalid"}`,
+			want: []FieldError{
+				{Field: "cidr", Message: "invalid"},
+				{Field: "name", Message: "required"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := error(NewAPIError(
+				http.StatusUnprocessableEntity, "", "message", json.RawMessage(test.detail),
+			))
+
+			var validation *ErrValidation
+			if !errors.As(err, &validation) {
+				t.Fatalf("errors.As(%v, *ErrValidation) = false, want true", err)
+			}
+
+			if !reflect.DeepEqual(validation.Fields, test.want) {
+				t.Errorf("got %+v, want %+v", validation.Fields, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+// Package fake provides an in-memory implementation of api.Client, so that
+// resources and data sources can be tested without a portal.
+//
+// Resources go through the statuses of the portal (e.g. a block storage is
+// `assigned`, then `prepared`). By default they reach their target status as
+// soon as they are created; SetTransitionDelay makes them take their time, the
+// way the portal does. The fake also fails the way the portal does: a missing
+// resource is api.ErrNotFound, deleting a deleted resource is an
+// api.ErrUnexpectedStatus, and so on.
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"terraform-provider-eci/internal/api"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Client struct {
+	ZoneId         uuid.UUID
+	OrganizationId uuid.UUID
+
+	mu              sync.Mutex
+	calls           []string
+	failures        map[string][]error
+	idempotencyKeys map[string]uuid.UUID
+	transitionDelay time.Duration
+	transitions     []transition
+
+	organization api.OrganizationGetResponse
+
+	regions       store[api.RegionGetResponse]
+	zones         store[api.InfraZoneGetResponse]
+	instanceTypes store[api.InfraInstanceTypeGetResponse]
+	images        store[api.ResourceBlockStorageImageGetResponse]
+
+	virtualMachines store[api.ResourceVirtualMachineGetResponse]
+	allocations     store[api.ResourceVirtualMachineAllocationGetResponse]
+
+	blockStorages store[api.ResourceBlockStorageGetResponse]
+	snapshots     store[api.ResourceBlockStorageSnapshotGetResponse]
+
+	virtualNetworks   store[api.ResourceVirtualNetworkGetResponse]
+	subnets           store[api.ResourceSubnetGetResponse]
+	networkInterfaces store[api.ResourceNetworkInterfaceGetResponse]
+	publicIps         store[api.ResourcePublicIpGetResponse]
+}
+
+var _ api.Client = &Client{}
+
+func NewClient() *Client {
+	organizationId := uuid.New()
+
+	return &Client{
+		ZoneId:          uuid.New(),
+		OrganizationId:  organizationId,
+		failures:        map[string][]error{},
+		idempotencyKeys: map[string]uuid.UUID{},
+		organization: api.OrganizationGetResponse{
+			Id:    organizationId,
+			Name:  "fake",
+			Ident: "fake",
+		},
+	}
+}
+
+// FailNext makes the next call of method (e.g. "PostVirtualMachine") return
+// err without doing anything. Failures of the same method are queued.
+func (c *Client) FailNext(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures[method] = append(c.failures[method], err)
+}
+
+// Calls returns the names of the methods called so far, in order.
+func (c *Client) Calls() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.calls...)
+}
+
+// SetTransitionDelay sets how long resources take to move from one status to
+// the next, e.g. from `assigned` to `prepared`.
+func (c *Client) SetTransitionDelay(delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.transitionDelay = delay
+}
+
+type transition struct {
+	at    time.Time
+	apply func()
+}
+
+// after schedules a status transition, which is applied right away when there
+// is no transition delay. It must be called with the lock held.
+func (c *Client) after(apply func()) {
+	if c.transitionDelay == 0 {
+		apply()
+		return
+	}
+
+	c.transitions = append(c.transitions, transition{
+		at:    time.Now().Add(c.transitionDelay),
+		apply: apply,
+	})
+}
+
+// advance applies the transitions that are due. Transitions scheduled while
+// applying them are kept for later. It must be called with the lock held.
+func (c *Client) advance() {
+	now := time.Now()
+
+	var due, pending []transition
+	for _, transition := range c.transitions {
+		if transition.at.After(now) {
+			pending = append(pending, transition)
+		} else {
+			due = append(due, transition)
+		}
+	}
+
+	c.transitions = pending
+	for _, transition := range due {
+		transition.apply()
+	}
+}
+
+// call records a call of method and returns the failure queued for it, if any.
+// Like APIClient, it fails once ctx is done. It must be called with the lock
+// held.
+func (c *Client) call(ctx context.Context, method string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.advance()
+	c.calls = append(c.calls, method)
+
+	failures := c.failures[method]
+	if len(failures) == 0 {
+		return nil
+	}
+
+	c.failures[method] = failures[1:]
+	return failures[0]
+}
+
+// created returns the id of the resource created by an earlier request with
+// the same idempotency key. It must be called with the lock held.
+func (c *Client) created(idempotencyKey string) (uuid.UUID, bool) {
+	id, ok := c.idempotencyKeys[idempotencyKey]
+	return id, ok && idempotencyKey != ""
+}
+
+// newId returns the id of a resource being created, remembering it for the
+// idempotency key. It must be called with the lock held.
+func (c *Client) newId(idempotencyKey string) uuid.UUID {
+	id := uuid.New()
+	if idempotencyKey != "" {
+		c.idempotencyKeys[idempotencyKey] = id
+	}
+	return id
+}
+
+// zone returns the zone of a resource being created, which is either the zone
+// of the client or one added with AddZone. It must be called with the lock
+// held.
+func (c *Client) zone(zoneId string) (uuid.UUID, error) {
+	parsed, err := uuid.Parse(zoneId)
+	if err != nil {
+		return uuid.Nil, validationError("zone_id", err.Error())
+	}
+
+	if _, ok := c.zones.items[parsed]; !ok && parsed != c.ZoneId {
+		return uuid.Nil, notFoundError("zone", zoneId)
+	}
+	return parsed, nil
+}
+
+type store[T any] struct {
+	items map[uuid.UUID]*T
+	order []uuid.UUID
+}
+
+func (s *store[T]) put(id uuid.UUID, item *T) {
+	if s.items == nil {
+		s.items = map[uuid.UUID]*T{}
+	}
+	if _, exists := s.items[id]; !exists {
+		s.order = append(s.order, id)
+	}
+	s.items[id] = item
+}
+
+func (s *store[T]) get(kind string, id string) (*T, error) {
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, validationError("id", err.Error())
+	}
+
+	item, ok := s.items[parsed]
+	if !ok {
+		return nil, notFoundError(kind, id)
+	}
+	return item, nil
+}
+
+// read returns a copy of an item, so that callers cannot modify the store.
+func (s *store[T]) read(kind string, id string) (*T, error) {
+	item, err := s.get(kind, id)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *item
+	return &copied, nil
+}
+
+// list returns a snapshot of the items matching filter, in creation order.
+func (s *store[T]) list(filter func(item *T) bool) iter.Seq2[T, error] {
+	var items []T
+	for _, id := range s.order {
+		if item := s.items[id]; filter(item) {
+			items = append(items, *item)
+		}
+	}
+
+	return func(yield func(T, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+func all[T any](*T) bool {
+	return true
+}
+
+// failed is the result of a list call that fails before its first page.
+func failed[T any](err error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		yield(zero, err)
+	}
+}
+
+func notFoundError(kind string, id string) error {
+	return api.NewAPIError(
+		http.StatusNotFound, "not_found", fmt.Sprintf("%s not found: %s", kind, id), nil,
+	)
+}
+
+func unexpectedStatusError(resourceKey string, status string) error {
+	detail, _ := json.Marshal(map[string]interface{}{
+		resourceKey: map[string]string{"status": status},
+	})
+
+	return api.NewAPIError(
+		http.StatusConflict,
+		api.UnexpectedStatusCode,
+		fmt.Sprintf("unexpected status of %s: %s", resourceKey, status),
+		detail,
+	)
+}
+
+func conflictError(message string) error {
+	return api.NewAPIError(http.StatusConflict, "conflict", message, nil)
+}
+
+func validationError(field string, message string) error {
+	detail, _ := json.Marshal(map[string]string{field: message})
+	return api.NewAPIError(
+		http.StatusUnprocessableEntity, "validation_error", "invalid request", detail,
+	)
+}
+
+// parseOptionalId parses the id of an optional reference, e.g. an image.
+func parseOptionalId(field string, id *string) (*uuid.UUID, error) {
+	if id == nil {
+		return nil, nil
+	}
+
+	parsed, err := uuid.Parse(*id)
+	if err != nil {
+		return nil, validationError(field, err.Error())
+	}
+	return &parsed, nil
+}
+
+// patchAttachment applies a patch of a nullable reference, where nil leaves
+// it unchanged and a pointer to nil detaches it.
+func patchAttachment(field string, target **uuid.UUID, patch **string) error {
+	if patch == nil {
+		return nil
+	}
+
+	parsed, err := parseOptionalId(field, *patch)
+	if err != nil {
+		return err
+	}
+
+	*target = parsed
+	return nil
+}
+
+func matchesIlike(value string, filter *string) bool {
+	return filter == nil || strings.Contains(strings.ToLower(value), strings.ToLower(*filter))
+}
+
+func matchesId(value *uuid.UUID, filter *string) bool {
+	return filter == nil || (value != nil && value.String() == *filter)
+}
+
+func cloneTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return map[string]string{}
+	}
+	return maps.Clone(tags)
+}
@@ -0,0 +1,334 @@
+package fake
+
+import (
+	"context"
+	"iter"
+	"terraform-provider-eci/internal/api"
+	"time"
+)
+
+func (c *Client) GetVirtualMachine(
+	ctx context.Context, id string,
+) (*api.ResourceVirtualMachineGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetVirtualMachine"); err != nil {
+		return nil, err
+	}
+
+	return c.virtualMachines.read("virtual machine", id)
+}
+
+func (c *Client) GetVirtualMachines(
+	ctx context.Context, filterNameIlike *string,
+) iter.Seq2[api.ResourceVirtualMachineGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetVirtualMachines"); err != nil {
+		return failed[api.ResourceVirtualMachineGetResponse](err)
+	}
+
+	return c.virtualMachines.list(func(machine *api.ResourceVirtualMachineGetResponse) bool {
+		return matchesIlike(machine.Name, filterNameIlike)
+	})
+}
+
+func (c *Client) PostVirtualMachine(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	instanceTypeId string,
+	name string,
+	alwaysOn bool,
+	DR bool,
+	username string,
+	password string,
+	onInitScript string,
+	tags map[string]string,
+) (*api.ResourceVirtualMachinePostResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PostVirtualMachine"); err != nil {
+		return nil, err
+	}
+
+	if id, ok := c.created(idempotencyKey); ok {
+		return &api.ResourceVirtualMachinePostResponse{Id: id}, nil
+	}
+
+	zone, err := c.zone(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceType, err := c.instanceTypes.get("instance type", instanceTypeId)
+	if err != nil {
+		return nil, err
+	}
+
+	id := c.newId(idempotencyKey)
+	c.virtualMachines.put(id, &api.ResourceVirtualMachineGetResponse{
+		Id:             id,
+		Tags:           cloneTags(tags),
+		Created:        time.Now(),
+		ZoneId:         zone,
+		OrganizationId: c.OrganizationId,
+		InstanceTypeId: instanceType.Id,
+		CpuVcore:       instanceType.CpuVcore,
+		MemoryGib:      instanceType.MemoryGib,
+		AlwaysOn:       alwaysOn,
+		DR:             DR,
+		Status:         "idle",
+		Name:           name,
+		Username:       username,
+		OnInitScript:   onInitScript,
+	})
+
+	return &api.ResourceVirtualMachinePostResponse{Id: id}, nil
+}
+
+func (c *Client) PatchVirtualMachine(
+	ctx context.Context,
+	id string,
+	instanceTypeIdPtr *string,
+	namePtr *string,
+	alwaysOnPtr *bool,
+	tagsPtr *map[string]string,
+) (*api.ResourceVirtualMachinePatchResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PatchVirtualMachine"); err != nil {
+		return nil, err
+	}
+
+	machine, err := c.virtualMachines.get("virtual machine", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if instanceTypeIdPtr != nil {
+		instanceType, err := c.instanceTypes.get("instance type", *instanceTypeIdPtr)
+		if err != nil {
+			return nil, err
+		}
+
+		machine.InstanceTypeId = instanceType.Id
+		machine.CpuVcore = instanceType.CpuVcore
+		machine.MemoryGib = instanceType.MemoryGib
+	}
+	if namePtr != nil {
+		machine.Name = *namePtr
+	}
+	if alwaysOnPtr != nil {
+		machine.AlwaysOn = *alwaysOnPtr
+	}
+	if tagsPtr != nil {
+		machine.Tags = cloneTags(*tagsPtr)
+	}
+
+	now := time.Now()
+	machine.Modified = &now
+
+	return &api.ResourceVirtualMachinePatchResponse{Id: machine.Id}, nil
+}
+
+func (c *Client) DeleteVirtualMachine(
+	ctx context.Context, id string,
+) (*api.ResourceVirtualMachineDeleteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "DeleteVirtualMachine"); err != nil {
+		return nil, err
+	}
+
+	machine, err := c.virtualMachines.get("virtual machine", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if machine.Status != "idle" {
+		return nil, unexpectedStatusError("resource_virtual_machine", machine.Status)
+	}
+
+	now := time.Now()
+	machine.Deleted = &now
+	machine.Status = "deleted"
+
+	return &api.ResourceVirtualMachineDeleteResponse{Id: machine.Id, Status: machine.Status}, nil
+}
+
+func (c *Client) GetVirtualMachineAllocation(
+	ctx context.Context, id string,
+) (*api.ResourceVirtualMachineAllocationGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetVirtualMachineAllocation"); err != nil {
+		return nil, err
+	}
+
+	return c.allocations.read("virtual machine allocation", id)
+}
+
+func (c *Client) GetVirtualMachineAllocations(
+	ctx context.Context, filterMachineIdPtr *string, filterStatusPtr *string,
+) iter.Seq2[api.ResourceVirtualMachineAllocationGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetVirtualMachineAllocations"); err != nil {
+		return failed[api.ResourceVirtualMachineAllocationGetResponse](err)
+	}
+
+	return c.allocations.list(
+		func(allocation *api.ResourceVirtualMachineAllocationGetResponse) bool {
+			return matchesId(&allocation.MachineId, filterMachineIdPtr) &&
+				(filterStatusPtr == nil || allocation.Status == *filterStatusPtr)
+		},
+	)
+}
+
+func (c *Client) PostVirtualMachineAllocation(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	machineId string,
+	tags map[string]string,
+) (*api.ResourceVirtualMachineAllocationPostResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PostVirtualMachineAllocation"); err != nil {
+		return nil, err
+	}
+
+	if id, ok := c.created(idempotencyKey); ok {
+		return &api.ResourceVirtualMachineAllocationPostResponse{Id: id}, nil
+	}
+
+	zone, err := c.zone(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	machine, err := c.virtualMachines.get("virtual machine", machineId)
+	if err != nil {
+		return nil, err
+	}
+
+	if machine.Status != "idle" {
+		return nil, unexpectedStatusError("resource_virtual_machine", machine.Status)
+	}
+
+	id := c.newId(idempotencyKey)
+	now := time.Now()
+	c.allocations.put(id, &api.ResourceVirtualMachineAllocationGetResponse{
+		Id:                 id,
+		Tags:               cloneTags(tags),
+		Created:            now,
+		ZoneId:             zone,
+		OrganizationId:     c.OrganizationId,
+		MachineId:          machine.Id,
+		RequestedCpuVcore:  machine.CpuVcore,
+		RequestedMemoryGib: machine.MemoryGib,
+		RequestedDevices:   []string{},
+		Status:             "pending",
+	})
+	machine.Allocated = &now
+	machine.Status = "running"
+
+	c.after(func() {
+		allocation := c.allocations.items[id]
+		if allocation.Status != "pending" {
+			return
+		}
+
+		assigned := time.Now()
+		allocation.Assigned = &assigned
+		allocation.Status = "assigned"
+
+		c.after(func() {
+			if allocation.Status != "assigned" {
+				return
+			}
+
+			started := time.Now()
+			allocation.Taken = &started
+			allocation.Started = &started
+			allocation.LastHeartbeat = &started
+			allocation.Status = "started"
+		})
+	})
+
+	return &api.ResourceVirtualMachineAllocationPostResponse{Id: id}, nil
+}
+
+func (c *Client) PatchVirtualMachineAllocation(
+	ctx context.Context, id string, tagsPtr *map[string]string,
+) (*api.ResourceVirtualMachineAllocationPatchResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PatchVirtualMachineAllocation"); err != nil {
+		return nil, err
+	}
+
+	allocation, err := c.allocations.get("virtual machine allocation", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if tagsPtr != nil {
+		allocation.Tags = cloneTags(*tagsPtr)
+	}
+
+	now := time.Now()
+	allocation.Modified = &now
+
+	return &api.ResourceVirtualMachineAllocationPatchResponse{Id: allocation.Id}, nil
+}
+
+func (c *Client) DeleteVirtualMachineAllocation(
+	ctx context.Context, id string,
+) (*api.ResourceVirtualMachineAllocationDeleteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "DeleteVirtualMachineAllocation"); err != nil {
+		return nil, err
+	}
+
+	allocation, err := c.allocations.get("virtual machine allocation", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if allocation.Status == "terminating" || allocation.Status == "terminated" {
+		return nil, unexpectedStatusError("resource_allocation", allocation.Status)
+	}
+
+	now := time.Now()
+	allocation.Terminating = &now
+	allocation.Status = "terminating"
+
+	c.after(func() {
+		terminated := time.Now()
+		allocation.Terminated = &terminated
+		allocation.Status = "terminated"
+
+		if machine, ok := c.virtualMachines.items[allocation.MachineId]; ok {
+			machine.Allocated = nil
+			machine.Status = "idle"
+		}
+	})
+
+	return &api.ResourceVirtualMachineAllocationDeleteResponse{
+		Id:     allocation.Id,
+		Status: allocation.Status,
+	}, nil
+}
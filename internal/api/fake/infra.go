@@ -0,0 +1,191 @@
+package fake
+
+import (
+	"context"
+	"iter"
+	"terraform-provider-eci/internal/api"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AddRegion adds a region to the catalog, with a new id if it has none.
+func (c *Client) AddRegion(region api.RegionGetResponse) api.RegionGetResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if region.Id == uuid.Nil {
+		region.Id = uuid.New()
+	}
+	c.regions.put(region.Id, &region)
+	return region
+}
+
+// AddZone adds a zone to the catalog, with a new id if it has none.
+func (c *Client) AddZone(zone api.InfraZoneGetResponse) api.InfraZoneGetResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if zone.Id == uuid.Nil {
+		zone.Id = uuid.New()
+	}
+	c.zones.put(zone.Id, &zone)
+	return zone
+}
+
+// AddInstanceType adds an instance type to the catalog of the zone of the
+// client, with a new id if it has none.
+func (c *Client) AddInstanceType(
+	instanceType api.InfraInstanceTypeGetResponse,
+) api.InfraInstanceTypeGetResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if instanceType.Id == uuid.Nil {
+		instanceType.Id = uuid.New()
+	}
+	instanceType.ZoneId = c.ZoneId
+	instanceType.Created = time.Now()
+	instanceType.Tags = cloneTags(instanceType.Tags)
+	c.instanceTypes.put(instanceType.Id, &instanceType)
+	return instanceType
+}
+
+// AddBlockStorageImage adds an image to the catalog of the zone of the
+// client, with a new id if it has none.
+func (c *Client) AddBlockStorageImage(
+	image api.ResourceBlockStorageImageGetResponse,
+) api.ResourceBlockStorageImageGetResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if image.Id == uuid.Nil {
+		image.Id = uuid.New()
+	}
+	image.ZoneId = c.ZoneId
+	image.Created = time.Now()
+	c.images.put(image.Id, &image)
+	return image
+}
+
+func (c *Client) GetOrganization(ctx context.Context) (*api.OrganizationGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetOrganization"); err != nil {
+		return nil, err
+	}
+
+	organization := c.organization
+	return &organization, nil
+}
+
+func (c *Client) GetRegion(ctx context.Context, id string) (*api.RegionGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetRegion"); err != nil {
+		return nil, err
+	}
+
+	return c.regions.read("region", id)
+}
+
+func (c *Client) GetRegions(
+	ctx context.Context, filterNameIlike *string,
+) iter.Seq2[api.RegionGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetRegions"); err != nil {
+		return failed[api.RegionGetResponse](err)
+	}
+
+	return c.regions.list(func(region *api.RegionGetResponse) bool {
+		return matchesIlike(region.Name, filterNameIlike)
+	})
+}
+
+func (c *Client) GetZone(ctx context.Context, id string) (*api.InfraZoneGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetZone"); err != nil {
+		return nil, err
+	}
+
+	return c.zones.read("zone", id)
+}
+
+func (c *Client) GetZones(
+	ctx context.Context, filterRegionId *string, filterNameIlike *string,
+) iter.Seq2[api.InfraZoneGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetZones"); err != nil {
+		return failed[api.InfraZoneGetResponse](err)
+	}
+
+	return c.zones.list(func(zone *api.InfraZoneGetResponse) bool {
+		return matchesId(&zone.RegionId, filterRegionId) &&
+			matchesIlike(zone.Name, filterNameIlike)
+	})
+}
+
+func (c *Client) GetInstanceType(
+	ctx context.Context, id string,
+) (*api.InfraInstanceTypeGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetInstanceType"); err != nil {
+		return nil, err
+	}
+
+	return c.instanceTypes.read("instance type", id)
+}
+
+func (c *Client) GetInstanceTypes(
+	ctx context.Context, filterNameIlike *string, filterActivated *bool,
+) iter.Seq2[api.InfraInstanceTypeGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetInstanceTypes"); err != nil {
+		return failed[api.InfraInstanceTypeGetResponse](err)
+	}
+
+	return c.instanceTypes.list(func(instanceType *api.InfraInstanceTypeGetResponse) bool {
+		return matchesIlike(instanceType.Name, filterNameIlike) &&
+			(filterActivated == nil || instanceType.Activated == *filterActivated)
+	})
+}
+
+func (c *Client) GetBlockStorageImage(
+	ctx context.Context, id string,
+) (*api.ResourceBlockStorageImageGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetBlockStorageImage"); err != nil {
+		return nil, err
+	}
+
+	return c.images.read("block storage image", id)
+}
+
+func (c *Client) GetBlockStorageImages(
+	ctx context.Context, filterNameIlike *string,
+) iter.Seq2[api.ResourceBlockStorageImageGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetBlockStorageImages"); err != nil {
+		return failed[api.ResourceBlockStorageImageGetResponse](err)
+	}
+
+	return c.images.list(func(image *api.ResourceBlockStorageImageGetResponse) bool {
+		return matchesIlike(image.Name, filterNameIlike)
+	})
+}
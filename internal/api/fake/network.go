@@ -0,0 +1,596 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"terraform-provider-eci/internal/api"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (c *Client) GetVirtualNetwork(
+	ctx context.Context, id string,
+) (*api.ResourceVirtualNetworkGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetVirtualNetwork"); err != nil {
+		return nil, err
+	}
+
+	return c.virtualNetworks.read("virtual network", id)
+}
+
+func (c *Client) GetVirtualNetworks(
+	ctx context.Context, filterNameIlike *string,
+) iter.Seq2[api.ResourceVirtualNetworkGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetVirtualNetworks"); err != nil {
+		return failed[api.ResourceVirtualNetworkGetResponse](err)
+	}
+
+	return c.virtualNetworks.list(func(network *api.ResourceVirtualNetworkGetResponse) bool {
+		return matchesIlike(network.Name, filterNameIlike)
+	})
+}
+
+func (c *Client) PostVirtualNetwork(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	name string, networkCidr string, tags map[string]string,
+) (*api.ResourceVirtualNetworkPostResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PostVirtualNetwork"); err != nil {
+		return nil, err
+	}
+
+	if id, ok := c.created(idempotencyKey); ok {
+		return &api.ResourceVirtualNetworkPostResponse{Id: id}, nil
+	}
+
+	zone, err := c.zone(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	id := c.newId(idempotencyKey)
+	c.virtualNetworks.put(id, &api.ResourceVirtualNetworkGetResponse{
+		Id:             id,
+		Tags:           cloneTags(tags),
+		Created:        time.Now(),
+		ZoneId:         zone,
+		OrganizationId: c.OrganizationId,
+		Status:         "active",
+		Name:           name,
+		NetworkCidr:    networkCidr,
+		FirewallRules:  []api.NetworkFirewallRule{},
+	})
+
+	return &api.ResourceVirtualNetworkPostResponse{Id: id}, nil
+}
+
+func (c *Client) PatchVirtualNetwork(
+	ctx context.Context,
+	id string,
+	namePtr *string,
+	firewallRulesPtr *[]api.NetworkFirewallRule,
+	tags *map[string]string,
+) (*api.ResourceVirtualNetworkPatchResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PatchVirtualNetwork"); err != nil {
+		return nil, err
+	}
+
+	network, err := c.virtualNetworks.get("virtual network", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if namePtr != nil {
+		network.Name = *namePtr
+	}
+	if firewallRulesPtr != nil {
+		network.FirewallRules = append([]api.NetworkFirewallRule{}, *firewallRulesPtr...)
+	}
+	if tags != nil {
+		network.Tags = cloneTags(*tags)
+	}
+
+	now := time.Now()
+	network.Modified = &now
+
+	return &api.ResourceVirtualNetworkPatchResponse{Id: network.Id}, nil
+}
+
+func (c *Client) DeleteVirtualNetwork(
+	ctx context.Context, id string,
+) (*api.ResourceVirtualNetworkDeleteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "DeleteVirtualNetwork"); err != nil {
+		return nil, err
+	}
+
+	network, err := c.virtualNetworks.get("virtual network", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if network.Status == "deleted" {
+		return nil, unexpectedStatusError("resource_virtual_network", network.Status)
+	}
+
+	for _, subnet := range c.subnets.items {
+		if subnet.AttachedNetworkId == network.Id && subnet.Status != "deleted" {
+			return nil, conflictError(fmt.Sprintf("virtual network has subnet %s", subnet.Id))
+		}
+	}
+
+	now := time.Now()
+	network.Deleted = &now
+	network.Status = "deleted"
+
+	return &api.ResourceVirtualNetworkDeleteResponse{Id: network.Id, Status: network.Status}, nil
+}
+
+func (c *Client) GetSubnet(ctx context.Context, id string) (*api.ResourceSubnetGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetSubnet"); err != nil {
+		return nil, err
+	}
+
+	return c.subnets.read("subnet", id)
+}
+
+func (c *Client) GetSubnets(
+	ctx context.Context, filterAttachedNetworkId *string,
+) iter.Seq2[api.ResourceSubnetGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetSubnets"); err != nil {
+		return failed[api.ResourceSubnetGetResponse](err)
+	}
+
+	return c.subnets.list(func(subnet *api.ResourceSubnetGetResponse) bool {
+		return matchesId(&subnet.AttachedNetworkId, filterAttachedNetworkId)
+	})
+}
+
+func (c *Client) PostSubnet(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	name string,
+	attachedNetworkId string,
+	purpose string,
+	networkGw string,
+	tags map[string]string,
+) (*api.ResourceSubnetPostResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PostSubnet"); err != nil {
+		return nil, err
+	}
+
+	if id, ok := c.created(idempotencyKey); ok {
+		return &api.ResourceSubnetPostResponse{Id: id}, nil
+	}
+
+	zone, err := c.zone(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	network, err := c.virtualNetworks.get("virtual network", attachedNetworkId)
+	if err != nil {
+		return nil, err
+	}
+
+	id := c.newId(idempotencyKey)
+	now := time.Now()
+	c.subnets.put(id, &api.ResourceSubnetGetResponse{
+		Id:                id,
+		Tags:              cloneTags(tags),
+		Created:           now,
+		ZoneId:            zone,
+		OrganizationId:    c.OrganizationId,
+		AttachedNetworkId: network.Id,
+		Activated:         &now,
+		Status:            "active",
+		Name:              name,
+		Purpose:           purpose,
+		NetworkGw:         networkGw,
+	})
+
+	return &api.ResourceSubnetPostResponse{Id: id}, nil
+}
+
+func (c *Client) PatchSubnet(
+	ctx context.Context, id string, namePtr *string, tagsPtr *map[string]string,
+) (*api.ResourceSubnetPatchResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PatchSubnet"); err != nil {
+		return nil, err
+	}
+
+	subnet, err := c.subnets.get("subnet", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if namePtr != nil {
+		subnet.Name = *namePtr
+	}
+	if tagsPtr != nil {
+		subnet.Tags = cloneTags(*tagsPtr)
+	}
+
+	now := time.Now()
+	subnet.Modified = &now
+
+	return &api.ResourceSubnetPatchResponse{Id: subnet.Id}, nil
+}
+
+func (c *Client) DeleteSubnet(
+	ctx context.Context, id string,
+) (*api.ResourceSubnetDeleteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "DeleteSubnet"); err != nil {
+		return nil, err
+	}
+
+	subnet, err := c.subnets.get("subnet", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if subnet.Status == "deleted" {
+		return nil, unexpectedStatusError("resource_subnet", subnet.Status)
+	}
+
+	for _, networkInterface := range c.networkInterfaces.items {
+		if networkInterface.AttachedSubnetId == subnet.Id && networkInterface.Status != "deleted" {
+			return nil, conflictError(
+				fmt.Sprintf("subnet has network interface %s", networkInterface.Id),
+			)
+		}
+	}
+
+	now := time.Now()
+	subnet.Deleted = &now
+	subnet.Status = "deleted"
+
+	return &api.ResourceSubnetDeleteResponse{Id: subnet.Id, Status: subnet.Status}, nil
+}
+
+func (c *Client) GetNetworkInterface(
+	ctx context.Context, id string,
+) (*api.ResourceNetworkInterfaceGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetNetworkInterface"); err != nil {
+		return nil, err
+	}
+
+	return c.networkInterfaces.read("network interface", id)
+}
+
+func (c *Client) GetNetworkInterfaces(
+	ctx context.Context, filterAttachedMachineIdPtr *string,
+) iter.Seq2[api.ResourceNetworkInterfaceGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetNetworkInterfaces"); err != nil {
+		return failed[api.ResourceNetworkInterfaceGetResponse](err)
+	}
+
+	return c.networkInterfaces.list(
+		func(networkInterface *api.ResourceNetworkInterfaceGetResponse) bool {
+			return matchesId(networkInterface.AttachedMachineId, filterAttachedMachineIdPtr)
+		},
+	)
+}
+
+func (c *Client) PostNetworkInterface(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	name string,
+	attachedSubnetId string,
+	dr bool,
+	ipPtr *string,
+	macPtr *string,
+	tags map[string]string,
+) (*api.ResourceNetworkInterfacePostResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PostNetworkInterface"); err != nil {
+		return nil, err
+	}
+
+	if id, ok := c.created(idempotencyKey); ok {
+		return &api.ResourceNetworkInterfacePostResponse{Id: id}, nil
+	}
+
+	zone, err := c.zone(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	subnet, err := c.subnets.get("subnet", attachedSubnetId)
+	if err != nil {
+		return nil, err
+	}
+
+	count := len(c.networkInterfaces.order) + 1
+	ip := fmt.Sprintf("10.%d.%d.%d", count>>16&0xff, count>>8&0xff, count&0xff)
+	if ipPtr != nil {
+		ip = *ipPtr
+	}
+
+	mac := fmt.Sprintf("02:00:00:%02x:%02x:%02x", count>>16&0xff, count>>8&0xff, count&0xff)
+	if macPtr != nil {
+		mac = *macPtr
+	}
+
+	id := c.newId(idempotencyKey)
+	c.networkInterfaces.put(id, &api.ResourceNetworkInterfaceGetResponse{
+		Id:               id,
+		Tags:             cloneTags(tags),
+		Created:          time.Now(),
+		ZoneId:           zone,
+		OrganizationId:   c.OrganizationId,
+		AttachedSubnetId: subnet.Id,
+		DR:               dr,
+		Status:           "pending",
+		Name:             name,
+		Ip:               ip,
+		Mac:              mac,
+	})
+	c.after(func() {
+		c.networkInterfaces.items[id].Status = "active"
+	})
+
+	return &api.ResourceNetworkInterfacePostResponse{Id: id}, nil
+}
+
+func (c *Client) PatchNetworkInterface(
+	ctx context.Context,
+	id string, namePtr *string, attachedMachineIdPtr **string, tagsPtr *map[string]string,
+) (*api.ResourceNetworkInterfacePatchResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PatchNetworkInterface"); err != nil {
+		return nil, err
+	}
+
+	networkInterface, err := c.networkInterfaces.get("network interface", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if attachedMachineIdPtr != nil && *attachedMachineIdPtr != nil {
+		_, err := c.virtualMachines.get("virtual machine", **attachedMachineIdPtr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = patchAttachment(
+		"attached_machine_id", &networkInterface.AttachedMachineId, attachedMachineIdPtr,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if namePtr != nil {
+		networkInterface.Name = *namePtr
+	}
+	if tagsPtr != nil {
+		networkInterface.Tags = cloneTags(*tagsPtr)
+	}
+
+	now := time.Now()
+	networkInterface.Modified = &now
+
+	return &api.ResourceNetworkInterfacePatchResponse{Id: networkInterface.Id}, nil
+}
+
+func (c *Client) DeleteNetworkInterface(
+	ctx context.Context, id string,
+) (*api.ResourceNetworkInterfaceDeleteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "DeleteNetworkInterface"); err != nil {
+		return nil, err
+	}
+
+	networkInterface, err := c.networkInterfaces.get("network interface", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if networkInterface.Status == "deleted" {
+		return nil, unexpectedStatusError("resource_network_interface", networkInterface.Status)
+	}
+
+	for _, publicIp := range c.publicIps.items {
+		if matchesId(publicIp.AttachedNetworkInterfaceId, &id) && publicIp.Status != "deleted" {
+			return nil, conflictError(
+				fmt.Sprintf("network interface has public ip %s", publicIp.Id),
+			)
+		}
+	}
+
+	now := time.Now()
+	networkInterface.AttachedMachineId = nil
+	networkInterface.Deleted = &now
+	networkInterface.Status = "deleted"
+
+	return &api.ResourceNetworkInterfaceDeleteResponse{
+		Id:     networkInterface.Id,
+		Status: networkInterface.Status,
+	}, nil
+}
+
+func (c *Client) GetPublicIp(
+	ctx context.Context, id string,
+) (*api.ResourcePublicIpGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetPublicIp"); err != nil {
+		return nil, err
+	}
+
+	return c.publicIps.read("public ip", id)
+}
+
+func (c *Client) GetPublicIps(
+	ctx context.Context, filterAttachedNetworkInterfaceIdPtr *string,
+) iter.Seq2[api.ResourcePublicIpGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetPublicIps"); err != nil {
+		return failed[api.ResourcePublicIpGetResponse](err)
+	}
+
+	return c.publicIps.list(func(publicIp *api.ResourcePublicIpGetResponse) bool {
+		return matchesId(publicIp.AttachedNetworkInterfaceId, filterAttachedNetworkInterfaceIdPtr)
+	})
+}
+
+func (c *Client) PostPublicIp(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	dr bool,
+	tags map[string]string,
+) (*api.ResourcePublicIpPostResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PostPublicIp"); err != nil {
+		return nil, err
+	}
+
+	if id, ok := c.created(idempotencyKey); ok {
+		return &api.ResourcePublicIpPostResponse{Id: id}, nil
+	}
+
+	zone, err := c.zone(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	count := len(c.publicIps.order) + 1
+
+	id := c.newId(idempotencyKey)
+	c.publicIps.put(id, &api.ResourcePublicIpGetResponse{
+		Id:             id,
+		Tags:           cloneTags(tags),
+		Created:        time.Now(),
+		ZoneId:         zone,
+		OrganizationId: c.OrganizationId,
+		DR:             dr,
+		PoolId:         uuid.New(),
+		Status:         "pending",
+		Ip:             fmt.Sprintf("198.18.%d.%d", count>>8&0xff, count&0xff),
+	})
+	c.after(func() {
+		c.publicIps.items[id].Status = "active"
+	})
+
+	return &api.ResourcePublicIpPostResponse{Id: id}, nil
+}
+
+func (c *Client) PatchPublicIp(
+	ctx context.Context,
+	id string, attachedNetworkInterfaceIdPtr **string, tagsPtr *map[string]string,
+) (*api.ResourcePublicIpPatchResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PatchPublicIp"); err != nil {
+		return nil, err
+	}
+
+	publicIp, err := c.publicIps.get("public ip", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if attachedNetworkInterfaceIdPtr != nil && *attachedNetworkInterfaceIdPtr != nil {
+		_, err := c.networkInterfaces.get("network interface", **attachedNetworkInterfaceIdPtr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = patchAttachment(
+		"attached_network_interface_id",
+		&publicIp.AttachedNetworkInterfaceId,
+		attachedNetworkInterfaceIdPtr,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if tagsPtr != nil {
+		publicIp.Tags = cloneTags(*tagsPtr)
+	}
+
+	now := time.Now()
+	publicIp.Modified = &now
+
+	return &api.ResourcePublicIpPatchResponse{Id: publicIp.Id}, nil
+}
+
+func (c *Client) DeletePublicIp(
+	ctx context.Context, id string,
+) (*api.ResourcePublicIpDeleteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "DeletePublicIp"); err != nil {
+		return nil, err
+	}
+
+	publicIp, err := c.publicIps.get("public ip", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if publicIp.Status == "deleted" {
+		return nil, unexpectedStatusError("resource_public_ip", publicIp.Status)
+	}
+
+	now := time.Now()
+	publicIp.AttachedNetworkInterfaceId = nil
+	publicIp.Deleted = &now
+	publicIp.Status = "deleted"
+
+	return &api.ResourcePublicIpDeleteResponse{Id: publicIp.Id, Status: publicIp.Status}, nil
+}
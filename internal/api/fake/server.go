@@ -0,0 +1,704 @@
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"terraform-provider-eci/internal/api"
+)
+
+// Server serves the portal API from a Client over HTTP, so that the provider
+// can be tested end to end, API client included, without a portal.
+//
+// The server speaks the same paths, query parameters and bodies as the portal
+// does: lists are paginated with `skip` and `count`, patches tell absent
+// fields from null ones, and errors are `{code, message, detail}` documents.
+type Server struct {
+	*httptest.Server
+
+	Client *Client
+	Token  string
+
+	requests atomic.Int64
+}
+
+// NewServer starts a server backed by client, which accepts requests bearing
+// token. It must be closed with Close.
+func NewServer(client *Client, token string) *Server {
+	s := &Server{Client: client, Token: token}
+	s.Server = httptest.NewServer(s.authorize(s.routes()))
+	return s
+}
+
+// handler serves the result of a call of Client as JSON.
+type handler func(r *http.Request) (any, error)
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	result, err := h(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// scope is the part of a creation request placing the resource.
+type scope struct {
+	ZoneId         string `json:"zone_id"`
+	OrganizationId string `json:"organization_id"`
+}
+
+// patch is a patch request, where an absent field is left unchanged and a
+// null field is cleared.
+type patch map[string]json.RawMessage
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", strconv.FormatInt(s.requests.Add(1), 10))
+
+		if r.Header.Get("Authorization") != "Bearer "+s.Token {
+			writeError(w, api.NewAPIError(
+				http.StatusUnauthorized, "unauthorized", "invalid access token", nil,
+			))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) routes() http.Handler {
+	c := s.Client
+	mux := http.NewServeMux()
+
+	mux.Handle("/", handler(func(r *http.Request) (any, error) {
+		return nil, notFoundError("route", r.Method+" "+r.URL.Path)
+	}))
+
+	mux.Handle("GET /user/organization", handler(func(r *http.Request) (any, error) {
+		return c.GetOrganization(r.Context())
+	}))
+
+	mux.Handle("GET /user/region", handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetRegions(r.Context(), query(r, "filter_name_ilike")))
+	}))
+	mux.Handle("GET /user/region/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetRegion(r.Context(), r.PathValue("id"))
+	}))
+
+	mux.Handle("GET /user/infra/zone", handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetZones(
+			r.Context(), query(r, "filter_region_id"), query(r, "filter_name_ilike"),
+		))
+	}))
+	mux.Handle("GET /user/infra/zone/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetZone(r.Context(), r.PathValue("id"))
+	}))
+
+	mux.Handle("GET /user/infra/instance_type", handler(func(r *http.Request) (any, error) {
+		filterActivated, err := queryBool(r, "filter_activated")
+		if err != nil {
+			return nil, err
+		}
+		if !s.inZone(r) {
+			return []api.InfraInstanceTypeGetResponse{}, nil
+		}
+
+		return page(r, c.GetInstanceTypes(
+			r.Context(), query(r, "filter_name_ilike"), filterActivated,
+		))
+	}))
+	mux.Handle("GET /user/infra/instance_type/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetInstanceType(r.Context(), r.PathValue("id"))
+	}))
+
+	mux.Handle("GET /user/infra/block_storage_image", handler(func(r *http.Request) (any, error) {
+		if !s.inZone(r) {
+			return []api.ResourceBlockStorageImageGetResponse{}, nil
+		}
+
+		return page(r, c.GetBlockStorageImages(r.Context(), query(r, "filter_name_ilike")))
+	}))
+	mux.Handle(
+		"GET /user/infra/block_storage_image/{id}",
+		handler(func(r *http.Request) (any, error) {
+			return c.GetBlockStorageImage(r.Context(), r.PathValue("id"))
+		}),
+	)
+
+	s.computeRoutes(mux)
+	s.storageRoutes(mux)
+	s.networkRoutes(mux)
+
+	return mux
+}
+
+func (s *Server) computeRoutes(mux *http.ServeMux) {
+	c := s.Client
+	const machines = "/user/resource/compute/virtual_machine"
+	const allocations = "/user/resource/compute/virtual_machine_allocation"
+
+	mux.Handle("GET "+machines, handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetVirtualMachines(r.Context(), query(r, "filter_name_ilike")))
+	}))
+	mux.Handle("GET "+machines+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetVirtualMachine(r.Context(), r.PathValue("id"))
+	}))
+	mux.Handle("POST "+machines, handler(func(r *http.Request) (any, error) {
+		var body struct {
+			scope
+			InstanceTypeId string            `json:"instance_type_id"`
+			Name           string            `json:"name"`
+			AlwaysOn       bool              `json:"always_on"`
+			DR             bool              `json:"dr"`
+			Username       string            `json:"username"`
+			Password       string            `json:"password"`
+			OnInitScript   string            `json:"on_init_script"`
+			Tags           map[string]string `json:"tags"`
+		}
+		if err := s.decodePost(r, &body, &body.scope); err != nil {
+			return nil, err
+		}
+
+		return c.PostVirtualMachine(
+			r.Context(),
+			r.Header.Get(api.IdempotencyKeyHeader),
+			body.ZoneId,
+			body.InstanceTypeId,
+			body.Name,
+			body.AlwaysOn,
+			body.DR,
+			body.Username,
+			body.Password,
+			body.OnInitScript,
+			body.Tags,
+		)
+	}))
+	mux.Handle("PATCH "+machines+"/{id}", handler(func(r *http.Request) (any, error) {
+		body, err := decodePatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		instanceTypeId, err := field[string](body, "instance_type_id")
+		if err != nil {
+			return nil, err
+		}
+		name, err := field[string](body, "name")
+		if err != nil {
+			return nil, err
+		}
+		alwaysOn, err := field[bool](body, "always_on")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := field[map[string]string](body, "tags")
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PatchVirtualMachine(
+			r.Context(), r.PathValue("id"), instanceTypeId, name, alwaysOn, tags,
+		)
+	}))
+	mux.Handle("DELETE "+machines+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.DeleteVirtualMachine(r.Context(), r.PathValue("id"))
+	}))
+
+	mux.Handle("GET "+allocations, handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetVirtualMachineAllocations(
+			r.Context(), query(r, "filter_machine_id"), query(r, "filter_status"),
+		))
+	}))
+	mux.Handle("GET "+allocations+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetVirtualMachineAllocation(r.Context(), r.PathValue("id"))
+	}))
+	mux.Handle("POST "+allocations, handler(func(r *http.Request) (any, error) {
+		var body struct {
+			scope
+			MachineId string            `json:"machine_id"`
+			Tags      map[string]string `json:"tags"`
+		}
+		if err := s.decodePost(r, &body, &body.scope); err != nil {
+			return nil, err
+		}
+
+		return c.PostVirtualMachineAllocation(
+			r.Context(), r.Header.Get(api.IdempotencyKeyHeader), body.ZoneId, body.MachineId, body.Tags,
+		)
+	}))
+	mux.Handle("PATCH "+allocations+"/{id}", handler(func(r *http.Request) (any, error) {
+		body, err := decodePatch(r)
+		if err != nil {
+			return nil, err
+		}
+		tags, err := field[map[string]string](body, "tags")
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PatchVirtualMachineAllocation(r.Context(), r.PathValue("id"), tags)
+	}))
+	mux.Handle("DELETE "+allocations+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.DeleteVirtualMachineAllocation(r.Context(), r.PathValue("id"))
+	}))
+}
+
+func (s *Server) storageRoutes(mux *http.ServeMux) {
+	c := s.Client
+	const storages = "/user/resource/storage/block_storage"
+	const snapshots = "/user/resource/storage/block_storage/snapshot"
+
+	mux.Handle("GET "+storages, handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetBlockStorages(
+			r.Context(), query(r, "filter_attached_machine_id"), query(r, "filter_name_ilike"),
+		))
+	}))
+	mux.Handle("GET "+storages+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetBlockStorage(r.Context(), r.PathValue("id"))
+	}))
+	mux.Handle("POST "+storages, handler(func(r *http.Request) (any, error) {
+		var body struct {
+			scope
+			Name       string            `json:"name"`
+			ImageId    *string           `json:"image_id"`
+			SnapshotId *string           `json:"snapshot_id"`
+			SizeGib    int               `json:"size_gib"`
+			DR         bool              `json:"dr"`
+			Tags       map[string]string `json:"tags"`
+		}
+		if err := s.decodePost(r, &body, &body.scope); err != nil {
+			return nil, err
+		}
+
+		return c.PostBlockStorage(
+			r.Context(),
+			r.Header.Get(api.IdempotencyKeyHeader),
+			body.ZoneId,
+			body.Name,
+			body.ImageId,
+			body.SnapshotId,
+			body.SizeGib,
+			body.DR,
+			body.Tags,
+		)
+	}))
+	mux.Handle("PATCH "+storages+"/{id}", handler(func(r *http.Request) (any, error) {
+		body, err := decodePatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := field[string](body, "name")
+		if err != nil {
+			return nil, err
+		}
+		attachedMachineId, err := field[*string](body, "attached_machine_id")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := field[map[string]string](body, "tags")
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PatchBlockStorage(r.Context(), r.PathValue("id"), name, attachedMachineId, tags)
+	}))
+	mux.Handle("DELETE "+storages+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.DeleteBlockStorage(r.Context(), r.PathValue("id"))
+	}))
+
+	mux.Handle("GET "+snapshots, handler(func(r *http.Request) (any, error) {
+		filterDr, err := queryBool(r, "filter_dr")
+		if err != nil {
+			return nil, err
+		}
+
+		return page(r, c.GetBlockStorageSnapshots(
+			r.Context(),
+			query(r, "filter_zone_id"),
+			query(r, "filter_organization_id"),
+			query(r, "filter_name_ilike"),
+			query(r, "filter_block_storage_id"),
+			query(r, "filter_image_id"),
+			query(r, "filter_status"),
+			filterDr,
+		))
+	}))
+	mux.Handle("GET "+snapshots+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetBlockStorageSnapshot(r.Context(), r.PathValue("id"))
+	}))
+	mux.Handle("POST "+snapshots, handler(func(r *http.Request) (any, error) {
+		var body struct {
+			scope
+			Name           string            `json:"name"`
+			BlockStorageId string            `json:"block_storage_id"`
+			Tags           map[string]string `json:"tags"`
+		}
+		if err := s.decodePost(r, &body, &body.scope); err != nil {
+			return nil, err
+		}
+
+		return c.PostBlockStorageSnapshot(
+			r.Context(),
+			r.Header.Get(api.IdempotencyKeyHeader),
+			body.ZoneId,
+			body.Name, body.BlockStorageId, body.Tags,
+		)
+	}))
+	mux.Handle("PATCH "+snapshots+"/{id}", handler(func(r *http.Request) (any, error) {
+		body, err := decodePatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := field[string](body, "name")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := field[map[string]string](body, "tags")
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PatchBlockStorageSnapshot(r.Context(), r.PathValue("id"), name, tags)
+	}))
+	mux.Handle("DELETE "+snapshots+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.DeleteBlockStorageSnapshot(r.Context(), r.PathValue("id"))
+	}))
+}
+
+func (s *Server) networkRoutes(mux *http.ServeMux) {
+	c := s.Client
+	const networks = "/user/resource/network/virtual_network"
+	const subnets = "/user/resource/network/subnet"
+	const networkInterfaces = "/user/resource/network/network_interface"
+	const publicIps = "/user/resource/network/public_ip"
+
+	mux.Handle("GET "+networks, handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetVirtualNetworks(r.Context(), query(r, "filter_name_ilike")))
+	}))
+	mux.Handle("GET "+networks+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetVirtualNetwork(r.Context(), r.PathValue("id"))
+	}))
+	mux.Handle("POST "+networks, handler(func(r *http.Request) (any, error) {
+		var body struct {
+			scope
+			Name        string            `json:"name"`
+			NetworkCidr string            `json:"network_cidr"`
+			Tags        map[string]string `json:"tags"`
+		}
+		if err := s.decodePost(r, &body, &body.scope); err != nil {
+			return nil, err
+		}
+
+		return c.PostVirtualNetwork(
+			r.Context(),
+			r.Header.Get(api.IdempotencyKeyHeader),
+			body.ZoneId,
+			body.Name, body.NetworkCidr, body.Tags,
+		)
+	}))
+	mux.Handle("PATCH "+networks+"/{id}", handler(func(r *http.Request) (any, error) {
+		body, err := decodePatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := field[string](body, "name")
+		if err != nil {
+			return nil, err
+		}
+		firewallRules, err := field[[]api.NetworkFirewallRule](body, "firewall_rules")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := field[map[string]string](body, "tags")
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PatchVirtualNetwork(r.Context(), r.PathValue("id"), name, firewallRules, tags)
+	}))
+	mux.Handle("DELETE "+networks+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.DeleteVirtualNetwork(r.Context(), r.PathValue("id"))
+	}))
+
+	mux.Handle("GET "+subnets, handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetSubnets(r.Context(), query(r, "filter_attached_network_id")))
+	}))
+	mux.Handle("GET "+subnets+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetSubnet(r.Context(), r.PathValue("id"))
+	}))
+	mux.Handle("POST "+subnets, handler(func(r *http.Request) (any, error) {
+		var body struct {
+			scope
+			Name              string            `json:"name"`
+			AttachedNetworkId string            `json:"attached_network_id"`
+			Purpose           string            `json:"purpose"`
+			NetworkGw         string            `json:"network_gw"`
+			Tags              map[string]string `json:"tags"`
+		}
+		if err := s.decodePost(r, &body, &body.scope); err != nil {
+			return nil, err
+		}
+
+		return c.PostSubnet(
+			r.Context(),
+			r.Header.Get(api.IdempotencyKeyHeader),
+			body.ZoneId,
+			body.Name, body.AttachedNetworkId, body.Purpose, body.NetworkGw, body.Tags,
+		)
+	}))
+	mux.Handle("PATCH "+subnets+"/{id}", handler(func(r *http.Request) (any, error) {
+		body, err := decodePatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := field[string](body, "name")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := field[map[string]string](body, "tags")
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PatchSubnet(r.Context(), r.PathValue("id"), name, tags)
+	}))
+	mux.Handle("DELETE "+subnets+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.DeleteSubnet(r.Context(), r.PathValue("id"))
+	}))
+
+	mux.Handle("GET "+networkInterfaces, handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetNetworkInterfaces(
+			r.Context(), query(r, "filter_attached_machine_id"),
+		))
+	}))
+	mux.Handle("GET "+networkInterfaces+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetNetworkInterface(r.Context(), r.PathValue("id"))
+	}))
+	mux.Handle("POST "+networkInterfaces, handler(func(r *http.Request) (any, error) {
+		var body struct {
+			scope
+			Name             string            `json:"name"`
+			AttachedSubnetId string            `json:"attached_subnet_id"`
+			DR               bool              `json:"dr"`
+			Ip               *string           `json:"ip"`
+			Mac              *string           `json:"mac"`
+			Tags             map[string]string `json:"tags"`
+		}
+		if err := s.decodePost(r, &body, &body.scope); err != nil {
+			return nil, err
+		}
+
+		return c.PostNetworkInterface(
+			r.Context(),
+			r.Header.Get(api.IdempotencyKeyHeader),
+			body.ZoneId,
+			body.Name,
+			body.AttachedSubnetId,
+			body.DR,
+			body.Ip,
+			body.Mac,
+			body.Tags,
+		)
+	}))
+	mux.Handle("PATCH "+networkInterfaces+"/{id}", handler(func(r *http.Request) (any, error) {
+		body, err := decodePatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name, err := field[string](body, "name")
+		if err != nil {
+			return nil, err
+		}
+		attachedMachineId, err := field[*string](body, "attached_machine_id")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := field[map[string]string](body, "tags")
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PatchNetworkInterface(
+			r.Context(), r.PathValue("id"), name, attachedMachineId, tags,
+		)
+	}))
+	mux.Handle("DELETE "+networkInterfaces+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.DeleteNetworkInterface(r.Context(), r.PathValue("id"))
+	}))
+
+	mux.Handle("GET "+publicIps, handler(func(r *http.Request) (any, error) {
+		return page(r, c.GetPublicIps(
+			r.Context(), query(r, "filter_attached_network_interface_id"),
+		))
+	}))
+	mux.Handle("GET "+publicIps+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.GetPublicIp(r.Context(), r.PathValue("id"))
+	}))
+	mux.Handle("POST "+publicIps, handler(func(r *http.Request) (any, error) {
+		var body struct {
+			scope
+			DR   bool              `json:"dr"`
+			Tags map[string]string `json:"tags"`
+		}
+		if err := s.decodePost(r, &body, &body.scope); err != nil {
+			return nil, err
+		}
+
+		return c.PostPublicIp(
+			r.Context(), r.Header.Get(api.IdempotencyKeyHeader), body.ZoneId, body.DR, body.Tags,
+		)
+	}))
+	mux.Handle("PATCH "+publicIps+"/{id}", handler(func(r *http.Request) (any, error) {
+		body, err := decodePatch(r)
+		if err != nil {
+			return nil, err
+		}
+
+		attachedNetworkInterfaceId, err := field[*string](body, "attached_network_interface_id")
+		if err != nil {
+			return nil, err
+		}
+		tags, err := field[map[string]string](body, "tags")
+		if err != nil {
+			return nil, err
+		}
+
+		return c.PatchPublicIp(r.Context(), r.PathValue("id"), attachedNetworkInterfaceId, tags)
+	}))
+	mux.Handle("DELETE "+publicIps+"/{id}", handler(func(r *http.Request) (any, error) {
+		return c.DeletePublicIp(r.Context(), r.PathValue("id"))
+	}))
+}
+
+// inZone reports whether the zone filter of a request, if any, matches the
+// zone of the client.
+func (s *Server) inZone(r *http.Request) bool {
+	zoneId := query(r, "filter_zone_id")
+	return zoneId == nil || *zoneId == s.Client.ZoneId.String()
+}
+
+// decodePost decodes the body of a creation request, checking that it places
+// the resource in the organization of the client. The zone is checked by the
+// client.
+func (s *Server) decodePost(r *http.Request, body any, scope *scope) error {
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		return validationError("body", err.Error())
+	}
+
+	if scope.OrganizationId != s.Client.OrganizationId.String() {
+		return notFoundError("organization", scope.OrganizationId)
+	}
+
+	return nil
+}
+
+func decodePatch(r *http.Request) (patch, error) {
+	body := patch{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, validationError("body", err.Error())
+	}
+	return body, nil
+}
+
+// field returns a field of a patch, or nil if it is absent. A nullable field
+// is read as a pointer, which is nil if the field is null.
+func field[T any](body patch, key string) (*T, error) {
+	raw, ok := body[key]
+	if !ok {
+		return nil, nil
+	}
+
+	var value T
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, validationError(key, err.Error())
+	}
+	return &value, nil
+}
+
+func query(r *http.Request, key string) *string {
+	values := r.URL.Query()
+	if !values.Has(key) {
+		return nil
+	}
+
+	value := values.Get(key)
+	return &value
+}
+
+func queryBool(r *http.Request, key string) (*bool, error) {
+	value := query(r, key)
+	if value == nil {
+		return nil, nil
+	}
+
+	parsed, err := strconv.ParseBool(*value)
+	if err != nil {
+		return nil, validationError(key, err.Error())
+	}
+	return &parsed, nil
+}
+
+// page returns the page of items selected by the `skip` and `count` query
+// parameters of a request. Without them, all items are returned.
+func page[T any](r *http.Request, items iter.Seq2[T, error]) (any, error) {
+	skip, count := 0, -1
+
+	for key, target := range map[string]*int{"skip": &skip, "count": &count} {
+		value := query(r, key)
+		if value == nil {
+			continue
+		}
+
+		parsed, err := strconv.Atoi(*value)
+		if err != nil || parsed < 0 {
+			return nil, validationError(key, "must be a non-negative integer")
+		}
+		*target = parsed
+	}
+
+	result := []T{}
+	index := 0
+	for item, err := range items {
+		if err != nil {
+			return nil, err
+		}
+		if index >= skip && (count < 0 || len(result) < count) {
+			result = append(result, item)
+		}
+		index++
+	}
+
+	return result, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var apiError *api.APIError
+	if !errors.As(err, &apiError) {
+		apiError = api.NewAPIError(http.StatusInternalServerError, "internal", err.Error(), nil)
+	}
+
+	body := struct {
+		Code    *string         `json:"code"`
+		Message *string         `json:"message"`
+		Detail  json.RawMessage `json:"detail"`
+	}{apiError.Code, apiError.Message, apiError.Detail}
+
+	writeJSON(w, apiError.HttpCode, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,355 @@
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"terraform-provider-eci/internal/api"
+	"terraform-provider-eci/internal/api/fake"
+	"testing"
+	"time"
+)
+
+const token = "token"
+
+// newAPIClient returns a client of the portal API talking to a server backed
+// by client, in the zone of client.
+func newAPIClient(t *testing.T, client *fake.Client) *api.APIClient {
+	t.Helper()
+
+	return newAPIClientInZone(t, client, api.ZoneSelector{Id: client.ZoneId.String()})
+}
+
+// newAPIClientInZone is newAPIClient in the zone that zone selects.
+func newAPIClientInZone(
+	t *testing.T, client *fake.Client, zone api.ZoneSelector,
+) *api.APIClient {
+	t.Helper()
+
+	server := fake.NewServer(client, token)
+	t.Cleanup(server.Close)
+
+	return api.NewAPIClient(
+		token,
+		server.URL,
+		"",
+		zone,
+		0,
+		api.DefaultRequestsPerSecond,
+		api.DefaultMaxConcurrentRequests,
+	)
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitStatus polls get until it returns status.
+func waitStatus(t *testing.T, status string, get func() (string, error)) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := get()
+		check(t, err)
+		if got == status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status: got %s, want %s", got, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerRejectsInvalidToken(t *testing.T) {
+	server := fake.NewServer(fake.NewClient(), token)
+	defer server.Close()
+
+	apiClient := api.NewAPIClient(
+		"invalid", server.URL, "", api.ZoneSelector{}, 0,
+		api.DefaultRequestsPerSecond, api.DefaultMaxConcurrentRequests,
+	)
+
+	_, err := apiClient.OrganizationId(context.Background())
+	if !errors.Is(err, api.ErrUnauthorized) {
+		t.Fatalf("got %v, want %v", err, api.ErrUnauthorized)
+	}
+}
+
+func TestAPIClientGetsOrganizationOnce(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+	apiClient := newAPIClient(t, client)
+
+	countCalls := func() int {
+		return len(slices.DeleteFunc(client.Calls(), func(call string) bool {
+			return call != "GetOrganization"
+		}))
+	}
+
+	if calls := countCalls(); calls != 0 {
+		t.Fatalf("organization was got %d times before any request", calls)
+	}
+
+	for i := range 2 {
+		name := fmt.Sprintf("network-%d", i)
+		_, err := apiClient.PostVirtualNetwork(
+			ctx, name, client.ZoneId.String(), name, "10.0.0.0/16", nil,
+		)
+		check(t, err)
+	}
+
+	if calls := countCalls(); calls != 1 {
+		t.Errorf("organization was got %d times, want 1", calls)
+	}
+}
+
+func TestAPIClientSelectsZoneByName(t *testing.T) {
+	client := fake.NewClient()
+	seoul := client.AddRegion(api.RegionGetResponse{Name: "seoul-1"})
+	busan := client.AddRegion(api.RegionGetResponse{Name: "busan-1"})
+	client.AddRegion(api.RegionGetResponse{Name: "tokyo-1"})
+	client.AddRegion(api.RegionGetResponse{Name: "tokyo-1"})
+	seoulA := client.AddZone(api.InfraZoneGetResponse{Name: "zone-a", RegionId: seoul.Id})
+	client.AddZone(api.InfraZoneGetResponse{Name: "zone-ab", RegionId: seoul.Id})
+	busanA := client.AddZone(api.InfraZoneGetResponse{Name: "zone-a", RegionId: busan.Id})
+
+	for name, test := range map[string]struct {
+		zoneName   string
+		regionName string
+		want       string
+		err        string
+	}{
+		"zone and region": {
+			zoneName: "zone-a", regionName: "seoul-1", want: seoulA.Id.String(),
+		},
+		"names are case insensitive": {
+			zoneName: "ZONE-A", regionName: "Busan-1", want: busanA.Id.String(),
+		},
+		"only zone of region": {
+			regionName: "busan-1", want: busanA.Id.String(),
+		},
+		"ambiguous zone": {
+			zoneName: "zone-a", err: "ambiguous zone",
+		},
+		"ambiguous zone of region": {
+			regionName: "seoul-1", err: "ambiguous zone",
+		},
+		"no such zone": {
+			zoneName: "zone-b", regionName: "seoul-1", err: "no such zone",
+		},
+		"no such region": {
+			zoneName: "zone-a", regionName: "seoul-2", err: "no such region",
+		},
+		"ambiguous region": {
+			zoneName: "zone-a", regionName: "tokyo-1", err: "ambiguous region",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			apiClient := newAPIClientInZone(t, client, api.ZoneSelector{
+				Name: test.zoneName, RegionName: test.regionName,
+			})
+
+			zoneId, err := apiClient.ZoneId(context.Background())
+
+			if test.err == "" {
+				check(t, err)
+				if zoneId != test.want {
+					t.Errorf("zone id: got %s, want %s", zoneId, test.want)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected an error, got zone %s", zoneId)
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got %q, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestAPIClientLooksUpZoneOnce(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+	region := client.AddRegion(api.RegionGetResponse{Name: "seoul-1"})
+	client.AddZone(api.InfraZoneGetResponse{
+		Id: client.ZoneId, Name: "zone-a", RegionId: region.Id,
+	})
+	client.AddInstanceType(api.InfraInstanceTypeGetResponse{Name: "small", Activated: true})
+	apiClient := newAPIClientInZone(t, client, api.ZoneSelector{Name: "zone-a"})
+
+	countCalls := func() int {
+		return len(slices.DeleteFunc(client.Calls(), func(call string) bool {
+			return call != "GetZones"
+		}))
+	}
+
+	if calls := countCalls(); calls != 0 {
+		t.Fatalf("zone was looked up %d times before any request", calls)
+	}
+
+	// Listing instance types filters them by the zone.
+	instanceTypes, err := api.Collect(apiClient.GetInstanceTypes(ctx, nil, nil))
+	check(t, err)
+	if len(instanceTypes) != 1 {
+		t.Errorf("instance types: got %d, want 1", len(instanceTypes))
+	}
+
+	zoneId, err := apiClient.ZoneId(ctx)
+	check(t, err)
+	if zoneId != client.ZoneId.String() {
+		t.Errorf("zone id: got %s, want %s", zoneId, client.ZoneId)
+	}
+
+	if calls := countCalls(); calls != 1 {
+		t.Errorf("zone was looked up %d times, want 1", calls)
+	}
+}
+
+func TestServerBlockStorageLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+	client.SetTransitionDelay(20 * time.Millisecond)
+	apiClient := newAPIClient(t, client)
+
+	instanceType := client.AddInstanceType(api.InfraInstanceTypeGetResponse{Name: "small"})
+	machine, err := apiClient.PostVirtualMachine(
+		ctx, "machine", client.ZoneId.String(),
+		instanceType.Id.String(), "vm", false, false, "elice", "secret", "", nil,
+	)
+	check(t, err)
+
+	storage, err := apiClient.PostBlockStorage(
+		ctx, "storage", client.ZoneId.String(), "disk", nil, nil, 10, false, nil,
+	)
+	check(t, err)
+	storageId := storage.Id.String()
+
+	getStatus := func() (string, error) {
+		storage, err := apiClient.GetBlockStorage(ctx, storageId)
+		if err != nil {
+			return "", err
+		}
+		return storage.Status, nil
+	}
+
+	status, err := getStatus()
+	check(t, err)
+	if status != "assigned" {
+		t.Errorf("status after creation: got %s, want assigned", status)
+	}
+	waitStatus(t, "prepared", getStatus)
+
+	machineId := machine.Id.String()
+	attachedMachineIdPtr := &machineId
+	_, err = apiClient.PatchBlockStorage(ctx, storageId, nil, &attachedMachineIdPtr, nil)
+	check(t, err)
+
+	attached, err := apiClient.GetBlockStorage(ctx, storageId)
+	check(t, err)
+	if attached.AttachedMachineId == nil || *attached.AttachedMachineId != machine.Id {
+		t.Errorf("attached machine: got %v, want %s", attached.AttachedMachineId, machineId)
+	}
+
+	var detachedMachineIdPtr *string
+	_, err = apiClient.PatchBlockStorage(ctx, storageId, nil, &detachedMachineIdPtr, nil)
+	check(t, err)
+
+	detached, err := apiClient.GetBlockStorage(ctx, storageId)
+	check(t, err)
+	if detached.AttachedMachineId != nil {
+		t.Errorf("block storage is still attached to %s", detached.AttachedMachineId)
+	}
+	if detached.Name != "disk" {
+		t.Errorf("name: got %s, want disk", detached.Name)
+	}
+
+	_, err = apiClient.DeleteBlockStorage(ctx, storageId)
+	check(t, err)
+
+	_, err = apiClient.DeleteBlockStorage(ctx, storageId)
+	var unexpectedStatus *api.ErrUnexpectedStatus
+	if !errors.As(err, &unexpectedStatus) {
+		t.Fatalf("got %v, want *api.ErrUnexpectedStatus", err)
+	}
+	want := api.ErrUnexpectedStatus{Resource: "resource_block_storage", Status: "deleted"}
+	if *unexpectedStatus != want {
+		t.Errorf("got %+v, want %+v", *unexpectedStatus, want)
+	}
+}
+
+func TestServerAllocationLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+	client.SetTransitionDelay(20 * time.Millisecond)
+	apiClient := newAPIClient(t, client)
+
+	instanceType := client.AddInstanceType(api.InfraInstanceTypeGetResponse{Name: "small"})
+	machine, err := apiClient.PostVirtualMachine(
+		ctx, "machine", client.ZoneId.String(),
+		instanceType.Id.String(), "vm", false, false, "elice", "secret", "", nil,
+	)
+	check(t, err)
+
+	allocation, err := apiClient.PostVirtualMachineAllocation(
+		ctx, "allocation", client.ZoneId.String(), machine.Id.String(), nil,
+	)
+	check(t, err)
+
+	getStatus := func() (string, error) {
+		allocation, err := apiClient.GetVirtualMachineAllocation(ctx, allocation.Id.String())
+		if err != nil {
+			return "", err
+		}
+		return allocation.Status, nil
+	}
+	waitStatus(t, "started", getStatus)
+
+	deleted, err := apiClient.DeleteVirtualMachineAllocation(ctx, allocation.Id.String())
+	check(t, err)
+	if deleted.Status != "terminating" {
+		t.Errorf("status after deletion: got %s, want terminating", deleted.Status)
+	}
+	waitStatus(t, "terminated", getStatus)
+
+	waitStatus(t, "idle", func() (string, error) {
+		machine, err := apiClient.GetVirtualMachine(ctx, machine.Id.String())
+		if err != nil {
+			return "", err
+		}
+		return machine.Status, nil
+	})
+}
+
+func TestServerPaginatesLists(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+	apiClient := newAPIClient(t, client)
+
+	const count = 150
+	for i := range count {
+		_, err := client.PostVirtualNetwork(
+			ctx, "", client.ZoneId.String(), fmt.Sprintf("network-%d", i), "10.0.0.0/16", nil,
+		)
+		check(t, err)
+	}
+
+	networks, err := api.Collect(apiClient.GetVirtualNetworks(ctx, nil))
+	check(t, err)
+	if len(networks) != count {
+		t.Fatalf("got %d virtual networks, want %d", len(networks), count)
+	}
+	for i, network := range networks {
+		if want := fmt.Sprintf("network-%d", i); network.Name != want {
+			t.Errorf("virtual network %d: got %s, want %s", i, network.Name, want)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"terraform-provider-eci/internal/api"
+	"time"
+)
+
+func (c *Client) GetBlockStorage(
+	ctx context.Context, id string,
+) (*api.ResourceBlockStorageGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetBlockStorage"); err != nil {
+		return nil, err
+	}
+
+	return c.blockStorages.read("block storage", id)
+}
+
+func (c *Client) GetBlockStorages(
+	ctx context.Context, filterAttachedMachineId *string, filterNameIlike *string,
+) iter.Seq2[api.ResourceBlockStorageGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetBlockStorages"); err != nil {
+		return failed[api.ResourceBlockStorageGetResponse](err)
+	}
+
+	return c.blockStorages.list(func(storage *api.ResourceBlockStorageGetResponse) bool {
+		return matchesId(storage.AttachedMachineId, filterAttachedMachineId) &&
+			matchesIlike(storage.Name, filterNameIlike)
+	})
+}
+
+func (c *Client) PostBlockStorage(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	name string,
+	imageId *string,
+	snapshotId *string,
+	sizeGiB int,
+	dr bool,
+	tags map[string]string,
+) (*api.ResourceBlockStoragePostResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PostBlockStorage"); err != nil {
+		return nil, err
+	}
+
+	if id, ok := c.created(idempotencyKey); ok {
+		return &api.ResourceBlockStoragePostResponse{Id: id}, nil
+	}
+
+	zone, err := c.zone(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedImageId, err := parseOptionalId("image_id", imageId)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedSnapshotId, err := parseOptionalId("snapshot_id", snapshotId)
+	if err != nil {
+		return nil, err
+	}
+
+	if imageId != nil {
+		image, err := c.images.get("block storage image", *imageId)
+		if err != nil {
+			return nil, err
+		}
+		if sizeGiB < image.SizeGib {
+			return nil, validationError(
+				"size_gib", fmt.Sprintf("must be at least %d", image.SizeGib),
+			)
+		}
+	}
+
+	if snapshotId != nil {
+		if _, err := c.snapshots.get("block storage snapshot", *snapshotId); err != nil {
+			return nil, err
+		}
+	}
+
+	id := c.newId(idempotencyKey)
+	now := time.Now()
+	c.blockStorages.put(id, &api.ResourceBlockStorageGetResponse{
+		Id:             id,
+		Name:           name,
+		Tags:           cloneTags(tags),
+		Created:        now,
+		ZoneId:         zone,
+		OrganizationId: c.OrganizationId,
+		ImageId:        parsedImageId,
+		SnapshotId:     parsedSnapshotId,
+		SizeGib:        sizeGiB,
+		DR:             dr,
+		Assigned:       &now,
+		Status:         "assigned",
+	})
+	c.after(func() {
+		storage := c.blockStorages.items[id]
+		prepared := time.Now()
+		storage.Prepared = &prepared
+		storage.Status = "prepared"
+	})
+
+	return &api.ResourceBlockStoragePostResponse{Id: id}, nil
+}
+
+func (c *Client) PatchBlockStorage(
+	ctx context.Context,
+	id string, namePtr *string, attachedMachineIdPtr **string, tagsPtr *map[string]string,
+) (*api.ResourceBlockStoragePatchResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PatchBlockStorage"); err != nil {
+		return nil, err
+	}
+
+	storage, err := c.blockStorages.get("block storage", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if attachedMachineIdPtr != nil && *attachedMachineIdPtr != nil {
+		if _, err := c.virtualMachines.get("virtual machine", **attachedMachineIdPtr); err != nil {
+			return nil, err
+		}
+	}
+
+	err = patchAttachment("attached_machine_id", &storage.AttachedMachineId, attachedMachineIdPtr)
+	if err != nil {
+		return nil, err
+	}
+	if namePtr != nil {
+		storage.Name = *namePtr
+	}
+	if tagsPtr != nil {
+		storage.Tags = cloneTags(*tagsPtr)
+	}
+
+	now := time.Now()
+	storage.Modified = &now
+
+	return &api.ResourceBlockStoragePatchResponse{Id: storage.Id}, nil
+}
+
+func (c *Client) DeleteBlockStorage(
+	ctx context.Context, id string,
+) (*api.ResourceBlockStorageDeleteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "DeleteBlockStorage"); err != nil {
+		return nil, err
+	}
+
+	storage, err := c.blockStorages.get("block storage", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if storage.Status == "deleted" {
+		return nil, unexpectedStatusError("resource_block_storage", storage.Status)
+	}
+
+	now := time.Now()
+	storage.AttachedMachineId = nil
+	storage.Deleting = &now
+	storage.Deleted = &now
+	storage.Status = "deleted"
+
+	return &api.ResourceBlockStorageDeleteResponse{Id: storage.Id, Status: storage.Status}, nil
+}
+
+func (c *Client) GetBlockStorageSnapshot(
+	ctx context.Context, id string,
+) (*api.ResourceBlockStorageSnapshotGetResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetBlockStorageSnapshot"); err != nil {
+		return nil, err
+	}
+
+	return c.snapshots.read("block storage snapshot", id)
+}
+
+func (c *Client) GetBlockStorageSnapshots(
+	ctx context.Context,
+	filterZoneId *string,
+	filterOrganizationId *string,
+	filterNameIlike *string,
+	filterBlockStorageId *string,
+	filterImageId *string,
+	filterStatus *string,
+	filterDr *bool,
+) iter.Seq2[api.ResourceBlockStorageSnapshotGetResponse, error] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "GetBlockStorageSnapshots"); err != nil {
+		return failed[api.ResourceBlockStorageSnapshotGetResponse](err)
+	}
+
+	return c.snapshots.list(func(snapshot *api.ResourceBlockStorageSnapshotGetResponse) bool {
+		return matchesId(&snapshot.ZoneId, filterZoneId) &&
+			matchesId(&snapshot.OrganizationId, filterOrganizationId) &&
+			matchesIlike(snapshot.Name, filterNameIlike) &&
+			matchesId(&snapshot.BlockStorageId, filterBlockStorageId) &&
+			matchesId(snapshot.ImageId, filterImageId) &&
+			(filterStatus == nil || snapshot.Status == *filterStatus) &&
+			(filterDr == nil || snapshot.DR == *filterDr)
+	})
+}
+
+func (c *Client) PostBlockStorageSnapshot(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	name string, blockStorageId string, tags map[string]string,
+) (*api.ResourceBlockStoragePostResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PostBlockStorageSnapshot"); err != nil {
+		return nil, err
+	}
+
+	if id, ok := c.created(idempotencyKey); ok {
+		return &api.ResourceBlockStoragePostResponse{Id: id}, nil
+	}
+
+	zone, err := c.zone(zoneId)
+	if err != nil {
+		return nil, err
+	}
+
+	storage, err := c.blockStorages.get("block storage", blockStorageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if storage.Status != "prepared" {
+		return nil, unexpectedStatusError("resource_block_storage", storage.Status)
+	}
+
+	id := c.newId(idempotencyKey)
+	now := time.Now()
+	c.snapshots.put(id, &api.ResourceBlockStorageSnapshotGetResponse{
+		Id:             id,
+		Name:           name,
+		Tags:           cloneTags(tags),
+		Created:        now,
+		ZoneId:         zone,
+		OrganizationId: c.OrganizationId,
+		BlockStorageId: storage.Id,
+		ImageId:        storage.ImageId,
+		SizeGib:        storage.SizeGib,
+		Assigned:       &now,
+		DR:             storage.DR,
+		Status:         "assigned",
+	})
+	c.after(func() {
+		snapshot := c.snapshots.items[id]
+		prepared := time.Now()
+		snapshot.Prepared = &prepared
+		snapshot.Status = "prepared"
+	})
+
+	return &api.ResourceBlockStoragePostResponse{Id: id}, nil
+}
+
+func (c *Client) PatchBlockStorageSnapshot(
+	ctx context.Context, id string, namePtr *string, tagsPtr *map[string]string,
+) (*api.ResourceBlockStoragePatchResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "PatchBlockStorageSnapshot"); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := c.snapshots.get("block storage snapshot", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if namePtr != nil {
+		snapshot.Name = *namePtr
+	}
+	if tagsPtr != nil {
+		snapshot.Tags = cloneTags(*tagsPtr)
+	}
+
+	now := time.Now()
+	snapshot.Modified = &now
+
+	return &api.ResourceBlockStoragePatchResponse{Id: snapshot.Id}, nil
+}
+
+func (c *Client) DeleteBlockStorageSnapshot(
+	ctx context.Context, id string,
+) (*api.ResourceBlockStorageDeleteResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.call(ctx, "DeleteBlockStorageSnapshot"); err != nil {
+		return nil, err
+	}
+
+	snapshot, err := c.snapshots.get("block storage snapshot", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot.Status == "deleted" {
+		return nil, unexpectedStatusError("resource_block_storage_snapshot", snapshot.Status)
+	}
+
+	now := time.Now()
+	snapshot.Deleting = &now
+	snapshot.Deleted = &now
+	snapshot.Status = "deleted"
+
+	return &api.ResourceBlockStorageDeleteResponse{Id: snapshot.Id, Status: snapshot.Status}, nil
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"strconv"
 	"time"
 
@@ -23,8 +25,11 @@ type InfraInstanceTypeGetResponse struct {
 	Activated    bool              `json:"activated"`
 }
 
-func (api *APIClient) GetInstanceType(id string) (*InfraInstanceTypeGetResponse, error) {
+func (api *APIClient) GetInstanceType(
+	ctx context.Context, id string,
+) (*InfraInstanceTypeGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&InfraInstanceTypeGetResponse{}).
 		Get(fmt.Sprintf("%s/user/infra/instance_type/%s", api.pathPrefix, id))
 
@@ -32,23 +37,17 @@ func (api *APIClient) GetInstanceType(id string) (*InfraInstanceTypeGetResponse,
 }
 
 func (api *APIClient) GetInstanceTypes(
-	filterNameIlike *string, filterActivated *bool, skip int, count int,
-) ([]InfraInstanceTypeGetResponse, error) {
-	params := map[string]string{
-		"filter_zone_id": api.ZoneId,
-		"skip":           strconv.FormatInt(int64(skip), 10),
-		"count":          strconv.FormatInt(int64(count), 10),
-	}
+	ctx context.Context,
+	filterNameIlike *string, filterActivated *bool,
+) iter.Seq2[InfraInstanceTypeGetResponse, error] {
+	params := map[string]string{}
 	setStrIfNotNil(params, "filter_name_ilike", filterNameIlike)
 
 	if filterActivated != nil {
 		params["filter_activated"] = strconv.FormatBool(*filterActivated)
 	}
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]InfraInstanceTypeGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/infra/instance_type", api.pathPrefix))
-
-	return handleListAPIResponse[InfraInstanceTypeGetResponse](resp, err)
+	return paginateInZone[InfraInstanceTypeGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/infra/instance_type", api.pathPrefix), params,
+	)
 }
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"iter"
+)
+
+// ComputeClient manages virtual machines and their allocations.
+type ComputeClient interface {
+	GetVirtualMachine(ctx context.Context, id string) (*ResourceVirtualMachineGetResponse, error)
+	GetVirtualMachines(
+		ctx context.Context, filterNameIlike *string,
+	) iter.Seq2[ResourceVirtualMachineGetResponse, error]
+	PostVirtualMachine(
+		ctx context.Context,
+		idempotencyKey string,
+		zoneId string,
+		instanceTypeId string,
+		name string,
+		alwaysOn bool,
+		DR bool,
+		username string,
+		password string,
+		onInitScript string,
+		tags map[string]string,
+	) (*ResourceVirtualMachinePostResponse, error)
+	PatchVirtualMachine(
+		ctx context.Context,
+		id string,
+		instanceTypeIdPtr *string,
+		namePtr *string,
+		alwaysOnPtr *bool,
+		tagsPtr *map[string]string,
+	) (*ResourceVirtualMachinePatchResponse, error)
+	DeleteVirtualMachine(
+		ctx context.Context, id string,
+	) (*ResourceVirtualMachineDeleteResponse, error)
+
+	GetVirtualMachineAllocation(
+		ctx context.Context, id string,
+	) (*ResourceVirtualMachineAllocationGetResponse, error)
+	GetVirtualMachineAllocations(
+		ctx context.Context, filterMachineIdPtr *string, filterStatusPtr *string,
+	) iter.Seq2[ResourceVirtualMachineAllocationGetResponse, error]
+	PostVirtualMachineAllocation(
+		ctx context.Context,
+		idempotencyKey string,
+		zoneId string,
+		machineId string,
+		tags map[string]string,
+	) (*ResourceVirtualMachineAllocationPostResponse, error)
+	PatchVirtualMachineAllocation(
+		ctx context.Context, id string, tagsPtr *map[string]string,
+	) (*ResourceVirtualMachineAllocationPatchResponse, error)
+	DeleteVirtualMachineAllocation(
+		ctx context.Context, id string,
+	) (*ResourceVirtualMachineAllocationDeleteResponse, error)
+}
+
+// StorageClient manages block storages and their snapshots.
+type StorageClient interface {
+	GetBlockStorage(ctx context.Context, id string) (*ResourceBlockStorageGetResponse, error)
+	GetBlockStorages(
+		ctx context.Context, filterAttachedMachineId *string, filterNameIlike *string,
+	) iter.Seq2[ResourceBlockStorageGetResponse, error]
+	PostBlockStorage(
+		ctx context.Context,
+		idempotencyKey string,
+		zoneId string,
+		name string,
+		imageId *string,
+		snapshotId *string,
+		sizeGiB int,
+		dr bool,
+		tags map[string]string,
+	) (*ResourceBlockStoragePostResponse, error)
+	PatchBlockStorage(
+		ctx context.Context,
+		id string, namePtr *string, attachedMachineIdPtr **string, tagsPtr *map[string]string,
+	) (*ResourceBlockStoragePatchResponse, error)
+	DeleteBlockStorage(ctx context.Context, id string) (*ResourceBlockStorageDeleteResponse, error)
+
+	GetBlockStorageSnapshot(
+		ctx context.Context, id string,
+	) (*ResourceBlockStorageSnapshotGetResponse, error)
+	GetBlockStorageSnapshots(
+		ctx context.Context,
+		filterZoneId *string,
+		filterOrganizationId *string,
+		filterNameIlike *string,
+		filterBlockStorageId *string,
+		filterImageId *string,
+		filterStatus *string,
+		filterDr *bool,
+	) iter.Seq2[ResourceBlockStorageSnapshotGetResponse, error]
+	PostBlockStorageSnapshot(
+		ctx context.Context,
+		idempotencyKey string,
+		zoneId string,
+		name string, blockStorageId string, tags map[string]string,
+	) (*ResourceBlockStoragePostResponse, error)
+	PatchBlockStorageSnapshot(
+		ctx context.Context, id string, namePtr *string, tagsPtr *map[string]string,
+	) (*ResourceBlockStoragePatchResponse, error)
+	DeleteBlockStorageSnapshot(
+		ctx context.Context, id string,
+	) (*ResourceBlockStorageDeleteResponse, error)
+}
+
+// NetworkClient manages virtual networks, subnets, network interfaces and
+// public ips.
+type NetworkClient interface {
+	GetVirtualNetwork(ctx context.Context, id string) (*ResourceVirtualNetworkGetResponse, error)
+	GetVirtualNetworks(
+		ctx context.Context, filterNameIlike *string,
+	) iter.Seq2[ResourceVirtualNetworkGetResponse, error]
+	PostVirtualNetwork(
+		ctx context.Context,
+		idempotencyKey string,
+		zoneId string,
+		name string, networkCidr string, tags map[string]string,
+	) (*ResourceVirtualNetworkPostResponse, error)
+	PatchVirtualNetwork(
+		ctx context.Context,
+		id string,
+		namePtr *string,
+		firewallRulesPtr *[]NetworkFirewallRule,
+		tags *map[string]string,
+	) (*ResourceVirtualNetworkPatchResponse, error)
+	DeleteVirtualNetwork(
+		ctx context.Context, id string,
+	) (*ResourceVirtualNetworkDeleteResponse, error)
+
+	GetSubnet(ctx context.Context, id string) (*ResourceSubnetGetResponse, error)
+	GetSubnets(
+		ctx context.Context, filterAttachedNetworkId *string,
+	) iter.Seq2[ResourceSubnetGetResponse, error]
+	PostSubnet(
+		ctx context.Context,
+		idempotencyKey string,
+		zoneId string,
+		name string,
+		attachedNetworkId string,
+		purpose string,
+		networkGw string,
+		tags map[string]string,
+	) (*ResourceSubnetPostResponse, error)
+	PatchSubnet(
+		ctx context.Context, id string, namePtr *string, tagsPtr *map[string]string,
+	) (*ResourceSubnetPatchResponse, error)
+	DeleteSubnet(ctx context.Context, id string) (*ResourceSubnetDeleteResponse, error)
+
+	GetNetworkInterface(
+		ctx context.Context, id string,
+	) (*ResourceNetworkInterfaceGetResponse, error)
+	GetNetworkInterfaces(
+		ctx context.Context, filterAttachedMachineIdPtr *string,
+	) iter.Seq2[ResourceNetworkInterfaceGetResponse, error]
+	PostNetworkInterface(
+		ctx context.Context,
+		idempotencyKey string,
+		zoneId string,
+		name string,
+		attachedSubnetId string,
+		dr bool,
+		ipPtr *string,
+		macPtr *string,
+		tags map[string]string,
+	) (*ResourceNetworkInterfacePostResponse, error)
+	PatchNetworkInterface(
+		ctx context.Context,
+		id string, namePtr *string, attachedMachineIdPtr **string, tagsPtr *map[string]string,
+	) (*ResourceNetworkInterfacePatchResponse, error)
+	DeleteNetworkInterface(
+		ctx context.Context, id string,
+	) (*ResourceNetworkInterfaceDeleteResponse, error)
+
+	GetPublicIp(ctx context.Context, id string) (*ResourcePublicIpGetResponse, error)
+	GetPublicIps(
+		ctx context.Context, filterAttachedNetworkInterfaceIdPtr *string,
+	) iter.Seq2[ResourcePublicIpGetResponse, error]
+	PostPublicIp(
+		ctx context.Context,
+		idempotencyKey string,
+		zoneId string,
+		dr bool,
+		tags map[string]string,
+	) (*ResourcePublicIpPostResponse, error)
+	PatchPublicIp(
+		ctx context.Context,
+		id string, attachedNetworkInterfaceIdPtr **string, tagsPtr *map[string]string,
+	) (*ResourcePublicIpPatchResponse, error)
+	DeletePublicIp(ctx context.Context, id string) (*ResourcePublicIpDeleteResponse, error)
+}
+
+// InfraClient reads the catalog of the portal: regions, zones, instance types
+// and block storage images, as well as the organization of the caller.
+type InfraClient interface {
+	GetOrganization(ctx context.Context) (*OrganizationGetResponse, error)
+
+	GetRegion(ctx context.Context, id string) (*RegionGetResponse, error)
+	GetRegions(ctx context.Context, filterNameIlike *string) iter.Seq2[RegionGetResponse, error]
+
+	GetZone(ctx context.Context, id string) (*InfraZoneGetResponse, error)
+	GetZones(
+		ctx context.Context, filterRegionId *string, filterNameIlike *string,
+	) iter.Seq2[InfraZoneGetResponse, error]
+
+	GetInstanceType(ctx context.Context, id string) (*InfraInstanceTypeGetResponse, error)
+	GetInstanceTypes(
+		ctx context.Context, filterNameIlike *string, filterActivated *bool,
+	) iter.Seq2[InfraInstanceTypeGetResponse, error]
+
+	GetBlockStorageImage(
+		ctx context.Context, id string,
+	) (*ResourceBlockStorageImageGetResponse, error)
+	GetBlockStorageImages(
+		ctx context.Context, filterNameIlike *string,
+	) iter.Seq2[ResourceBlockStorageImageGetResponse, error]
+}
+
+// Client is everything the provider needs from the portal. It is implemented
+// by APIClient, and by an in-memory fake in internal/api/fake for tests.
+type Client interface {
+	ComputeClient
+	StorageClient
+	NetworkClient
+	InfraClient
+}
+
+var _ Client = &APIClient{}
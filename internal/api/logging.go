@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const redacted = "***"
+
+// sensitiveFields are JSON keys whose values never show up in logs, wherever
+// they appear in a request or response body.
+var sensitiveFields = map[string]bool{
+	"password":       true,
+	"on_init_script": true,
+	"token":          true,
+	"access_token":   true,
+}
+
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// loggingTransport logs every attempt of every request through tflog: a
+// summary at debug level, and redacted headers and bodies at trace level.
+type loggingTransport struct {
+	next http.RoundTripper
+}
+
+func newLoggingTransport(next http.RoundTripper) *loggingTransport {
+	return &loggingTransport{next: next}
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	fields := map[string]interface{}{
+		"method": req.Method,
+		"path":   req.URL.Path,
+	}
+
+	var requestBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		requestBody = body
+		req = req.Clone(ctx)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	tflog.Trace(ctx, "sending API request", map[string]interface{}{
+		"method":          req.Method,
+		"path":            req.URL.Path,
+		"request_headers": redactHeaders(req.Header),
+		"request_body":    redactBody(requestBody),
+	})
+
+	started := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	fields["latency_ms"] = time.Since(started).Milliseconds()
+
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.Debug(ctx, "API request failed", fields)
+		return nil, err
+	}
+
+	fields["status"] = resp.StatusCode
+	fields["request_id"] = resp.Header.Get("X-Request-Id")
+	tflog.Debug(ctx, "API request completed", fields)
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	tflog.Trace(ctx, "received API response", map[string]interface{}{
+		"method":           req.Method,
+		"path":             req.URL.Path,
+		"status":           resp.StatusCode,
+		"response_headers": redactHeaders(resp.Header),
+		"response_body":    redactBody(body),
+	})
+
+	return resp, nil
+}
+
+func redactHeaders(header http.Header) http.Header {
+	header = header.Clone()
+	for _, name := range sensitiveHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, redacted)
+		}
+	}
+	return header
+}
+
+// redactBody masks sensitive fields of a JSON body. Bodies that are not JSON
+// are only described by their size, as their content cannot be inspected.
+func redactBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Sprintf("<non-JSON body, %d bytes>", len(body))
+	}
+
+	masked, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return fmt.Sprintf("<unprintable body, %d bytes>", len(body))
+	}
+
+	return string(masked)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if sensitiveFields[key] && field != nil {
+				value[key] = redacted
+			} else {
+				value[key] = redactValue(field)
+			}
+		}
+	case []interface{}:
+		for index, item := range value {
+			value[index] = redactValue(item)
+		}
+	}
+	return value
+}
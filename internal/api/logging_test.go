@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		hidden []string
+		shown  []string
+	}{
+		{
+			name:   "password and init script",
+			body:   `{"username": "elice", "password": "hunter2", "on_init_script": "echo s3cret"}`,
+			hidden: []string{"hunter2", "s3cret"},
+			shown:  []string{"elice", `"password":"***"`, `"on_init_script":"***"`},
+		},
+		{
+			name: "nested tokens",
+			body: `{"auth": {"token": "tok-1", "nested": [{"access_token": "tok-2"}]}, ` +
+				`"name": "vm"}`,
+			hidden: []string{"tok-1", "tok-2"},
+			shown:  []string{`"token":"***"`, `"access_token":"***"`, "vm"},
+		},
+		{
+			name:   "list of objects",
+			body:   `[{"password": "hunter2"}, {"password": null}]`,
+			hidden: []string{"hunter2"},
+			shown:  []string{`"password":"***"`, `"password":null`},
+		},
+		{
+			name:   "not JSON",
+			body:   "password=hunter2&token=tok-1",
+			hidden: []string{"hunter2", "tok-1", "password"},
+			shown:  []string{"non-JSON body, 28 bytes"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := redactBody([]byte(test.body))
+
+			for _, secret := range test.hidden {
+				if strings.Contains(got, secret) {
+					t.Errorf("%q is not redacted: %s", secret, got)
+				}
+			}
+			for _, field := range test.shown {
+				if !strings.Contains(got, field) {
+					t.Errorf("%q is missing: %s", field, got)
+				}
+			}
+		})
+	}
+
+	if got := redactBody(nil); got != "" {
+		t.Errorf("empty body: got %q, want nothing", got)
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer tok-1")
+	header.Set("Cookie", "session=tok-2")
+	header.Set("Content-Type", "application/json")
+
+	got := redactHeaders(header)
+
+	for _, name := range []string{"Authorization", "Cookie"} {
+		if value := got.Get(name); value != redacted {
+			t.Errorf("%s: got %q, want %q", name, value, redacted)
+		}
+	}
+	if value := got.Get("Content-Type"); value != "application/json" {
+		t.Errorf("Content-Type: got %q, want application/json", value)
+	}
+	if value := header.Get("Authorization"); value != "Bearer tok-1" {
+		t.Errorf("the headers of the request were changed: %q", value)
+	}
+	if _, ok := got["Set-Cookie"]; ok {
+		t.Errorf("a missing header was added: %v", got)
+	}
+}
+
+func TestLoggingTransportRedacts(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.Contains(string(body), "hunter2") {
+			t.Errorf("the request body was not sent as is: %s", body)
+		}
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Set-Cookie": []string{"session=tok-2"}},
+			Body:       io.NopCloser(strings.NewReader(`{"access_token": "tok-3"}`)),
+		}, nil
+	})
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, "http://portal/user/resource/compute/virtual_machine",
+		strings.NewReader(`{"name": "vm", "password": "hunter2"}`),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer tok-1")
+
+	resp, err := newLoggingTransport(next).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "tok-3") {
+		t.Errorf("the response body was not returned as is: %s", body)
+	}
+
+	logs := output.String()
+	if !strings.Contains(logs, "received API response") {
+		t.Fatalf("the response was not logged: %s", logs)
+	}
+	for _, secret := range []string{"hunter2", "tok-1", "tok-2", "tok-3"} {
+		if strings.Contains(logs, secret) {
+			t.Errorf("%q is logged: %s", secret, logs)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/google/uuid"
@@ -38,9 +40,11 @@ type ResourceNetworkInterfaceDeleteResponse struct {
 }
 
 func (api *APIClient) GetNetworkInterface(
+	ctx context.Context,
 	id string,
 ) (*ResourceNetworkInterfaceGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceNetworkInterfaceGetResponse{}).
 		Get(fmt.Sprintf("%s/user/resource/network/network_interface/%s", api.pathPrefix, id))
 
@@ -48,20 +52,21 @@ func (api *APIClient) GetNetworkInterface(
 }
 
 func (api *APIClient) GetNetworkInterfaces(
+	ctx context.Context,
 	filterAttachedMachineIdPtr *string,
-) ([]ResourceNetworkInterfaceGetResponse, error) {
+) iter.Seq2[ResourceNetworkInterfaceGetResponse, error] {
 	params := map[string]string{}
 	setStrIfNotNil(params, "filter_attached_machine_id", filterAttachedMachineIdPtr)
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]ResourceNetworkInterfaceGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/resource/network/network_interface", api.pathPrefix))
-
-	return handleListAPIResponse[ResourceNetworkInterfaceGetResponse](resp, err)
+	return paginate[ResourceNetworkInterfaceGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/resource/network/network_interface", api.pathPrefix), params,
+	)
 }
 
 func (api *APIClient) PostNetworkInterface(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
 	name string,
 	attachedSubnetId string,
 	dr bool,
@@ -69,9 +74,14 @@ func (api *APIClient) PostNetworkInterface(
 	macPtr *string,
 	tags map[string]string,
 ) (*ResourceNetworkInterfacePostResponse, error) {
+	organizationId, err := api.OrganizationId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	params := map[string]interface{}{
-		"zone_id":            api.ZoneId,
-		"organization_id":    api.OrganizationId,
+		"zone_id":            zoneId,
+		"organization_id":    organizationId,
 		"name":               name,
 		"attached_subnet_id": attachedSubnetId,
 		"dr":                 dr,
@@ -81,6 +91,8 @@ func (api *APIClient) PostNetworkInterface(
 	setIfNotNil(params, "mac", macPtr)
 
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetHeader(IdempotencyKeyHeader, idempotencyKey).
 		SetResult(&ResourceNetworkInterfacePostResponse{}).
 		SetBody(params).
 		Post(fmt.Sprintf("%s/user/resource/network/network_interface", api.pathPrefix))
@@ -89,6 +101,7 @@ func (api *APIClient) PostNetworkInterface(
 }
 
 func (api *APIClient) PatchNetworkInterface(
+	ctx context.Context,
 	id string, namePtr *string, attachedMachineIdPtr **string, tagsPtr *map[string]string,
 ) (*ResourceNetworkInterfacePatchResponse, error) {
 	params := map[string]interface{}{}
@@ -97,6 +110,7 @@ func (api *APIClient) PatchNetworkInterface(
 	setIfNotNil(params, "tags", tagsPtr)
 
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceNetworkInterfacePatchResponse{}).
 		SetBody(params).
 		Patch(fmt.Sprintf("%s/user/resource/network/network_interface/%s", api.pathPrefix, id))
@@ -105,9 +119,11 @@ func (api *APIClient) PatchNetworkInterface(
 }
 
 func (api *APIClient) DeleteNetworkInterface(
+	ctx context.Context,
 	id string,
 ) (*ResourceNetworkInterfaceDeleteResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceNetworkInterfaceDeleteResponse{}).
 		Delete(fmt.Sprintf("%s/user/resource/network/network_interface/%s", api.pathPrefix, id))
 
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -16,11 +17,11 @@ type OrganizationGetResponse struct {
 	AllowedIps []string   `json:"allowed_ips"`
 }
 
-func (api *APIClient) GetOrganization() (*OrganizationGetResponse, error) {
+func (api *APIClient) GetOrganization(ctx context.Context) (*OrganizationGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&OrganizationGetResponse{}).
 		Get(fmt.Sprintf("%s/user/organization", api.pathPrefix))
 
-	println(fmt.Sprintf("organization id: %s", resp.String()))
 	return handleAPIResponse[OrganizationGetResponse](resp, err)
 }
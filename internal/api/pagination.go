@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"reflect"
+	"strconv"
+)
+
+// pageSize is the number of items requested per page from list endpoints.
+const pageSize = 100
+
+// paginate walks every page of a list endpoint, yielding items one by one.
+// Iteration stops at the first error, which is yielded with a zero item, or
+// after a page shorter than pageSize. It also stops when the endpoint ignores
+// skip and count, i.e. after a page longer than pageSize (everything at once)
+// or before a page that repeats the previous one, rather than loop forever.
+func paginate[T any](
+	ctx context.Context, api *APIClient, path string, params map[string]string,
+) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var previous []T
+
+		for skip := 0; ; skip += pageSize {
+			page, err := getPage[T](ctx, api, path, params, skip)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			if skip > 0 && reflect.DeepEqual(page, previous) {
+				return
+			}
+			previous = page
+
+			for _, item := range page {
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			if len(page) != pageSize {
+				return
+			}
+		}
+	}
+}
+
+// paginateInZone is paginate filtered by the zone of the client, which is
+// looked up, if needed, when iteration starts.
+func paginateInZone[T any](
+	ctx context.Context, api *APIClient, path string, params map[string]string,
+) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		zoneId, err := api.ZoneId(ctx)
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+
+		zoneParams := maps.Clone(params)
+		zoneParams["filter_zone_id"] = zoneId
+
+		for item, err := range paginate[T](ctx, api, path, zoneParams) {
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+}
+
+func getPage[T any](
+	ctx context.Context, api *APIClient, path string, params map[string]string, skip int,
+) ([]T, error) {
+	pageParams := maps.Clone(params)
+	pageParams["skip"] = strconv.Itoa(skip)
+	pageParams["count"] = strconv.Itoa(pageSize)
+
+	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetResult(&[]T{}).
+		SetQueryParams(pageParams).
+		Get(path)
+
+	return handleListAPIResponse[T](resp, err)
+}
+
+// Collect drains an iterator returned by a list method into a slice.
+func Collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var items []T
+
+	for item, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
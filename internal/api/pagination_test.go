@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+type testItem struct {
+	Id int `json:"id"`
+}
+
+// newTestList serves a list endpoint of total items, paginated by serve,
+// and returns a client of it along with a counter of its requests.
+func newTestList(
+	t *testing.T, total int, serve func(items []testItem, skip int, count int) any,
+) (*APIClient, *atomic.Int32) {
+	t.Helper()
+
+	items := make([]testItem, total)
+	for i := range items {
+		items[i] = testItem{Id: i}
+	}
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+
+		response := serve(items, skip, count)
+		if err, ok := response.(error); ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"code": "internal", "message": err.Error()})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewAPIClient("token", server.URL, "", ZoneSelector{}, 0, 1000, 1), &requests
+}
+
+// servePage serves the page of items asked for.
+func servePage(items []testItem, skip int, count int) any {
+	return items[min(skip, len(items)):min(skip+count, len(items))]
+}
+
+func listTestItems(client *APIClient) iter.Seq2[testItem, error] {
+	return paginate[testItem](context.Background(), client, "/items", map[string]string{})
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    int
+		serve    func(items []testItem, skip int, count int) any
+		items    int
+		requests int32
+	}{
+		{name: "empty", total: 0, serve: servePage, items: 0, requests: 1},
+		{name: "short page", total: 42, serve: servePage, items: 42, requests: 1},
+		{name: "several pages", total: 250, serve: servePage, items: 250, requests: 3},
+		{
+			name:     "exact multiple of page size",
+			total:    2 * pageSize,
+			serve:    servePage,
+			items:    2 * pageSize,
+			requests: 3,
+		},
+		{
+			name:  "count ignored",
+			total: 250,
+			serve: func(items []testItem, skip int, count int) any {
+				return items[min(skip, len(items)):]
+			},
+			items:    250,
+			requests: 1,
+		},
+		{
+			name:  "skip ignored",
+			total: 250,
+			serve: func(items []testItem, skip int, count int) any {
+				return items[:count]
+			},
+			items:    pageSize,
+			requests: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, requests := newTestList(t, test.total, test.serve)
+
+			items, err := Collect(listTestItems(client))
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(items) != test.items {
+				t.Errorf("items: got %d, want %d", len(items), test.items)
+			}
+			for i, item := range items {
+				if item.Id != i {
+					t.Fatalf("item %d: got %d", i, item.Id)
+				}
+			}
+			if got := requests.Load(); got != test.requests {
+				t.Errorf("requests: got %d, want %d", got, test.requests)
+			}
+		})
+	}
+}
+
+func TestPaginateStopsWhenConsumerBreaks(t *testing.T) {
+	client, requests := newTestList(t, 250, servePage)
+
+	seen := 0
+	for _, err := range listTestItems(client) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen++
+		if seen == pageSize {
+			break
+		}
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests: got %d, want 1", got)
+	}
+}
+
+func TestPaginateYieldsError(t *testing.T) {
+	client, requests := newTestList(t, 250, func(items []testItem, skip int, count int) any {
+		if skip > 0 {
+			return errors.New("failed")
+		}
+		return servePage(items, skip, count)
+	})
+
+	seen := 0
+	var errs []error
+	for item, err := range listTestItems(client) {
+		if err != nil {
+			errs = append(errs, err)
+			if item != (testItem{}) {
+				t.Errorf("item of the error: got %+v, want a zero item", item)
+			}
+			continue
+		}
+		seen++
+	}
+
+	if seen != pageSize {
+		t.Errorf("items: got %d, want %d", seen, pageSize)
+	}
+	if len(errs) != 1 {
+		t.Fatalf("errors: got %v, want one", errs)
+	}
+	var apiError *APIError
+	if !errors.As(errs[0], &apiError) || apiError.HttpCode != http.StatusInternalServerError {
+		t.Errorf("got %v, want an error of status 500", errs[0])
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests: got %d, want 2", got)
+	}
+
+	if _, err := Collect(listTestItems(client)); err == nil {
+		t.Errorf("Collect: expected an error")
+	}
+}
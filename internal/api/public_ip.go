@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,8 +39,11 @@ type ResourcePublicIpDeleteResponse struct {
 	Status string    `json:"status"`
 }
 
-func (api *APIClient) GetPublicIp(id string) (*ResourcePublicIpGetResponse, error) {
+func (api *APIClient) GetPublicIp(
+	ctx context.Context, id string,
+) (*ResourcePublicIpGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourcePublicIpGetResponse{}).
 		Get(fmt.Sprintf("%s/user/resource/network/public_ip/%s", api.pathPrefix, id))
 
@@ -46,29 +51,37 @@ func (api *APIClient) GetPublicIp(id string) (*ResourcePublicIpGetResponse, erro
 }
 
 func (api *APIClient) GetPublicIps(
+	ctx context.Context,
 	filterAttachedNetworkInterfaceIdPtr *string,
-) ([]ResourcePublicIpGetResponse, error) {
+) iter.Seq2[ResourcePublicIpGetResponse, error] {
 	params := map[string]string{}
 	setStrIfNotNil(
 		params, "filter_attached_network_interface_id", filterAttachedNetworkInterfaceIdPtr,
 	)
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]ResourcePublicIpGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/resource/network/public_ip", api.pathPrefix))
-
-	return handleListAPIResponse[ResourcePublicIpGetResponse](resp, err)
+	return paginate[ResourcePublicIpGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/resource/network/public_ip", api.pathPrefix), params,
+	)
 }
 
 func (api *APIClient) PostPublicIp(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
 	dr bool, tags map[string]string,
 ) (*ResourcePublicIpPostResponse, error) {
+	organizationId, err := api.OrganizationId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetHeader(IdempotencyKeyHeader, idempotencyKey).
 		SetResult(&ResourcePublicIpPostResponse{}).
 		SetBody(map[string]interface{}{
-			"zone_id":         api.ZoneId,
-			"organization_id": api.OrganizationId,
+			"zone_id":         zoneId,
+			"organization_id": organizationId,
 			"dr":              dr,
 			"tags":            tags,
 		}).
@@ -78,6 +91,7 @@ func (api *APIClient) PostPublicIp(
 }
 
 func (api *APIClient) PatchPublicIp(
+	ctx context.Context,
 	id string, attachedNetworkInterfaceIdPtr **string, tagsPtr *map[string]string,
 ) (*ResourcePublicIpPatchResponse, error) {
 	params := map[string]interface{}{}
@@ -85,6 +99,7 @@ func (api *APIClient) PatchPublicIp(
 	setIfNotNil(params, "tags", tagsPtr)
 
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourcePublicIpPatchResponse{}).
 		SetBody(params).
 		Patch(fmt.Sprintf("%s/user/resource/network/public_ip/%s", api.pathPrefix, id))
@@ -92,8 +107,11 @@ func (api *APIClient) PatchPublicIp(
 	return handleAPIResponse[ResourcePublicIpPatchResponse](resp, err)
 }
 
-func (api *APIClient) DeletePublicIp(id string) (*ResourcePublicIpDeleteResponse, error) {
+func (api *APIClient) DeletePublicIp(
+	ctx context.Context, id string,
+) (*ResourcePublicIpDeleteResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourcePublicIpDeleteResponse{}).
 		Delete(fmt.Sprintf("%s/user/resource/network/public_ip/%s", api.pathPrefix, id))
 
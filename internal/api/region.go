@@ -1,8 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
-	"strconv"
+	"iter"
 
 	"github.com/google/uuid"
 )
@@ -14,8 +15,9 @@ type RegionGetResponse struct {
 	SecondaryZoneId *uuid.UUID `json:"secondary_zone_id"`
 }
 
-func (api *APIClient) GetRegion(id string) (*RegionGetResponse, error) {
+func (api *APIClient) GetRegion(ctx context.Context, id string) (*RegionGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&RegionGetResponse{}).
 		Get(fmt.Sprintf("%s/user/region/%s", api.pathPrefix, id))
 
@@ -23,18 +25,13 @@ func (api *APIClient) GetRegion(id string) (*RegionGetResponse, error) {
 }
 
 func (api *APIClient) GetRegions(
-	filterNameIlike *string, skip int, count int,
-) ([]RegionGetResponse, error) {
-	params := map[string]string{
-		"skip":  strconv.FormatInt(int64(skip), 10),
-		"count": strconv.FormatInt(int64(count), 10),
-	}
+	ctx context.Context,
+	filterNameIlike *string,
+) iter.Seq2[RegionGetResponse, error] {
+	params := map[string]string{}
 	setStrIfNotNil(params, "filter_name_ilike", filterNameIlike)
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]RegionGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/region", api.pathPrefix))
-
-	return handleListAPIResponse[RegionGetResponse](resp, err)
+	return paginate[RegionGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/region", api.pathPrefix), params,
+	)
 }
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	DefaultMaxRetries = 4
+
+	// IdempotencyKeyHeader lets the portal deduplicate repeated creation
+	// requests, which makes retrying a POST safe.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	retryWaitTime    = 1 * time.Second
+	retryMaxWaitTime = 30 * time.Second
+)
+
+// retryableStatusCodes are responses that indicate the portal (or a proxy in
+// front of it) could not serve the request for a transient reason.
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+func isIdempotentRequest(request *resty.Request) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPatch, http.MethodDelete:
+		return true
+	case http.MethodPost:
+		return request.Header.Get(IdempotencyKeyHeader) != ""
+	}
+	return false
+}
+
+// isDialError reports whether the request failed before a connection was
+// established, i.e. the server cannot have seen it.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// shouldRetry decides whether a request is worth another attempt.
+//
+// Idempotent requests (including POSTs carrying an idempotency key) are
+// retried on transport errors and transient status codes. Other requests are
+// only retried when the server provably did not process them: the connection
+// could not be established, or the request was rejected by throttling.
+func shouldRetry(resp *resty.Response, err error) bool {
+	if resp == nil || resp.Request == nil {
+		return isDialError(err)
+	}
+
+	idempotent := isIdempotentRequest(resp.Request)
+
+	if err != nil {
+		return idempotent || isDialError(err)
+	}
+
+	if !retryableStatusCodes[resp.StatusCode()] {
+		return false
+	}
+
+	return idempotent || resp.StatusCode() == http.StatusTooManyRequests
+}
+
+// retryAfter honors the `Retry-After` header, given either in seconds or as an
+// HTTP date. Returning zero lets resty fall back to jittered exponential
+// backoff.
+func retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	header := resp.Header().Get("Retry-After")
+	if header == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	if date, err := http.ParseTime(header); err == nil && time.Until(date) > 0 {
+		return time.Until(date), nil
+	}
+
+	return 0, nil
+}
+
+func setRetryPolicy(client *resty.Client, maxRetries int) *resty.Client {
+	return client.
+		SetRetryCount(maxRetries).
+		SetRetryWaitTime(retryWaitTime).
+		SetRetryMaxWaitTime(retryMaxWaitTime).
+		SetRetryAfter(retryAfter).
+		AddRetryCondition(shouldRetry)
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// newTestResponse returns a response of status to a request of method, with
+// the given headers of the request.
+func newTestResponse(method string, status int, requestHeader http.Header) *resty.Response {
+	request := resty.New().R()
+	request.Method = method
+	for key, values := range requestHeader {
+		request.Header[key] = values
+	}
+
+	var rawResponse *http.Response
+	if status != 0 {
+		rawResponse = &http.Response{StatusCode: status, Header: http.Header{}}
+	}
+
+	return &resty.Response{Request: request, RawResponse: rawResponse}
+}
+
+func TestShouldRetry(t *testing.T) {
+	dialError := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readError := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+	withKey := http.Header{IdempotencyKeyHeader: []string{"key"}}
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		status int
+		err    error
+		want   bool
+	}{
+		{name: "get, ok", method: http.MethodGet, status: 200, want: false},
+		{name: "get, too many requests", method: http.MethodGet, status: 429, want: true},
+		{name: "get, bad gateway", method: http.MethodGet, status: 502, want: true},
+		{name: "get, unavailable", method: http.MethodGet, status: 503, want: true},
+		{name: "get, gateway timeout", method: http.MethodGet, status: 504, want: true},
+		{name: "get, internal error", method: http.MethodGet, status: 500, want: false},
+		{name: "get, bad request", method: http.MethodGet, status: 400, want: false},
+		{name: "get, not found", method: http.MethodGet, status: 404, want: false},
+		{name: "get, conflict", method: http.MethodGet, status: 409, want: false},
+		{name: "get, read error", method: http.MethodGet, err: readError, want: true},
+		{name: "get, unexpected eof", method: http.MethodGet, err: io.ErrUnexpectedEOF, want: true},
+		{name: "delete, unavailable", method: http.MethodDelete, status: 503, want: true},
+		{name: "patch, read error", method: http.MethodPatch, err: readError, want: true},
+		{name: "post, unavailable", method: http.MethodPost, status: 503, want: false},
+		{name: "post, read error", method: http.MethodPost, err: readError, want: false},
+		{name: "post, dial error", method: http.MethodPost, err: dialError, want: true},
+		{name: "post, too many requests", method: http.MethodPost, status: 429, want: true},
+		{name: "post, bad request", method: http.MethodPost, status: 400, want: false},
+		{
+			name:   "post with key, unavailable",
+			method: http.MethodPost, header: withKey, status: 503, want: true,
+		},
+		{
+			name:   "post with key, read error",
+			method: http.MethodPost, header: withKey, err: readError, want: true,
+		},
+		{
+			name:   "post with key, unprocessable",
+			method: http.MethodPost, header: withKey, status: 422, want: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := newTestResponse(test.method, test.status, test.header)
+
+			if got := shouldRetry(resp, test.err); got != test.want {
+				t.Errorf("got %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestShouldRetryWithoutResponse(t *testing.T) {
+	dialError := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	if !shouldRetry(nil, dialError) {
+		t.Errorf("a dial error is not retried")
+	}
+	if shouldRetry(nil, io.ErrUnexpectedEOF) {
+		t.Errorf("an error of an unknown request is retried")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{name: "missing", header: "", min: 0, max: 0},
+		{name: "seconds", header: "7", min: 7 * time.Second, max: 7 * time.Second},
+		{
+			name:   "date",
+			header: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat),
+			min:    50 * time.Second,
+			max:    time.Minute,
+		},
+		{
+			name:   "past date",
+			header: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat),
+			min:    0,
+			max:    0,
+		},
+		{name: "garbage", header: "soon", min: 0, max: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := newTestResponse(http.MethodGet, http.StatusServiceUnavailable, nil)
+			if test.header != "" {
+				resp.RawResponse.Header.Set("Retry-After", test.header)
+			}
+
+			got, err := retryAfter(nil, resp)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got < test.min || got > test.max {
+				t.Errorf("got %s, want between %s and %s", got, test.min, test.max)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,13 +39,23 @@ type ResourceSubnetDeleteResponse struct {
 }
 
 func (api *APIClient) PostSubnet(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
 	name string, attachedNetworkId string, purpose string, networkGw string, tags map[string]string,
 ) (*ResourceSubnetPostResponse, error) {
+	organizationId, err := api.OrganizationId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetHeader(IdempotencyKeyHeader, idempotencyKey).
 		SetResult(&ResourceSubnetPostResponse{}).
 		SetBody(map[string]interface{}{
-			"zone_id":             api.ZoneId,
-			"organization_id":     api.OrganizationId,
+			"zone_id":             zoneId,
+			"organization_id":     organizationId,
 			"name":                name,
 			"attached_network_id": attachedNetworkId,
 			"purpose":             purpose,
@@ -55,15 +67,31 @@ func (api *APIClient) PostSubnet(
 	return handleAPIResponse[ResourceSubnetPostResponse](resp, err)
 }
 
-func (api *APIClient) GetSubnet(id string) (*ResourceSubnetGetResponse, error) {
+func (api *APIClient) GetSubnet(
+	ctx context.Context, id string,
+) (*ResourceSubnetGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceSubnetGetResponse{}).
 		Get(fmt.Sprintf("%s/user/resource/network/subnet/%s", api.pathPrefix, id))
 
 	return handleAPIResponse[ResourceSubnetGetResponse](resp, err)
 }
 
+func (api *APIClient) GetSubnets(
+	ctx context.Context,
+	filterAttachedNetworkId *string,
+) iter.Seq2[ResourceSubnetGetResponse, error] {
+	params := map[string]string{}
+	setStrIfNotNil(params, "filter_attached_network_id", filterAttachedNetworkId)
+
+	return paginate[ResourceSubnetGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/resource/network/subnet", api.pathPrefix), params,
+	)
+}
+
 func (api *APIClient) PatchSubnet(
+	ctx context.Context,
 	id string, namePtr *string, tagsPtr *map[string]string,
 ) (*ResourceSubnetPatchResponse, error) {
 	params := map[string]interface{}{}
@@ -71,6 +99,7 @@ func (api *APIClient) PatchSubnet(
 	setIfNotNil(params, "tags", tagsPtr)
 
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceSubnetPatchResponse{}).
 		SetBody(params).
 		Patch(fmt.Sprintf("%s/user/resource/network/subnet/%s", api.pathPrefix, id))
@@ -78,8 +107,11 @@ func (api *APIClient) PatchSubnet(
 	return handleAPIResponse[ResourceSubnetPatchResponse](resp, err)
 }
 
-func (api *APIClient) DeleteSubnet(id string) (*ResourceSubnetDeleteResponse, error) {
+func (api *APIClient) DeleteSubnet(
+	ctx context.Context, id string,
+) (*ResourceSubnetDeleteResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceSubnetDeleteResponse{}).
 		Delete(fmt.Sprintf("%s/user/resource/network/subnet/%s", api.pathPrefix, id))
 
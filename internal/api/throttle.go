@@ -0,0 +1,70 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	DefaultRequestsPerSecond     = 10.0
+	DefaultMaxConcurrentRequests = 8
+)
+
+// throttledTransport is shared by every request of a client, so that the
+// rate limit and the number of in-flight requests apply across all resources
+// that terraform manages in parallel (including status polling and retries).
+type throttledTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+	slots   chan struct{}
+}
+
+func newThrottledTransport(
+	next http.RoundTripper, requestsPerSecond float64, maxConcurrentRequests int,
+) *throttledTransport {
+	return &throttledTransport{
+		next:    next,
+		limiter: rate.NewLimiter(rate.Limit(requestsPerSecond), max(1, int(requestsPerSecond))),
+		slots:   make(chan struct{}, maxConcurrentRequests),
+	}
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	select {
+	case t.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	release := sync.OnceFunc(func() { <-t.slots })
+
+	if err := t.limiter.Wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	// the request stays in flight until its body has been consumed
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	defer b.release()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc is an http.RoundTripper calling itself.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestRequest(t *testing.T, ctx context.Context) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://portal/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestThrottledTransportCapsConcurrency(t *testing.T) {
+	const maxConcurrent = 3
+
+	var inFlight, maxInFlight atomic.Int32
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+	transport := newThrottledTransport(next, 1000, maxConcurrent)
+
+	var wg sync.WaitGroup
+	for range 4 * maxConcurrent {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := transport.RoundTrip(newTestRequest(t, context.Background()))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got != maxConcurrent {
+		t.Errorf("requests in flight: got at most %d, want %d", got, maxConcurrent)
+	}
+}
+
+func TestThrottledTransportHoldsSlotUntilBodyIsClosed(t *testing.T) {
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+	transport := newThrottledTransport(next, 1000, 1)
+
+	resp, err := transport.RoundTrip(newTestRequest(t, context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := transport.RoundTrip(newTestRequest(t, ctx)); !errors.Is(
+		err, context.DeadlineExceeded,
+	) {
+		t.Errorf("got %v while the slot is held, want %v", err, context.DeadlineExceeded)
+	}
+
+	resp.Body.Close()
+	resp, err = transport.RoundTrip(newTestRequest(t, context.Background()))
+	if err != nil {
+		t.Fatalf("the slot was not released: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestThrottledTransportReleasesSlotOnCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	block := make(chan struct{})
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		started <- struct{}{}
+		select {
+		case <-block:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(""))}, nil
+	})
+	transport := newThrottledTransport(next, 1000, 1)
+
+	// A request waiting for the only slot returns as soon as it is cancelled.
+	held, cancelHeld := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := transport.RoundTrip(newTestRequest(t, held))
+		done <- err
+	}()
+	<-started
+
+	waiting, cancelWaiting := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancelWaiting)
+	startedAt := time.Now()
+	if _, err := transport.RoundTrip(newTestRequest(t, waiting)); !errors.Is(
+		err, context.Canceled,
+	) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(startedAt); elapsed > time.Second {
+		t.Errorf("returned %s after the cancellation", elapsed)
+	}
+
+	// A cancelled request in flight gives its slot back.
+	cancelHeld()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+
+	close(block)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := transport.RoundTrip(newTestRequest(t, ctx))
+	if err != nil {
+		t.Fatalf("the slot was not released: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/google/uuid"
@@ -40,15 +42,33 @@ type ResourceVirtualMachineDeleteResponse struct {
 	Status string    `json:"status"`
 }
 
-func (api *APIClient) GetVirtualMachine(id string) (*ResourceVirtualMachineGetResponse, error) {
+func (api *APIClient) GetVirtualMachine(
+	ctx context.Context, id string,
+) (*ResourceVirtualMachineGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceVirtualMachineGetResponse{}).
 		Get(fmt.Sprintf("%s/user/resource/compute/virtual_machine/%s", api.pathPrefix, id))
 
 	return handleAPIResponse[ResourceVirtualMachineGetResponse](resp, err)
 }
 
+func (api *APIClient) GetVirtualMachines(
+	ctx context.Context,
+	filterNameIlike *string,
+) iter.Seq2[ResourceVirtualMachineGetResponse, error] {
+	params := map[string]string{}
+	setStrIfNotNil(params, "filter_name_ilike", filterNameIlike)
+
+	return paginate[ResourceVirtualMachineGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/resource/compute/virtual_machine", api.pathPrefix), params,
+	)
+}
+
 func (api *APIClient) PostVirtualMachine(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
 	instanceTypeId string,
 	name string,
 	alwaysOn bool,
@@ -58,11 +78,18 @@ func (api *APIClient) PostVirtualMachine(
 	onInitScript string,
 	tags map[string]string,
 ) (*ResourceVirtualMachinePostResponse, error) {
+	organizationId, err := api.OrganizationId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetHeader(IdempotencyKeyHeader, idempotencyKey).
 		SetResult(&ResourceVirtualMachinePostResponse{}).
 		SetBody(map[string]interface{}{
-			"zone_id":          api.ZoneId,
-			"organization_id":  api.OrganizationId,
+			"zone_id":          zoneId,
+			"organization_id":  organizationId,
 			"instance_type_id": instanceTypeId,
 			"name":             name,
 			"always_on":        alwaysOn,
@@ -78,6 +105,7 @@ func (api *APIClient) PostVirtualMachine(
 }
 
 func (api *APIClient) PatchVirtualMachine(
+	ctx context.Context,
 	id string,
 	instanceTypeIdPtr *string,
 	namePtr *string,
@@ -91,6 +119,7 @@ func (api *APIClient) PatchVirtualMachine(
 	setIfNotNil(params, "tags", tagsPtr)
 
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceVirtualMachinePatchResponse{}).
 		SetBody(params).
 		Patch(fmt.Sprintf("%s/user/resource/compute/virtual_machine/%s", api.pathPrefix, id))
@@ -99,9 +128,11 @@ func (api *APIClient) PatchVirtualMachine(
 }
 
 func (api *APIClient) DeleteVirtualMachine(
+	ctx context.Context,
 	id string,
 ) (*ResourceVirtualMachineDeleteResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceVirtualMachineDeleteResponse{}).
 		Delete(fmt.Sprintf("%s/user/resource/compute/virtual_machine/%s", api.pathPrefix, id))
 
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/google/uuid"
@@ -41,9 +43,11 @@ type ResourceVirtualMachineAllocationDeleteResponse struct {
 }
 
 func (api *APIClient) GetVirtualMachineAllocation(
+	ctx context.Context,
 	id string,
 ) (*ResourceVirtualMachineAllocationGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceVirtualMachineAllocationGetResponse{}).
 		Get(fmt.Sprintf("%s/user/resource/compute/virtual_machine_allocation/%s", api.pathPrefix, id))
 
@@ -51,28 +55,39 @@ func (api *APIClient) GetVirtualMachineAllocation(
 }
 
 func (api *APIClient) GetVirtualMachineAllocations(
+	ctx context.Context,
 	filterMachineIdPtr *string, filterStatusPtr *string,
-) ([]ResourceVirtualMachineAllocationGetResponse, error) {
+) iter.Seq2[ResourceVirtualMachineAllocationGetResponse, error] {
 	params := map[string]string{}
 	setStrIfNotNil(params, "filter_machine_id", filterMachineIdPtr)
 	setStrIfNotNil(params, "filter_status", filterStatusPtr)
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]ResourceVirtualMachineAllocationGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/resource/compute/virtual_machine_allocation", api.pathPrefix))
-
-	return handleListAPIResponse[ResourceVirtualMachineAllocationGetResponse](resp, err)
+	return paginate[ResourceVirtualMachineAllocationGetResponse](
+		ctx,
+		api,
+		fmt.Sprintf("%s/user/resource/compute/virtual_machine_allocation", api.pathPrefix),
+		params,
+	)
 }
 
 func (api *APIClient) PostVirtualMachineAllocation(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
 	machineId string, tags map[string]string,
 ) (*ResourceVirtualMachineAllocationPostResponse, error) {
+	organizationId, err := api.OrganizationId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetHeader(IdempotencyKeyHeader, idempotencyKey).
 		SetResult(&ResourceVirtualMachineAllocationPostResponse{}).
 		SetBody(map[string]interface{}{
-			"zone_id":         api.ZoneId,
-			"organization_id": api.OrganizationId,
+			"zone_id":         zoneId,
+			"organization_id": organizationId,
 			"machine_id":      machineId,
 			"tags":            tags,
 		}).
@@ -81,9 +96,26 @@ func (api *APIClient) PostVirtualMachineAllocation(
 	return handleAPIResponse[ResourceVirtualMachineAllocationPostResponse](resp, err)
 }
 
-func (api *APIClient) DeleteVirtualMachineAllocation(id string,
+func (api *APIClient) PatchVirtualMachineAllocation(
+	ctx context.Context, id string, tagsPtr *map[string]string,
+) (*ResourceVirtualMachineAllocationPatchResponse, error) {
+	params := map[string]interface{}{}
+	setIfNotNil(params, "tags", tagsPtr)
+
+	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetResult(&ResourceVirtualMachineAllocationPatchResponse{}).
+		SetBody(params).
+		Patch(fmt.Sprintf("%s/user/resource/compute/virtual_machine_allocation/%s", api.pathPrefix, id))
+
+	return handleAPIResponse[ResourceVirtualMachineAllocationPatchResponse](resp, err)
+}
+
+func (api *APIClient) DeleteVirtualMachineAllocation(
+	ctx context.Context, id string,
 ) (*ResourceVirtualMachineAllocationDeleteResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceVirtualMachineAllocationDeleteResponse{}).
 		Delete(fmt.Sprintf("%s/user/resource/compute/virtual_machine_allocation/%s", api.pathPrefix, id))
 
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"iter"
 	"time"
 
 	"github.com/google/uuid"
@@ -44,22 +46,47 @@ type ResourceVirtualNetworkDeleteResponse struct {
 	Status string    `json:"status"`
 }
 
-func (api *APIClient) GetVirtualNetwork(id string) (*ResourceVirtualNetworkGetResponse, error) {
+func (api *APIClient) GetVirtualNetwork(
+	ctx context.Context, id string,
+) (*ResourceVirtualNetworkGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceVirtualNetworkGetResponse{}).
 		Get(fmt.Sprintf("%s/user/resource/network/virtual_network/%s", api.pathPrefix, id))
 
 	return handleAPIResponse[ResourceVirtualNetworkGetResponse](resp, err)
 }
 
+func (api *APIClient) GetVirtualNetworks(
+	ctx context.Context,
+	filterNameIlike *string,
+) iter.Seq2[ResourceVirtualNetworkGetResponse, error] {
+	params := map[string]string{}
+	setStrIfNotNil(params, "filter_name_ilike", filterNameIlike)
+
+	return paginate[ResourceVirtualNetworkGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/resource/network/virtual_network", api.pathPrefix), params,
+	)
+}
+
 func (api *APIClient) PostVirtualNetwork(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
 	name string, networkCidr string, tags map[string]string,
 ) (*ResourceVirtualNetworkPostResponse, error) {
+	organizationId, err := api.OrganizationId(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
+		SetHeader(IdempotencyKeyHeader, idempotencyKey).
 		SetResult(&ResourceVirtualNetworkPostResponse{}).
 		SetBody(map[string]interface{}{
-			"zone_id":         api.ZoneId,
-			"organization_id": api.OrganizationId,
+			"zone_id":         zoneId,
+			"organization_id": organizationId,
 			"name":            name,
 			"network_cidr":    networkCidr,
 			"tags":            tags,
@@ -70,6 +97,7 @@ func (api *APIClient) PostVirtualNetwork(
 }
 
 func (api *APIClient) PatchVirtualNetwork(
+	ctx context.Context,
 	id string, namePtr *string, firewallRulesPtr *[]NetworkFirewallRule, tags *map[string]string,
 ) (*ResourceVirtualNetworkPatchResponse, error) {
 	params := map[string]interface{}{}
@@ -78,6 +106,7 @@ func (api *APIClient) PatchVirtualNetwork(
 	setIfNotNil(params, "tags", tags)
 
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceVirtualNetworkPatchResponse{}).
 		SetBody(params).
 		Patch(fmt.Sprintf("%s/user/resource/network/virtual_network/%s", api.pathPrefix, id))
@@ -86,9 +115,11 @@ func (api *APIClient) PatchVirtualNetwork(
 }
 
 func (api *APIClient) DeleteVirtualNetwork(
+	ctx context.Context,
 	id string,
 ) (*ResourceVirtualNetworkDeleteResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&ResourceVirtualNetworkDeleteResponse{}).
 		Delete(fmt.Sprintf("%s/user/resource/network/virtual_network/%s", api.pathPrefix, id))
 
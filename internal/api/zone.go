@@ -1,8 +1,10 @@
 package api
 
 import (
+	"context"
 	"fmt"
-	"strconv"
+	"iter"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -14,8 +16,9 @@ type InfraZoneGetResponse struct {
 	SecondaryZoneId *uuid.UUID `json:"secondary_zone_id,omitempty"`
 }
 
-func (api *APIClient) GetZone(id string) (*InfraZoneGetResponse, error) {
+func (api *APIClient) GetZone(ctx context.Context, id string) (*InfraZoneGetResponse, error) {
 	resp, err := api.restyClient.R().
+		SetContext(ctx).
 		SetResult(&InfraZoneGetResponse{}).
 		Get(fmt.Sprintf("%s/user/infra/zone/%s", api.pathPrefix, id))
 
@@ -23,19 +26,91 @@ func (api *APIClient) GetZone(id string) (*InfraZoneGetResponse, error) {
 }
 
 func (api *APIClient) GetZones(
-	filterRegionId *string, filterNameIlike *string, skip int, count int,
-) ([]InfraZoneGetResponse, error) {
-	params := map[string]string{
-		"skip":  strconv.FormatInt(int64(skip), 10),
-		"count": strconv.FormatInt(int64(count), 10),
-	}
+	ctx context.Context,
+	filterRegionId *string, filterNameIlike *string,
+) iter.Seq2[InfraZoneGetResponse, error] {
+	params := map[string]string{}
 	setStrIfNotNil(params, "filter_region_id", filterRegionId)
 	setStrIfNotNil(params, "filter_name_ilike", filterNameIlike)
 
-	resp, err := api.restyClient.R().
-		SetResult(&[]InfraZoneGetResponse{}).
-		SetQueryParams(params).
-		Get(fmt.Sprintf("%s/user/infra/zone", api.pathPrefix))
+	return paginate[InfraZoneGetResponse](
+		ctx, api, fmt.Sprintf("%s/user/infra/zone", api.pathPrefix), params,
+	)
+}
+
+// resolveZone returns the id of the zone named zoneName in the region named
+// regionName, as selected by ZoneSelector.
+func resolveZone(
+	ctx context.Context, client InfraClient, zoneName string, regionName string,
+) (string, error) {
+	var regionIdPtr *string = nil
+	if regionName != "" {
+		regions, err := Collect(client.GetRegions(ctx, &regionName))
+		if err != nil {
+			return "", fmt.Errorf("failed to get regions (region_name: %s): %w", regionName, err)
+		}
+
+		var matches []string
+		for _, region := range regions {
+			if strings.EqualFold(region.Name, regionName) {
+				matches = append(matches, region.Id.String())
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return "", fmt.Errorf(
+				"no such region: no region is named %s. Please check region_name", regionName,
+			)
+		case 1:
+			regionIdPtr = &matches[0]
+		default:
+			return "", fmt.Errorf(
+				"ambiguous region: %d regions are named %s (ids: %s). "+
+					"Please select the zone using zone_id",
+				len(matches), regionName, strings.Join(matches, ", "),
+			)
+		}
+	}
+
+	var zoneNamePtr *string = nil
+	if zoneName != "" {
+		zoneNamePtr = &zoneName
+	}
+
+	zones, err := Collect(client.GetZones(ctx, regionIdPtr, zoneNamePtr))
+	if err != nil {
+		return "", fmt.Errorf("failed to get zones (zone_name: %s): %w", zoneName, err)
+	}
+
+	var matches []InfraZoneGetResponse
+	for _, zone := range zones {
+		if zoneName == "" || strings.EqualFold(zone.Name, zoneName) {
+			matches = append(matches, zone)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf(
+			"no such zone: no zone matches zone_name %q and region_name %q. "+
+				"Please check the names of the zone and the region",
+			zoneName, regionName,
+		)
+	case 1:
+		return matches[0].Id.String(), nil
+	}
+
+	descriptions := make([]string, 0, len(matches))
+	for _, zone := range matches {
+		descriptions = append(descriptions, fmt.Sprintf(
+			"%s (id: %s, region id: %s)", zone.Name, zone.Id, zone.RegionId,
+		))
+	}
 
-	return handleListAPIResponse[InfraZoneGetResponse](resp, err)
+	return "", fmt.Errorf(
+		"ambiguous zone: %d zones match zone_name %q and region_name %q: %s. "+
+			"Please narrow them down with region_name, or select the zone using zone_id",
+		len(matches), zoneName, regionName, strings.Join(descriptions, ", "),
+	)
 }
@@ -21,7 +21,7 @@ func NewBlockStorageImageDataSource() datasource.DataSource {
 }
 
 type BlockStorageImageDataSource struct {
-	client *api.APIClient
+	client api.InfraClient
 }
 
 type BlockStorageImageDataSourceModel struct {
@@ -42,13 +42,13 @@ func (d *BlockStorageImageDataSource) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	client, ok := req.ProviderData.(api.InfraClient)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
 			fmt.Sprintf(
-				`expected *api.APIClient, got: %T. 
+				`expected api.InfraClient, got: %T. 
 				please report this issue to the provider developers.`,
 				req.ProviderData,
 			),
@@ -136,6 +136,11 @@ func BlockStorageImageGetResponseToBlockStorageImageModel(
 func (d *BlockStorageImageDataSource) Read(
 	ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse,
 ) {
+	if d.client == nil {
+		readWithoutClient(req, resp)
+		return
+	}
+
 	var config BlockStorageImageDataSourceModel
 	var state BlockStorageImageDataSourceModel
 
@@ -144,7 +149,9 @@ func (d *BlockStorageImageDataSource) Read(
 		return
 	}
 
-	images, err := d.client.GetBlockStorageImages(config.Name.ValueStringPointer(), 0, 2)
+	images, err := api.Collect(
+		d.client.GetBlockStorageImages(ctx, config.Name.ValueStringPointer()),
+	)
 
 	if err != nil {
 		resp.Diagnostics.AddError(
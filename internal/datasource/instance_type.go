@@ -21,7 +21,7 @@ func NewInstanceTypeDataSource() datasource.DataSource {
 }
 
 type InstanceTypeDataSource struct {
-	client *api.APIClient
+	client api.InfraClient
 }
 
 type InstanceTypeDataSourceModel struct {
@@ -44,13 +44,13 @@ func (d *InstanceTypeDataSource) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	client, ok := req.ProviderData.(api.InfraClient)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
 			fmt.Sprintf(
-				`expected *api.APIClient, got: %T. 
+				`expected api.InfraClient, got: %T. 
 				please report this issue to the provider developers.`,
 				req.ProviderData,
 			),
@@ -159,6 +159,11 @@ func InstanceTypeGetResponseToInstanceTypeModel(
 func (d *InstanceTypeDataSource) Read(
 	ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse,
 ) {
+	if d.client == nil {
+		readWithoutClient(req, resp)
+		return
+	}
+
 	var config InstanceTypeDataSourceModel
 	var state InstanceTypeDataSourceModel
 
@@ -169,7 +174,9 @@ func (d *InstanceTypeDataSource) Read(
 
 	filterActivated := true
 
-	instances, err := d.client.GetInstanceTypes(config.Name.ValueStringPointer(), &filterActivated, 0, 2)
+	instances, err := api.Collect(
+		d.client.GetInstanceTypes(ctx, config.Name.ValueStringPointer(), &filterActivated),
+	)
 
 	if err != nil {
 		resp.Diagnostics.AddError(
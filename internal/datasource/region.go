@@ -21,7 +21,7 @@ func NewRegionDataSource() datasource.DataSource {
 }
 
 type RegionDataSource struct {
-	client *api.APIClient
+	client api.InfraClient
 }
 
 type RegionDataSourceModel struct {
@@ -36,13 +36,13 @@ func (d *RegionDataSource) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	client, ok := req.ProviderData.(api.InfraClient)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
 			fmt.Sprintf(
-				`expected *api.APIClient, got: %T. 
+				`expected api.InfraClient, got: %T. 
 				please report this issue to the provider developers.`,
 				req.ProviderData,
 			),
@@ -93,6 +93,11 @@ func RegionGetResponseToRegionModel(
 func (d *RegionDataSource) Read(
 	ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse,
 ) {
+	if d.client == nil {
+		readWithoutClient(req, resp)
+		return
+	}
+
 	var config RegionDataSourceModel
 	var state RegionDataSourceModel
 
@@ -101,7 +106,7 @@ func (d *RegionDataSource) Read(
 		return
 	}
 
-	regions, err := d.client.GetRegions(config.Name.ValueStringPointer(), 0, 2)
+	regions, err := api.Collect(d.client.GetRegions(ctx, config.Name.ValueStringPointer()))
 
 	if err != nil {
 		resp.Diagnostics.AddError(
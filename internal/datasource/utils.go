@@ -0,0 +1,24 @@
+package datasource
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+)
+
+// readWithoutClient reads a data source of a provider that is not configured
+// because its configuration is not known yet. The read is deferred when
+// Terraform can defer it. Otherwise it is skipped with a warning, so that the
+// plan goes on with the attributes of the data source left null.
+func readWithoutClient(req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	if req.ClientCapabilities.DeferralAllowed {
+		resp.Deferred = &datasource.Deferred{
+			Reason: datasource.DeferredReasonProviderConfigUnknown,
+		}
+		return
+	}
+
+	resp.Diagnostics.AddWarning(
+		"provider is not configured",
+		"the configuration of the provider is not known yet, so the data source is not "+
+			"read and its attributes are null",
+	)
+}
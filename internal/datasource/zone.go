@@ -22,7 +22,7 @@ func NewZoneDataSource() datasource.DataSource {
 }
 
 type ZoneDataSource struct {
-	client *api.APIClient
+	client api.InfraClient
 }
 
 type ZoneDataSourceModel struct {
@@ -39,13 +39,13 @@ func (d *ZoneDataSource) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	client, ok := req.ProviderData.(api.InfraClient)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
 			fmt.Sprintf(
-				`expected *api.APIClient, got: %T. 
+				`expected api.InfraClient, got: %T. 
 				please report this issue to the provider developers.`,
 				req.ProviderData,
 			),
@@ -107,6 +107,11 @@ func ZoneGetResponseToZoneModel(
 func (d *ZoneDataSource) Read(
 	ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse,
 ) {
+	if d.client == nil {
+		readWithoutClient(req, resp)
+		return
+	}
+
 	var config ZoneDataSourceModel
 	var state ZoneDataSourceModel
 
@@ -115,8 +120,11 @@ func (d *ZoneDataSource) Read(
 		return
 	}
 
-	zones, err := d.client.GetZones(
-		config.RegionId.ValueStringPointer(), config.Name.ValueStringPointer(), 0, 2)
+	zones, err := api.Collect(
+		d.client.GetZones(
+			ctx, config.RegionId.ValueStringPointer(), config.Name.ValueStringPointer(),
+		),
+	)
 
 	if err != nil {
 		resp.Diagnostics.AddError(
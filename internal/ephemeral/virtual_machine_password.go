@@ -0,0 +1,151 @@
+package ephemeral
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ ephemeral.EphemeralResource = &VirtualMachinePasswordEphemeralResource{}
+
+const (
+	defaultPasswordLength = 20
+	minPasswordLength     = 8
+	maxPasswordLength     = 64
+)
+
+// passwordCharacterClasses are the classes of characters of a password, each
+// of which it has at least one character of.
+var passwordCharacterClasses = []string{
+	"abcdefghijklmnopqrstuvwxyz",
+	"ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"0123456789",
+	"!#%*+-=?@^_",
+}
+
+func NewVirtualMachinePasswordEphemeralResource() ephemeral.EphemeralResource {
+	return &VirtualMachinePasswordEphemeralResource{}
+}
+
+type VirtualMachinePasswordEphemeralResource struct{}
+
+type VirtualMachinePasswordEphemeralResourceModel struct {
+	Length types.Int64  `tfsdk:"length"`
+	Result types.String `tfsdk:"result"`
+}
+
+func (e *VirtualMachinePasswordEphemeralResource) Metadata(
+	_ context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse,
+) {
+	resp.TypeName = req.ProviderTypeName + "_virtual_machine_password"
+}
+
+func (e *VirtualMachinePasswordEphemeralResource) Schema(
+	_ context.Context, _ ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse,
+) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Virtual Machine Password\n\n" +
+			"Generates a password that virtual machines accept, i.e. one with a lowercase " +
+			"letter, an uppercase letter, a digit and a special character. It is never " +
+			"stored in the plan or the state, so it is meant for `password_wo` of " +
+			"`eci_virtual_machine`.",
+
+		Attributes: map[string]schema.Attribute{
+			"length": schema.Int64Attribute{
+				Description: fmt.Sprintf(
+					"length of the password, from %d to %d. Defaults to %d",
+					minPasswordLength, maxPasswordLength, defaultPasswordLength,
+				),
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.Between(minPasswordLength, maxPasswordLength),
+				},
+			},
+			"result": schema.StringAttribute{
+				Description: "generated password",
+				Computed:    true,
+				Sensitive:   true,
+			},
+		},
+	}
+}
+
+func (e *VirtualMachinePasswordEphemeralResource) Open(
+	ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse,
+) {
+	var data VirtualMachinePasswordEphemeralResourceModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	length := defaultPasswordLength
+	if !data.Length.IsNull() {
+		length = int(data.Length.ValueInt64())
+	}
+
+	password, err := generatePassword(length)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"failed to generate a password", fmt.Sprintf("error: %v", err.Error()),
+		)
+		return
+	}
+
+	data.Result = types.StringValue(password)
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
+
+// generatePassword returns a random password of length characters, with at
+// least one character of each of passwordCharacterClasses.
+func generatePassword(length int) (string, error) {
+	all := ""
+	for _, class := range passwordCharacterClasses {
+		all += class
+	}
+
+	password := make([]byte, length)
+	for i := range password {
+		characters := all
+		if i < len(passwordCharacterClasses) {
+			characters = passwordCharacterClasses[i]
+		}
+
+		character, err := randomIndex(len(characters))
+		if err != nil {
+			return "", err
+		}
+		password[i] = characters[character]
+	}
+
+	// The characters of each class are shuffled in, not left at the start.
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+// randomIndex returns a uniformly random integer in [0, n).
+func randomIndex(n int) (int, error) {
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(index.Int64()), nil
+}
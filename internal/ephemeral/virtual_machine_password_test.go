@@ -0,0 +1,31 @@
+package ephemeral
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	for _, length := range []int{minPasswordLength, defaultPasswordLength, maxPasswordLength} {
+		password, err := generatePassword(length)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(password) != length {
+			t.Errorf("length: got %d, want %d", len(password), length)
+		}
+
+		for _, class := range passwordCharacterClasses {
+			if !strings.ContainsAny(password, class) {
+				t.Errorf("%q has no character of %q", password, class)
+			}
+		}
+	}
+
+	first, _ := generatePassword(defaultPasswordLength)
+	second, _ := generatePassword(defaultPasswordLength)
+	if first == second {
+		t.Errorf("two passwords are the same: %q", first)
+	}
+}
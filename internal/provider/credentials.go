@@ -0,0 +1,286 @@
+package provider
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Environment variables that the provider falls back to when an attribute is
+// not configured.
+const (
+	apiEndpointEnvVar     = "ECI_API_ENDPOINT"
+	apiTokenEnvVar        = "ECI_API_TOKEN"
+	zoneIdEnvVar          = "ECI_ZONE_ID"
+	zoneNameEnvVar        = "ECI_ZONE_NAME"
+	regionNameEnvVar      = "ECI_REGION_NAME"
+	profileEnvVar         = "ECI_PROFILE"
+	credentialsFileEnvVar = "ECI_CREDENTIALS_FILE"
+)
+
+const defaultProfile = "default"
+
+// credentialsProfile is a named section of the credentials file, e.g.
+//
+//	[staging]
+//	api_endpoint     = https://portal.elice.cloud/api
+//	api_access_token = ...
+//	zone_id          = ...
+//
+// where the zone may be given by zone_name and region_name instead.
+type credentialsProfile struct {
+	ApiEndpoint    string
+	ApiAccessToken string
+	ZoneId         string
+	ZoneName       string
+	RegionName     string
+}
+
+// defaultCredentialsFile returns the path of the credentials file, which is
+// `~/.config/eci/credentials` unless ECI_CREDENTIALS_FILE is set.
+func defaultCredentialsFile() (string, error) {
+	if file := os.Getenv(credentialsFileEnvVar); file != "" {
+		return file, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".config", "eci", "credentials"), nil
+}
+
+// readCredentialsFile reads the profiles of the credentials file at path.
+func readCredentialsFile(path string) (map[string]credentialsProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseCredentials(file)
+}
+
+// parseCredentials parses profiles given as INI sections of `key = value`
+// lines. Blank lines and lines starting with `#` or `;` are ignored.
+func parseCredentials(r io.Reader) (map[string]credentialsProfile, error) {
+	profiles := map[string]credentialsProfile{}
+	profileName := ""
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			profileName = strings.TrimSpace(line[1 : len(line)-1])
+			profiles[profileName] = profiles[profileName]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected `key = value`", lineNumber)
+		}
+		if profileName == "" {
+			return nil, fmt.Errorf("line %d: expected a [profile] before settings", lineNumber)
+		}
+
+		profile := profiles[profileName]
+		value = strings.TrimSpace(value)
+
+		switch key = strings.TrimSpace(key); key {
+		case "api_endpoint":
+			profile.ApiEndpoint = value
+		case "api_access_token":
+			profile.ApiAccessToken = value
+		case "zone_id":
+			profile.ZoneId = value
+		case "zone_name":
+			profile.ZoneName = value
+		case "region_name":
+			profile.RegionName = value
+		default:
+			return nil, fmt.Errorf("line %d: unknown setting %q", lineNumber, key)
+		}
+
+		profiles[profileName] = profile
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// providerSettings are the settings of the provider that may come from the
+// configuration, the environment or the credentials file.
+//
+// The zone is selected either by ZoneId, or by ZoneName and RegionName, which
+// are resolved into a zone id once the client is created.
+type providerSettings struct {
+	ApiEndpoint    string
+	ApiAccessToken string
+	ZoneId         string
+	ZoneName       string
+	RegionName     string
+}
+
+// selectsZone reports whether the settings select a zone, by id or by name.
+func (s providerSettings) selectsZone() bool {
+	return s.ZoneId != "" || s.ZoneName != "" || s.RegionName != ""
+}
+
+// selectZone selects the zone of from, unless a zone is already selected. The
+// zone is taken as a whole from a single source so that, e.g., ECI_ZONE_ID
+// does not get mixed up with a configured zone_name.
+func (s *providerSettings) selectZone(from providerSettings) {
+	if s.selectsZone() {
+		return
+	}
+
+	s.ZoneId = from.ZoneId
+	s.ZoneName = from.ZoneName
+	s.RegionName = from.RegionName
+}
+
+// resolveProviderSettings resolves each setting from, in order of precedence:
+//
+//  1. the attribute in the provider configuration,
+//  2. its environment variable (e.g. ECI_API_TOKEN),
+//  3. the profile of the credentials file selected by the `profile`
+//     attribute, or ECI_PROFILE, or else `default`.
+//
+// The zone is selected by zone_id, or by zone_name and region_name, all of
+// which are taken from the first of those that sets any of them.
+//
+// A profile that is selected explicitly must exist, while the default one
+// may be missing along with the credentials file.
+func resolveProviderSettings(data EliceCloudProviderModel) (providerSettings, diag.Diagnostics) {
+	diags := diag.Diagnostics{}
+
+	settings := providerSettings{
+		ApiEndpoint:    stringOrEnv(data.ApiEndpoint, apiEndpointEnvVar),
+		ApiAccessToken: stringOrEnv(data.ApiAccessToken, apiTokenEnvVar),
+	}
+	settings.selectZone(providerSettings{
+		ZoneId:     data.ZoneId.ValueString(),
+		ZoneName:   data.ZoneName.ValueString(),
+		RegionName: data.RegionName.ValueString(),
+	})
+	settings.selectZone(providerSettings{
+		ZoneId:     os.Getenv(zoneIdEnvVar),
+		ZoneName:   os.Getenv(zoneNameEnvVar),
+		RegionName: os.Getenv(regionNameEnvVar),
+	})
+
+	profileName := stringOrEnv(data.Profile, profileEnvVar)
+	explicitProfile := profileName != ""
+	if !explicitProfile {
+		profileName = defaultProfile
+	}
+
+	complete := settings.ApiEndpoint != "" &&
+		settings.ApiAccessToken != "" &&
+		settings.selectsZone()
+
+	if explicitProfile || !complete {
+		profile, err := loadProfile(profileName, explicitProfile)
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("profile"),
+				"failed to load credentials profile",
+				fmt.Sprintf("profile: %s (reason: %s)", profileName, err),
+			)
+			return settings, diags
+		}
+
+		settings.ApiEndpoint = firstNonEmpty(settings.ApiEndpoint, profile.ApiEndpoint)
+		settings.ApiAccessToken = firstNonEmpty(settings.ApiAccessToken, profile.ApiAccessToken)
+		settings.selectZone(providerSettings{
+			ZoneId:     profile.ZoneId,
+			ZoneName:   profile.ZoneName,
+			RegionName: profile.RegionName,
+		})
+	}
+
+	for _, setting := range []struct {
+		attribute string
+		envVar    string
+		missing   bool
+	}{
+		{"api_endpoint", apiEndpointEnvVar, settings.ApiEndpoint == ""},
+		{"api_access_token", apiTokenEnvVar, settings.ApiAccessToken == ""},
+		{"zone_id", zoneIdEnvVar, !settings.selectsZone()},
+	} {
+		if !setting.missing {
+			continue
+		}
+
+		diags.AddAttributeError(
+			path.Root(setting.attribute),
+			fmt.Sprintf("missing %s", setting.attribute),
+			fmt.Sprintf(
+				"set %s in the provider configuration, the %s environment variable, "+
+					"or the credentials file (profile: %s)",
+				setting.attribute, setting.envVar, profileName,
+			),
+		)
+	}
+
+	return settings, diags
+}
+
+// loadProfile returns the profile of the credentials file named profileName.
+// Unless it is required, a missing file or profile is an empty profile.
+func loadProfile(profileName string, required bool) (credentialsProfile, error) {
+	file, err := defaultCredentialsFile()
+	if err != nil {
+		return credentialsProfile{}, err
+	}
+
+	profiles, err := readCredentialsFile(file)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return credentialsProfile{}, nil
+	}
+	if err != nil {
+		return credentialsProfile{}, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	profile, ok := profiles[profileName]
+	if !ok && required {
+		return credentialsProfile{}, fmt.Errorf("no such profile in %s", file)
+	}
+
+	return profile, nil
+}
+
+func stringOrEnv(value types.String, envVar string) string {
+	if value.IsNull() || value.IsUnknown() {
+		return os.Getenv(envVar)
+	}
+	return value.ValueString()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,303 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+const testCredentials = `
+# comments and blank lines are ignored
+[default]
+api_endpoint     = https://default.example.com/api
+api_access_token = default-token
+zone_id          = default-zone
+
+[staging]
+api_endpoint = https://staging.example.com/api
+; settings may be partial
+api_access_token = staging-token
+`
+
+// setCredentialsEnv sets the environment variables of the provider settings,
+// clearing the ones missing from env, and points the credentials file at a
+// file of credentials.
+func setCredentialsEnv(t *testing.T, credentials string, env map[string]string) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "credentials")
+	if credentials != "" {
+		if err := os.WriteFile(file, []byte(credentials), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(credentialsFileEnvVar, file)
+
+	for _, envVar := range []string{
+		apiEndpointEnvVar, apiTokenEnvVar, zoneIdEnvVar, zoneNameEnvVar, regionNameEnvVar,
+		profileEnvVar,
+	} {
+		t.Setenv(envVar, env[envVar])
+	}
+}
+
+func nullProviderModel() EliceCloudProviderModel {
+	return EliceCloudProviderModel{
+		ApiEndpoint:    types.StringNull(),
+		ApiAccessToken: types.StringNull(),
+		ZoneId:         types.StringNull(),
+		ZoneName:       types.StringNull(),
+		RegionName:     types.StringNull(),
+		Profile:        types.StringNull(),
+	}
+}
+
+func TestParseCredentials(t *testing.T) {
+	profiles, err := parseCredentials(strings.NewReader(testCredentials))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]credentialsProfile{
+		"default": {
+			ApiEndpoint:    "https://default.example.com/api",
+			ApiAccessToken: "default-token",
+			ZoneId:         "default-zone",
+		},
+		"staging": {
+			ApiEndpoint:    "https://staging.example.com/api",
+			ApiAccessToken: "staging-token",
+		},
+	}
+	if len(profiles) != len(want) {
+		t.Fatalf("got %d profiles, want %d", len(profiles), len(want))
+	}
+	for name, profile := range want {
+		if profiles[name] != profile {
+			t.Errorf("profile %s: got %+v, want %+v", name, profiles[name], profile)
+		}
+	}
+}
+
+func TestParseCredentialsRejectsInvalidLines(t *testing.T) {
+	for name, credentials := range map[string]string{
+		"no profile":      "zone_id = zone\n",
+		"no value":        "[default]\nzone_id\n",
+		"unknown setting": "[default]\nregion = seoul\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseCredentials(strings.NewReader(credentials)); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestResolveProviderSettingsPrecedence(t *testing.T) {
+	setCredentialsEnv(t, testCredentials, map[string]string{
+		apiTokenEnvVar: "env-token",
+		zoneIdEnvVar:   "env-zone",
+	})
+
+	data := nullProviderModel()
+	data.ZoneId = types.StringValue("config-zone")
+
+	settings, diags := resolveProviderSettings(data)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	want := providerSettings{
+		ApiEndpoint:    "https://default.example.com/api",
+		ApiAccessToken: "env-token",
+		ZoneId:         "config-zone",
+	}
+	if settings != want {
+		t.Errorf("got %+v, want %+v", settings, want)
+	}
+}
+
+func TestResolveProviderSettingsProfile(t *testing.T) {
+	setCredentialsEnv(t, testCredentials, map[string]string{profileEnvVar: "default"})
+
+	data := nullProviderModel()
+	data.Profile = types.StringValue("staging")
+	data.ZoneId = types.StringValue("config-zone")
+
+	settings, diags := resolveProviderSettings(data)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	want := providerSettings{
+		ApiEndpoint:    "https://staging.example.com/api",
+		ApiAccessToken: "staging-token",
+		ZoneId:         "config-zone",
+	}
+	if settings != want {
+		t.Errorf("got %+v, want %+v", settings, want)
+	}
+}
+
+func TestResolveProviderSettingsZone(t *testing.T) {
+	setCredentialsEnv(t, testCredentials, map[string]string{
+		zoneIdEnvVar:     "env-zone",
+		regionNameEnvVar: "env-region",
+	})
+
+	data := nullProviderModel()
+	data.ZoneName = types.StringValue("config-zone")
+
+	settings, diags := resolveProviderSettings(data)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+
+	// The zone is taken as a whole from the configuration.
+	want := providerSettings{
+		ApiEndpoint:    "https://default.example.com/api",
+		ApiAccessToken: "default-token",
+		ZoneName:       "config-zone",
+	}
+	if settings != want {
+		t.Errorf("got %+v, want %+v", settings, want)
+	}
+}
+
+func TestResolveProviderSettingsErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		credentials string
+		env         map[string]string
+		summary     string
+	}{
+		"unknown profile": {
+			credentials: testCredentials,
+			env:         map[string]string{profileEnvVar: "production"},
+			summary:     "failed to load credentials profile",
+		},
+		"missing file of explicit profile": {
+			env:     map[string]string{profileEnvVar: "default"},
+			summary: "failed to load credentials profile",
+		},
+		"missing settings": {
+			env: map[string]string{
+				apiEndpointEnvVar: "https://example.com/api",
+				apiTokenEnvVar:    "token",
+			},
+			summary: "missing zone_id",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			setCredentialsEnv(t, test.credentials, test.env)
+
+			_, diags := resolveProviderSettings(nullProviderModel())
+
+			if !diags.HasError() {
+				t.Fatalf("expected an error")
+			}
+			if summary := diags.Errors()[0].Summary(); summary != test.summary {
+				t.Errorf("summary: got %q, want %q", summary, test.summary)
+			}
+		})
+	}
+}
+
+func TestAccProviderConfiguredFromEnvironment(t *testing.T) {
+	env := newTestAccEnvironment(t)
+	setCredentialsEnv(t, "", map[string]string{
+		apiEndpointEnvVar: env.Endpoint,
+		apiTokenEnvVar:    env.Token,
+		zoneIdEnvVar:      env.ZoneId,
+	})
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `provider "eci" {}` + testAccRegionConfig(env.RegionName),
+				Check:  resource.TestCheckResourceAttrSet("data.eci_region.test", "id"),
+			},
+		},
+	})
+}
+
+func TestAccProviderConfiguredFromCredentialsFile(t *testing.T) {
+	env := newTestAccEnvironment(t)
+	setCredentialsEnv(t, fmt.Sprintf(`
+[default]
+api_endpoint = https://default.invalid/api
+
+[test]
+api_endpoint     = %s
+api_access_token = %s
+zone_id          = %s
+`, env.Endpoint, env.Token, env.ZoneId), nil)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: `
+provider "eci" {
+  profile = "test"
+}
+` + testAccRegionConfig(env.RegionName),
+				Check: resource.TestCheckResourceAttrSet("data.eci_region.test", "id"),
+			},
+		},
+	})
+}
+
+func TestAccProviderConfiguredWithZoneName(t *testing.T) {
+	env := newTestAccEnvironment(t)
+	setCredentialsEnv(t, "", nil)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+provider "eci" {
+  api_endpoint     = %q
+  api_access_token = %q
+  zone_name        = "no-such-zone"
+}
+`, env.Endpoint, env.Token) + testAccVirtualNetworkConfig("tf-acc-network", "10.0.0.0/16"),
+				ExpectError: regexp.MustCompile("no such zone"),
+			},
+			{
+				// The zone is looked up when configuring, even if no resource
+				// needs it.
+				Config: fmt.Sprintf(`
+provider "eci" {
+  api_endpoint     = %q
+  api_access_token = %q
+  zone_name        = "no-such-zone"
+}
+`, env.Endpoint, env.Token) + testAccRegionConfig(env.RegionName),
+				ExpectError: regexp.MustCompile("failed to select the zone"),
+			},
+			{
+				Config: fmt.Sprintf(`
+provider "eci" {
+  api_endpoint     = %q
+  api_access_token = %q
+  zone_name        = %q
+  region_name      = %q
+}
+`, env.Endpoint, env.Token, env.ZoneName, env.RegionName) +
+					testAccVirtualNetworkConfig("tf-acc-network", "10.0.0.0/16"),
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_network.test", "zone_id", env.ZoneId,
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccDataSourceBlockStorageImage(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"data.eci_block_storage_image.test", "name", env.BlockStorageImageName,
+					),
+					resource.TestCheckResourceAttr(
+						"data.eci_block_storage_image.test", "zone_id", env.ZoneId,
+					),
+					resource.TestCheckResourceAttrSet("data.eci_block_storage_image.test", "id"),
+					resource.TestCheckResourceAttrSet(
+						"data.eci_block_storage_image.test", "size_gib",
+					),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccDataSourceInstanceType(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(""),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"data.eci_instance_type.test", "name", env.InstanceTypeName,
+					),
+					resource.TestCheckResourceAttr(
+						"data.eci_instance_type.test", "zone_id", env.ZoneId,
+					),
+					resource.TestCheckResourceAttrSet("data.eci_instance_type.test", "id"),
+					resource.TestCheckResourceAttrSet("data.eci_instance_type.test", "cpu_vcore"),
+					resource.TestCheckResourceAttrSet("data.eci_instance_type.test", "memory_gib"),
+				),
+			},
+		},
+	})
+}
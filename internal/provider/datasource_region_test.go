@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccDataSourceRegion(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccRegionConfig(env.RegionName)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.eci_region.test", "name", env.RegionName),
+					resource.TestCheckResourceAttrSet("data.eci_region.test", "id"),
+				),
+			},
+		},
+	})
+}
+
+func testAccRegionConfig(name string) string {
+	return fmt.Sprintf(`
+data "eci_region" "test" {
+  name = %q
+}
+`, name)
+}
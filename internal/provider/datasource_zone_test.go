@@ -0,0 +1,33 @@
+package provider
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccDataSourceZone(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccRegionConfig(env.RegionName) + fmt.Sprintf(`
+data "eci_zone" "test" {
+  name      = %q
+  region_id = data.eci_region.test.id
+}
+`, env.ZoneName)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.eci_zone.test", "id", env.ZoneId),
+					resource.TestCheckResourceAttr("data.eci_zone.test", "name", env.ZoneName),
+					resource.TestCheckResourceAttrPair(
+						"data.eci_zone.test", "region_id", "data.eci_region.test", "id",
+					),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestAccEphemeralVirtualMachinePassword(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		// Write-only attributes, which the password is meant for, need 1.11.
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_11_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(`
+ephemeral "eci_virtual_machine_password" "test" {
+  length = 4
+}
+`),
+				ExpectError: regexp.MustCompile("Invalid Attribute Value"),
+			},
+			{
+				Config: env.config(`
+ephemeral "eci_virtual_machine_password" "test" {}
+
+resource "eci_virtual_machine" "test" {
+  name                = "tf-acc-vm"
+  instance_type_id    = data.eci_instance_type.test.id
+  always_on           = false
+  dr                  = false
+  username            = "elice"
+  password_wo         = ephemeral.eci_virtual_machine_password.test.result
+  password_wo_version = 1
+  on_init_script      = ""
+}
+`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("eci_virtual_machine.test", "status", "idle"),
+					resource.TestCheckNoResourceAttr("eci_virtual_machine.test", "password"),
+					resource.TestCheckNoResourceAttr("eci_virtual_machine.test", "password_wo"),
+				),
+			},
+		},
+	})
+}
@@ -7,39 +7,63 @@ import (
 
 	"terraform-provider-eci/internal/api"
 	ds "terraform-provider-eci/internal/datasource"
+	eph "terraform-provider-eci/internal/ephemeral"
 	res "terraform-provider-eci/internal/resource"
 
+	"github.com/hashicorp/terraform-plugin-framework-validators/float64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 var (
-	_ provider.Provider = &EliceCloudProvider{}
+	_ provider.Provider                       = &EliceCloudProvider{}
+	_ provider.ProviderWithEphemeralResources = &EliceCloudProvider{}
 )
 
-func New(version string, debug bool) func() provider.Provider {
+func New(version string) func() provider.Provider {
 	return func() provider.Provider {
 		return &EliceCloudProvider{
 			version: version,
-			debug:   debug,
 		}
 	}
 }
 
 type EliceCloudProvider struct {
 	version string
-	debug   bool
 }
 
 type EliceCloudProviderModel struct {
 	ApiEndpoint    types.String `tfsdk:"api_endpoint"`
 	ApiAccessToken types.String `tfsdk:"api_access_token"`
 	ZoneId         types.String `tfsdk:"zone_id"`
+	ZoneName       types.String `tfsdk:"zone_name"`
+	RegionName     types.String `tfsdk:"region_name"`
+	Profile        types.String `tfsdk:"profile"`
+	MaxRetries     types.Int64  `tfsdk:"max_retries"`
+
+	RequestsPerSecond     types.Float64 `tfsdk:"requests_per_second"`
+	MaxConcurrentRequests types.Int64   `tfsdk:"max_concurrent_requests"`
+
+	DefaultTags *DefaultTagsModel `tfsdk:"default_tags"`
+	IgnoreTags  *IgnoreTagsModel  `tfsdk:"ignore_tags"`
+}
+
+type DefaultTagsModel struct {
+	Tags types.Map `tfsdk:"tags"`
+}
+
+type IgnoreTagsModel struct {
+	Keys        types.Set `tfsdk:"keys"`
+	KeyPrefixes types.Set `tfsdk:"key_prefixes"`
 }
 
 func (p *EliceCloudProvider) Metadata(
@@ -57,19 +81,119 @@ func (p *EliceCloudProvider) Schema(
 	resp *provider.SchemaResponse,
 ) {
 	resp.Schema = schema.Schema{
+		MarkdownDescription: "Each of `api_endpoint`, `api_access_token` and the zone is " +
+			"taken from, in order of precedence:\n\n" +
+			"1. the provider configuration,\n" +
+			"2. the `ECI_API_ENDPOINT`, `ECI_API_TOKEN` and `ECI_ZONE_ID` (or " +
+			"`ECI_ZONE_NAME` and `ECI_REGION_NAME`) environment variables,\n" +
+			"3. a profile of the credentials file `~/.config/eci/credentials` (or the file " +
+			"given by `ECI_CREDENTIALS_FILE`), selected by `profile` or `ECI_PROFILE` and " +
+			"`default` otherwise.\n\n" +
+			"The credentials file holds a section of settings per profile:\n\n" +
+			"```ini\n" +
+			"[default]\n" +
+			"api_endpoint     = https://portal.elice.cloud/api\n" +
+			"api_access_token = <token>\n" +
+			"zone_id          = <zone id>\n" +
+			"```\n\n" +
+			"The zone is selected either by `zone_id`, or by `zone_name` and `region_name`, " +
+			"which must select exactly one zone. Names are resolved into the id of the zone " +
+			"when the provider is configured; this is the only time configuring the provider " +
+			"contacts the portal.\n\n" +
+			"Values that are not known until applying, such as attributes of other resources, " +
+			"defer the resources of the provider, and the zone is then resolved when the " +
+			"provider is configured for applying. Where Terraform cannot defer them, data " +
+			"sources of the provider are not read until then, and their attributes are null.",
+
 		Attributes: map[string]schema.Attribute{
 			"api_access_token": schema.StringAttribute{
-				Description: "API access token",
-				Required:    true,
+				Description: "API access token. Can also be set with `ECI_API_TOKEN`",
+				Optional:    true,
 				Sensitive:   true,
 			},
 			"api_endpoint": schema.StringAttribute{
-				Description: "API endpoint URL (e.g., https://portal.elice.cloud/api/)",
-				Required:    true,
+				Description: "API endpoint URL (e.g., https://portal.elice.cloud/api/). " +
+					"Can also be set with `ECI_API_ENDPOINT`",
+				Optional: true,
 			},
 			"zone_id": schema.StringAttribute{
-				Description: "ID of the zone (UUID) that you will manage resources in",
-				Required:    true,
+				Description: "ID of the zone (UUID) that you will manage resources in. " +
+					"Can also be set with `ECI_ZONE_ID`",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.ConflictsWith(
+						path.MatchRoot("zone_name"), path.MatchRoot("region_name"),
+					),
+				},
+			},
+			"zone_name": schema.StringAttribute{
+				Description: "name of the zone that you will manage resources in, instead of " +
+					"`zone_id`. Can also be set with `ECI_ZONE_NAME`",
+				Optional: true,
+			},
+			"region_name": schema.StringAttribute{
+				Description: "name of the region of the zone, which selects the zone along with " +
+					"`zone_name`, or alone if the region has a single zone. " +
+					"Can also be set with `ECI_REGION_NAME`",
+				Optional: true,
+			},
+			"profile": schema.StringAttribute{
+				Description: "profile of the credentials file to take the settings from that " +
+					"are not configured otherwise. Can also be set with `ECI_PROFILE` " +
+					"(default: default)",
+				Optional: true,
+			},
+			"max_retries": schema.Int64Attribute{
+				Description: fmt.Sprintf(
+					"maximum number of retries for transient API failures (default: %d)",
+					api.DefaultMaxRetries,
+				),
+				Optional:   true,
+				Validators: []validator.Int64{int64validator.AtLeast(0)},
+			},
+			"requests_per_second": schema.Float64Attribute{
+				Description: fmt.Sprintf(
+					"maximum number of API requests per second across all resources (default: %v)",
+					api.DefaultRequestsPerSecond,
+				),
+				Optional:   true,
+				Validators: []validator.Float64{float64validator.AtLeast(0.1)},
+			},
+			"max_concurrent_requests": schema.Int64Attribute{
+				Description: fmt.Sprintf(
+					"maximum number of API requests in flight at the same time (default: %d)",
+					api.DefaultMaxConcurrentRequests,
+				),
+				Optional:   true,
+				Validators: []validator.Int64{int64validator.AtLeast(1)},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"default_tags": schema.SingleNestedBlock{
+				Description: "tags of every resource, merged under the tags of the resource",
+				Attributes: map[string]schema.Attribute{
+					"tags": schema.MapAttribute{
+						Description: "default tags of key-value pairs",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+				},
+			},
+			"ignore_tags": schema.SingleNestedBlock{
+				Description: "tags that are managed outside Terraform, which are neither read " +
+					"nor removed from resources",
+				Attributes: map[string]schema.Attribute{
+					"keys": schema.SetAttribute{
+						Description: "keys of the ignored tags",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+					"key_prefixes": schema.SetAttribute{
+						Description: "prefixes of the keys of the ignored tags",
+						ElementType: types.StringType,
+						Optional:    true,
+					},
+				},
 			},
 		},
 	}
@@ -87,35 +211,51 @@ func (p *EliceCloudProvider) Configure(
 		return
 	}
 
-	if data.ApiEndpoint.IsUnknown() {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("api_endpoint"),
-			"missing api_endpoint",
-			"missing api_endpoint",
-		)
+	if !req.Config.Raw.IsFullyKnown() {
+		// Values such as the attributes of other resources are known only
+		// when applying. Terraform defers the resources of the provider
+		// until then if it can; otherwise they are planned without a client,
+		// and the provider is configured again before applying.
+		if req.ClientCapabilities.DeferralAllowed {
+			resp.Deferred = &provider.Deferred{
+				Reason: provider.DeferredReasonProviderConfigUnknown,
+			}
+		}
+
+		tflog.Info(ctx, "provider configuration is not known yet, skipping configuring")
+		return
 	}
 
-	if data.ApiAccessToken.IsUnknown() {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("api_access_token"),
-			"missing api_access_token",
-			"missing api_access_token",
-		)
+	defaultTags := map[string]string{}
+	if data.DefaultTags != nil && !data.DefaultTags.Tags.IsNull() {
+		resp.Diagnostics.Append(data.DefaultTags.Tags.ElementsAs(ctx, &defaultTags, false)...)
 	}
 
-	if data.ZoneId.IsUnknown() {
-		resp.Diagnostics.AddAttributeError(
-			path.Root("zone_id"),
-			"missing zone_id",
-			"missing zone_id",
-		)
+	var ignoreKeys, ignoreKeyPrefixes []string
+	if data.IgnoreTags != nil {
+		if !data.IgnoreTags.Keys.IsNull() {
+			resp.Diagnostics.Append(data.IgnoreTags.Keys.ElementsAs(ctx, &ignoreKeys, false)...)
+		}
+
+		if !data.IgnoreTags.KeyPrefixes.IsNull() {
+			resp.Diagnostics.Append(
+				data.IgnoreTags.KeyPrefixes.ElementsAs(ctx, &ignoreKeyPrefixes, false)...,
+			)
+		}
 	}
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	baseURL := data.ApiEndpoint.ValueString()
+	settings, diags := resolveProviderSettings(data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	baseURL := settings.ApiEndpoint
 	parsedBaseURL, err := url.Parse(baseURL)
 	if err != nil {
 		resp.Diagnostics.AddError(
@@ -129,33 +269,51 @@ func (p *EliceCloudProvider) Configure(
 	parsedBaseURL.Path = ""
 	parsedBaseURL.RawPath = ""
 
-	tflog.Info(
-		ctx,
-		fmt.Sprintf("zone_id: %s", data.ZoneId.ValueString()),
-	)
+	maxRetries := api.DefaultMaxRetries
+	if !data.MaxRetries.IsNull() && !data.MaxRetries.IsUnknown() {
+		maxRetries = int(data.MaxRetries.ValueInt64())
+	}
 
-	client, err := api.NewAPIClient(
-		data.ApiAccessToken.ValueString(),
+	requestsPerSecond := api.DefaultRequestsPerSecond
+	if !data.RequestsPerSecond.IsNull() && !data.RequestsPerSecond.IsUnknown() {
+		requestsPerSecond = data.RequestsPerSecond.ValueFloat64()
+	}
+
+	maxConcurrentRequests := api.DefaultMaxConcurrentRequests
+	if !data.MaxConcurrentRequests.IsNull() && !data.MaxConcurrentRequests.IsUnknown() {
+		maxConcurrentRequests = int(data.MaxConcurrentRequests.ValueInt64())
+	}
+
+	client := api.NewAPIClient(
+		settings.ApiAccessToken,
 		parsedBaseURL.String(),
 		pathPrefix,
-		data.ZoneId.ValueString(),
-		p.debug,
+		api.ZoneSelector{
+			Id:         settings.ZoneId,
+			Name:       settings.ZoneName,
+			RegionName: settings.RegionName,
+		},
+		maxRetries,
+		requestsPerSecond,
+		maxConcurrentRequests,
 	)
 
-	if err != nil {
-		resp.Diagnostics.AddError(
-			"failed to create api client", fmt.Sprintf("Error: %v", err.Error()),
-		)
-		return
+	// A zone selected by name is looked up now, so that a name that selects no
+	// zone or several zones fails configuring rather than the first resource
+	// that is created in the zone.
+	if settings.ZoneId == "" {
+		if _, err := client.ZoneId(ctx); err != nil {
+			resp.Diagnostics.AddError("failed to select the zone", err.Error())
+			return
+		}
 	}
 
-	tflog.Info(
-		ctx,
-		fmt.Sprintf("organizeion_id: %s", client.OrganizationId),
-	)
-
 	resp.DataSourceData = client
-	resp.ResourceData = client
+	resp.ResourceData = &res.ProviderData{
+		Client: client,
+		Tags:   res.NewTagPolicy(defaultTags, ignoreKeys, ignoreKeyPrefixes),
+		ZoneId: client.ZoneId,
+	}
 }
 
 func (p *EliceCloudProvider) DataSources(_ context.Context) []func() datasource.DataSource {
@@ -203,3 +361,13 @@ func (p *EliceCloudProvider) Resources(_ context.Context) []func() resource.Reso
 		},
 	}
 }
+
+func (p *EliceCloudProvider) EphemeralResources(
+	_ context.Context,
+) []func() ephemeral.EphemeralResource {
+	return []func() ephemeral.EphemeralResource{
+		func() ephemeral.EphemeralResource {
+			return eph.NewVirtualMachinePasswordEphemeralResource()
+		},
+	}
+}
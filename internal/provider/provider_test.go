@@ -0,0 +1,536 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"terraform-provider-eci/internal/api"
+	"terraform-provider-eci/internal/api/fake"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// testAccProtoV6ProviderFactories serves the provider to Terraform in
+// acceptance tests.
+var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"eci": providerserver.NewProtocol6WithError(New("test")()),
+}
+
+// testAccPortalEnvVar runs acceptance tests against a real portal rather
+// than a fake one, when set to any value.
+const testAccPortalEnvVar = "ECI_ACC_PORTAL"
+
+// testAccEnvironment is the portal that acceptance tests run against, along
+// with the names of the infra that they refer to.
+type testAccEnvironment struct {
+	Endpoint string
+	Token    string
+	ZoneId   string
+
+	// Client checks what the tests did, e.g. that resources are destroyed.
+	Client api.Client
+
+	RegionName            string
+	ZoneName              string
+	OtherZoneId           string
+	InstanceTypeName      string
+	OtherInstanceTypeName string
+	BlockStorageImageName string
+}
+
+// newTestAccEnvironment returns the environment of an acceptance test.
+//
+// By default, it is a fake portal serving infra of known names. When
+// ECI_ACC_PORTAL is set, it is the portal given by ECI_API_ENDPOINT,
+// ECI_API_TOKEN and ECI_ZONE_ID, whose infra is named by ECI_ACC_REGION,
+// ECI_ACC_ZONE, ECI_ACC_INSTANCE_TYPE, ECI_ACC_OTHER_INSTANCE_TYPE and
+// ECI_ACC_BLOCK_STORAGE_IMAGE. Tests of resources in another zone than the
+// one of the provider also need ECI_ACC_OTHER_ZONE_ID.
+func newTestAccEnvironment(t *testing.T) *testAccEnvironment {
+	t.Helper()
+
+	if os.Getenv(resource.EnvTfAcc) == "" {
+		t.Skipf("acceptance tests skipped unless env '%s' set", resource.EnvTfAcc)
+	}
+
+	if os.Getenv(testAccPortalEnvVar) != "" {
+		return newTestAccPortalEnvironment(t)
+	}
+
+	return newTestAccFakeEnvironment(t)
+}
+
+func newTestAccFakeEnvironment(t *testing.T) *testAccEnvironment {
+	t.Helper()
+
+	client := fake.NewClient()
+	region := client.AddRegion(api.RegionGetResponse{Name: "seoul-1"})
+	client.AddZone(api.InfraZoneGetResponse{
+		Id: client.ZoneId, Name: "test-zone", RegionId: region.Id,
+	})
+	otherZone := client.AddZone(api.InfraZoneGetResponse{
+		Name: "other-zone", RegionId: region.Id,
+	})
+	client.AddInstanceType(api.InfraInstanceTypeGetResponse{
+		Name: "tiny", CpuVcore: 1, MemoryGib: 2, Activated: true,
+	})
+	client.AddInstanceType(api.InfraInstanceTypeGetResponse{
+		Name: "small", CpuVcore: 2, MemoryGib: 4, Activated: true,
+	})
+	client.AddBlockStorageImage(api.ResourceBlockStorageImageGetResponse{
+		Name: "Ubuntu 22.04", SizeGib: 10, Status: "active",
+	})
+
+	const token = "test-token"
+	server := fake.NewServer(client, token)
+	t.Cleanup(server.Close)
+
+	return &testAccEnvironment{
+		Endpoint:              server.URL,
+		Token:                 token,
+		ZoneId:                client.ZoneId.String(),
+		Client:                client,
+		RegionName:            "seoul-1",
+		ZoneName:              "test-zone",
+		OtherZoneId:           otherZone.Id.String(),
+		InstanceTypeName:      "tiny",
+		OtherInstanceTypeName: "small",
+		BlockStorageImageName: "Ubuntu 22.04",
+	}
+}
+
+func newTestAccPortalEnvironment(t *testing.T) *testAccEnvironment {
+	t.Helper()
+
+	env := &testAccEnvironment{
+		Endpoint:              os.Getenv("ECI_API_ENDPOINT"),
+		Token:                 os.Getenv("ECI_API_TOKEN"),
+		ZoneId:                os.Getenv("ECI_ZONE_ID"),
+		RegionName:            os.Getenv("ECI_ACC_REGION"),
+		ZoneName:              os.Getenv("ECI_ACC_ZONE"),
+		OtherZoneId:           os.Getenv("ECI_ACC_OTHER_ZONE_ID"),
+		InstanceTypeName:      os.Getenv("ECI_ACC_INSTANCE_TYPE"),
+		OtherInstanceTypeName: os.Getenv("ECI_ACC_OTHER_INSTANCE_TYPE"),
+		BlockStorageImageName: os.Getenv("ECI_ACC_BLOCK_STORAGE_IMAGE"),
+	}
+
+	for name, value := range map[string]string{
+		"ECI_API_ENDPOINT":            env.Endpoint,
+		"ECI_API_TOKEN":               env.Token,
+		"ECI_ZONE_ID":                 env.ZoneId,
+		"ECI_ACC_REGION":              env.RegionName,
+		"ECI_ACC_ZONE":                env.ZoneName,
+		"ECI_ACC_INSTANCE_TYPE":       env.InstanceTypeName,
+		"ECI_ACC_OTHER_INSTANCE_TYPE": env.OtherInstanceTypeName,
+		"ECI_ACC_BLOCK_STORAGE_IMAGE": env.BlockStorageImageName,
+	} {
+		if value == "" {
+			t.Fatalf("%s must be set for acceptance tests against a portal", name)
+		}
+	}
+
+	endpoint, err := url.Parse(env.Endpoint)
+	if err != nil {
+		t.Fatalf("failed to parse ECI_API_ENDPOINT: %v", err)
+	}
+	pathPrefix := endpoint.Path
+	endpoint.Path = ""
+	endpoint.RawPath = ""
+
+	env.Client = api.NewAPIClient(
+		env.Token,
+		endpoint.String(),
+		pathPrefix,
+		api.ZoneSelector{Id: env.ZoneId},
+		api.DefaultMaxRetries,
+		api.DefaultRequestsPerSecond,
+		api.DefaultMaxConcurrentRequests,
+	)
+
+	return env
+}
+
+// fakeClient returns the fake portal of the environment, skipping the test
+// when it runs against a real portal, whose timing it cannot control.
+func (e *testAccEnvironment) fakeClient(t *testing.T) *fake.Client {
+	t.Helper()
+
+	client, ok := e.Client.(*fake.Client)
+	if !ok {
+		t.Skip("the test needs the fake portal")
+	}
+
+	return client
+}
+
+// config returns the configuration of a test step: the provider pointed at
+// the environment, followed by body.
+func (e *testAccEnvironment) config(body string) string {
+	return e.configWithProvider("", body)
+}
+
+// configWithProvider is config with providerBody added to the provider block.
+func (e *testAccEnvironment) configWithProvider(providerBody string, body string) string {
+	return fmt.Sprintf(`
+provider "eci" {
+  api_endpoint     = %q
+  api_access_token = %q
+  zone_id          = %q
+%s
+}
+
+data "eci_instance_type" "test" {
+  name = %q
+}
+
+data "eci_instance_type" "other" {
+  name = %q
+}
+
+data "eci_block_storage_image" "test" {
+  name = %q
+}
+`,
+		e.Endpoint,
+		e.Token,
+		e.ZoneId,
+		providerBody,
+		e.InstanceTypeName,
+		e.OtherInstanceTypeName,
+		e.BlockStorageImageName,
+	) + body
+}
+
+// checkDestroy checks that the resources that were in the state are either
+// gone or in their deleted status.
+func (e *testAccEnvironment) checkDestroy(state *terraform.State) error {
+	ctx := context.Background()
+
+	for name, rs := range state.RootModule().Resources {
+		if strings.HasPrefix(name, "data.") {
+			continue
+		}
+
+		status, err := e.status(ctx, rs.Type, rs.Primary.ID)
+		if errors.Is(err, api.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		deletedStatus := "deleted"
+		if rs.Type == "eci_virtual_machine_allocation" {
+			deletedStatus = "terminated"
+		}
+		if status != deletedStatus {
+			return fmt.Errorf("%s still exists: %s", name, status)
+		}
+	}
+
+	return nil
+}
+
+// deletedOutsideTerraformSteps returns the steps of a test of the resource at
+// address in config: it is created, then deleted by deleteResource, after
+// which terraform must plan to create it again rather than fail to read it.
+func deletedOutsideTerraformSteps(
+	t *testing.T,
+	config string,
+	address string,
+	deleteResource func(ctx context.Context, id string) error,
+) []resource.TestStep {
+	var id string
+
+	return []resource.TestStep{
+		{
+			Config: config,
+			Check: func(state *terraform.State) error {
+				id = state.RootModule().Resources[address].Primary.ID
+				return nil
+			},
+		},
+		{
+			PreConfig: func() {
+				if err := deleteResource(context.Background(), id); err != nil {
+					t.Fatalf("failed to delete %s (%s): %v", address, id, err)
+				}
+			},
+			Config: config,
+			ConfigPlanChecks: resource.ConfigPlanChecks{
+				PreApply: []plancheck.PlanCheck{
+					plancheck.ExpectResourceAction(address, plancheck.ResourceActionCreate),
+				},
+			},
+			Check: resource.TestCheckResourceAttrWith(address, "id", func(value string) error {
+				if value == id {
+					return fmt.Errorf("%s was not created again: %s", address, id)
+				}
+				return nil
+			}),
+		},
+	}
+}
+
+// status returns the status of a resource.
+func (e *testAccEnvironment) status(
+	ctx context.Context, resourceType string, id string,
+) (string, error) {
+	switch resourceType {
+	case "eci_block_storage":
+		storage, err := e.Client.GetBlockStorage(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return storage.Status, nil
+	case "eci_block_storage_snapshot":
+		snapshot, err := e.Client.GetBlockStorageSnapshot(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return snapshot.Status, nil
+	case "eci_virtual_machine":
+		machine, err := e.Client.GetVirtualMachine(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return machine.Status, nil
+	case "eci_virtual_machine_allocation":
+		allocation, err := e.Client.GetVirtualMachineAllocation(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return allocation.Status, nil
+	case "eci_virtual_network":
+		network, err := e.Client.GetVirtualNetwork(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return network.Status, nil
+	case "eci_subnet":
+		subnet, err := e.Client.GetSubnet(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return subnet.Status, nil
+	case "eci_network_interface":
+		networkInterface, err := e.Client.GetNetworkInterface(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return networkInterface.Status, nil
+	case "eci_public_ip":
+		publicIp, err := e.Client.GetPublicIp(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return publicIp.Status, nil
+	}
+
+	return "", fmt.Errorf("unknown resource type: %s", resourceType)
+}
+
+// planResourceChange plans the change of a resource of typeName from its
+// prior state to config, the way Terraform does, on a provider that is not
+// configured. Attributes that are left out are null.
+func planResourceChange(
+	t *testing.T, typeName string, prior map[string]tftypes.Value, config map[string]tftypes.Value,
+) *tfprotov6.PlanResourceChangeResponse {
+	t.Helper()
+
+	ctx := context.Background()
+	server := providerserver.NewProtocol6(New("test")())()
+
+	schemaResp, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectType := schemaResp.ResourceSchemas[typeName].ValueType().(tftypes.Object)
+
+	// Terraform proposes the configured values, keeping the prior ones of
+	// attributes that are not configured.
+	proposed := map[string]tftypes.Value{}
+	for name, value := range prior {
+		proposed[name] = value
+	}
+	for name, value := range config {
+		proposed[name] = value
+	}
+
+	dynamicValue := func(values map[string]tftypes.Value) *tfprotov6.DynamicValue {
+		object := map[string]tftypes.Value{}
+		for name, attributeType := range objectType.AttributeTypes {
+			object[name] = tftypes.NewValue(attributeType, nil)
+			if value, ok := values[name]; ok {
+				object[name] = value
+			}
+		}
+
+		value, err := tfprotov6.NewDynamicValue(objectType, tftypes.NewValue(objectType, object))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &value
+	}
+
+	resp, err := server.PlanResourceChange(ctx, &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         typeName,
+		PriorState:       dynamicValue(prior),
+		ProposedNewState: dynamicValue(proposed),
+		Config:           dynamicValue(config),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, diagnostic := range resp.Diagnostics {
+		if diagnostic.Severity == tfprotov6.DiagnosticSeverityError {
+			t.Fatalf("failed to plan %s: %s: %s", typeName, diagnostic.Summary, diagnostic.Detail)
+		}
+	}
+
+	return resp
+}
+
+// stringMapValue returns a map of strings as a Terraform value.
+func stringMapValue(values map[string]string) tftypes.Value {
+	elements := map[string]tftypes.Value{}
+	for key, value := range values {
+		elements[key] = tftypes.NewValue(tftypes.String, value)
+	}
+
+	return tftypes.NewValue(tftypes.Map{ElementType: tftypes.String}, elements)
+}
+
+// unknownConfig returns a configuration of the provider where only
+// api_access_token is set, to a value that is not known yet.
+func unknownConfig(t *testing.T, p provider.Provider) tfsdk.Config {
+	t.Helper()
+
+	ctx := context.Background()
+	schemaResp := &provider.SchemaResponse{}
+	p.Schema(ctx, provider.SchemaRequest{}, schemaResp)
+
+	objectType := schemaResp.Schema.Type().TerraformType(ctx).(tftypes.Object)
+	values := map[string]tftypes.Value{}
+	for name, attributeType := range objectType.AttributeTypes {
+		values[name] = tftypes.NewValue(attributeType, nil)
+	}
+	values["api_access_token"] = tftypes.NewValue(tftypes.String, tftypes.UnknownValue)
+
+	return tfsdk.Config{Schema: schemaResp.Schema, Raw: tftypes.NewValue(objectType, values)}
+}
+
+func TestProviderConfigureDefersUnknownValues(t *testing.T) {
+	for _, deferralAllowed := range []bool{true, false} {
+		t.Run(fmt.Sprintf("deferral allowed: %t", deferralAllowed), func(t *testing.T) {
+			p := New("test")()
+			resp := &provider.ConfigureResponse{}
+			p.Configure(context.Background(), provider.ConfigureRequest{
+				Config: unknownConfig(t, p),
+				ClientCapabilities: provider.ConfigureProviderClientCapabilities{
+					DeferralAllowed: deferralAllowed,
+				},
+			}, resp)
+
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+			}
+			if deferred := resp.Deferred != nil; deferred != deferralAllowed {
+				t.Errorf("deferred: got %t, want %t", deferred, deferralAllowed)
+			}
+			if resp.ResourceData != nil || resp.DataSourceData != nil {
+				t.Error("provider was configured with unknown values")
+			}
+		})
+	}
+}
+
+func TestDataSourcesReadWithUnknownProviderConfig(t *testing.T) {
+	ctx := context.Background()
+	p := New("test")()
+	config := unknownConfig(t, p)
+	providerConfig, err := tfprotov6.NewDynamicValue(config.Raw.Type(), config.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, typeName := range []string{
+		"eci_block_storage_image", "eci_instance_type", "eci_region", "eci_zone",
+	} {
+		for _, deferralAllowed := range []bool{true, false} {
+			name := fmt.Sprintf("%s, deferral allowed: %t", typeName, deferralAllowed)
+			t.Run(name, func(t *testing.T) {
+				server := providerserver.NewProtocol6(p)()
+
+				configureResp, err := server.ConfigureProvider(
+					ctx,
+					&tfprotov6.ConfigureProviderRequest{
+						Config: &providerConfig,
+						ClientCapabilities: &tfprotov6.ConfigureProviderClientCapabilities{
+							DeferralAllowed: deferralAllowed,
+						},
+					},
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(configureResp.Diagnostics) != 0 {
+					t.Fatalf("unexpected diagnostics: %v", configureResp.Diagnostics)
+				}
+
+				schemaResp, err := server.GetProviderSchema(
+					ctx, &tfprotov6.GetProviderSchemaRequest{},
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				objectType := schemaResp.DataSourceSchemas[typeName].ValueType().(tftypes.Object)
+				values := map[string]tftypes.Value{}
+				for name, attributeType := range objectType.AttributeTypes {
+					values[name] = tftypes.NewValue(attributeType, nil)
+				}
+				values["name"] = tftypes.NewValue(tftypes.String, "test")
+				dataSourceConfig, err := tfprotov6.NewDynamicValue(
+					objectType, tftypes.NewValue(objectType, values),
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				resp, err := server.ReadDataSource(ctx, &tfprotov6.ReadDataSourceRequest{
+					TypeName: typeName,
+					Config:   &dataSourceConfig,
+					ClientCapabilities: &tfprotov6.ReadDataSourceClientCapabilities{
+						DeferralAllowed: deferralAllowed,
+					},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, diagnostic := range resp.Diagnostics {
+					if diagnostic.Severity == tfprotov6.DiagnosticSeverityError {
+						t.Errorf("%s: %s", diagnostic.Summary, diagnostic.Detail)
+					}
+				}
+				if deferred := resp.Deferred != nil; deferred != deferralAllowed {
+					t.Errorf("deferred: got %t, want %t", deferred, deferralAllowed)
+				}
+				if resp.State == nil {
+					t.Errorf("no state")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+)
+
+func TestAccResourceBlockStorageSnapshot(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccBlockStorageSnapshotConfig("tf-acc-snapshot")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"eci_block_storage_snapshot.test", "name", "tf-acc-snapshot",
+					),
+					resource.TestCheckResourceAttrPair(
+						"eci_block_storage_snapshot.test", "block_storage_id",
+						"eci_block_storage.test", "id",
+					),
+					resource.TestCheckResourceAttrPair(
+						"eci_block_storage_snapshot.test", "size_gib",
+						"eci_block_storage.test", "size_gib",
+					),
+				),
+			},
+			{
+				Config: env.config(testAccBlockStorageSnapshotConfig("tf-acc-renamed")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_block_storage_snapshot.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_block_storage_snapshot.test", "name", "tf-acc-renamed",
+				),
+			},
+			{
+				// A snapshot of a replaced block storage is a new snapshot.
+				Config: env.config(
+					testAccBlockStorageConfig("tf-acc-disk", 30) + `
+resource "eci_block_storage_snapshot" "test" {
+  name             = "tf-acc-renamed"
+  block_storage_id = eci_block_storage.test.id
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`,
+				),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_block_storage_snapshot.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_block_storage_snapshot.test", "size_gib", "30",
+				),
+			},
+			{
+				ResourceName:      "eci_block_storage_snapshot.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceBlockStorageSnapshotDeletedOutsideTerraform(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: deletedOutsideTerraformSteps(
+			t,
+			env.config(testAccBlockStorageSnapshotConfig("tf-acc-snapshot")),
+			"eci_block_storage_snapshot.test",
+			func(ctx context.Context, id string) error {
+				_, err := env.Client.DeleteBlockStorageSnapshot(ctx, id)
+				return err
+			},
+		),
+	})
+}
+
+func TestResourceBlockStorageSnapshotPlansRenameInPlace(t *testing.T) {
+	tags := stringMapValue(map[string]string{"created-by": "terraform"})
+	resp := planResourceChange(t, "eci_block_storage_snapshot",
+		map[string]tftypes.Value{
+			"id":               tftypes.NewValue(tftypes.String, "snapshot"),
+			"name":             tftypes.NewValue(tftypes.String, "tf-acc-snapshot"),
+			"tags":             tags,
+			"tags_all":         tags,
+			"zone_id":          tftypes.NewValue(tftypes.String, "zone"),
+			"block_storage_id": tftypes.NewValue(tftypes.String, "block-storage"),
+			"image_id":         tftypes.NewValue(tftypes.String, "image"),
+			"size_gib":         tftypes.NewValue(tftypes.Number, 20),
+			"dr":               tftypes.NewValue(tftypes.Bool, false),
+			"status":           tftypes.NewValue(tftypes.String, "prepared"),
+		},
+		map[string]tftypes.Value{
+			"name":             tftypes.NewValue(tftypes.String, "tf-acc-renamed"),
+			"tags":             tags,
+			"block_storage_id": tftypes.NewValue(tftypes.String, "block-storage"),
+		},
+	)
+
+	if len(resp.RequiresReplace) != 0 {
+		t.Errorf("renaming requires replacement: %v", resp.RequiresReplace)
+	}
+}
+
+func testAccBlockStorageSnapshotConfig(name string) string {
+	return testAccBlockStorageConfig("tf-acc-disk", 20) + fmt.Sprintf(`
+resource "eci_block_storage_snapshot" "test" {
+  name             = %q
+  block_storage_id = eci_block_storage.test.id
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`, name)
+}
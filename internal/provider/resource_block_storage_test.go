@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+)
+
+func TestAccResourceBlockStorage(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(
+					testAccBlockStorageConfig("tf-acc-disk", 20),
+				),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("eci_block_storage.test", "name", "tf-acc-disk"),
+					resource.TestCheckResourceAttr("eci_block_storage.test", "size_gib", "20"),
+					resource.TestCheckResourceAttrPair(
+						"eci_block_storage.test", "image_id",
+						"data.eci_block_storage_image.test", "id",
+					),
+					resource.TestCheckResourceAttrPair(
+						"eci_block_storage.test", "attached_machine_id",
+						"eci_virtual_machine.test", "id",
+					),
+				),
+			},
+			{
+				Config: env.config(testAccBlockStorageConfig("tf-acc-renamed", 20)),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_block_storage.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_block_storage.test", "name", "tf-acc-renamed",
+				),
+			},
+			{
+				Config: env.config(testAccBlockStorageConfig("tf-acc-renamed", 30)),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_block_storage.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr("eci_block_storage.test", "size_gib", "30"),
+			},
+			{
+				ResourceName:      "eci_block_storage.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceBlockStorageDeletedOutsideTerraform(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: deletedOutsideTerraformSteps(
+			t,
+			env.config(testAccBlockStorageConfig("tf-acc-disk", 20)),
+			"eci_block_storage.test",
+			func(ctx context.Context, id string) error {
+				_, err := env.Client.DeleteBlockStorage(ctx, id)
+				return err
+			},
+		),
+	})
+}
+
+func TestAccResourceBlockStorageDefaultTags(t *testing.T) {
+	env := newTestAccEnvironment(t)
+	// The block storage is created without image_id, which the portal then
+	// computes.
+	config := func(team string) string {
+		return env.configWithProvider(fmt.Sprintf(`
+  default_tags {
+    tags = {
+      "team" = %q
+    }
+  }
+`, team), testAccVirtualMachineConfig("tf-acc-vm", "test", "elice")+`
+resource "eci_block_storage" "test" {
+  name                = "tf-acc-disk"
+  attached_machine_id = eci_virtual_machine.test.id
+  size_gib            = 20
+  dr                  = false
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: config("infra"),
+				Check: resource.TestCheckResourceAttr(
+					"eci_block_storage.test", "tags_all.team", "infra",
+				),
+			},
+			{
+				Config: config("platform"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_block_storage.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_block_storage.test", "tags_all.team", "platform",
+				),
+			},
+		},
+	})
+}
+
+func testAccBlockStorageConfig(name string, sizeGib int) string {
+	return testAccVirtualMachineConfig("tf-acc-vm", "test", "elice") + fmt.Sprintf(`
+resource "eci_block_storage" "test" {
+  name                = %q
+  attached_machine_id = eci_virtual_machine.test.id
+  image_id            = data.eci_block_storage_image.test.id
+  size_gib            = %d
+  dr                  = false
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`, name, sizeGib)
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+)
+
+func TestAccResourceNetworkInterface(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccNetworkInterfaceConfig("tf-acc-nic", "")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"eci_network_interface.test", "name", "tf-acc-nic",
+					),
+					resource.TestCheckResourceAttrPair(
+						"eci_network_interface.test", "attached_subnet_id", "eci_subnet.test", "id",
+					),
+					resource.TestCheckResourceAttrPair(
+						"eci_network_interface.test", "attached_machine_id",
+						"eci_virtual_machine.test", "id",
+					),
+					resource.TestCheckResourceAttrSet("eci_network_interface.test", "ip"),
+					resource.TestCheckResourceAttrSet("eci_network_interface.test", "mac"),
+				),
+			},
+			{
+				Config: env.config(testAccNetworkInterfaceConfig("tf-acc-renamed", "")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_network_interface.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_network_interface.test", "name", "tf-acc-renamed",
+				),
+			},
+			{
+				Config: env.config(testAccNetworkInterfaceConfig("tf-acc-renamed", "10.0.0.10")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_network_interface.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_network_interface.test", "ip", "10.0.0.10",
+				),
+			},
+			{
+				ResourceName:      "eci_network_interface.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceNetworkInterfaceDeletedOutsideTerraform(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: deletedOutsideTerraformSteps(
+			t,
+			env.config(testAccNetworkInterfaceConfig("tf-acc-nic", "")),
+			"eci_network_interface.test",
+			func(ctx context.Context, id string) error {
+				_, err := env.Client.DeleteNetworkInterface(ctx, id)
+				return err
+			},
+		),
+	})
+}
+
+func TestResourceNetworkInterfacePlansRenameInPlace(t *testing.T) {
+	tags := stringMapValue(map[string]string{"created-by": "terraform"})
+	resp := planResourceChange(t, "eci_network_interface",
+		map[string]tftypes.Value{
+			"id":                  tftypes.NewValue(tftypes.String, "network-interface"),
+			"name":                tftypes.NewValue(tftypes.String, "tf-acc-nic"),
+			"tags":                tags,
+			"tags_all":            tags,
+			"zone_id":             tftypes.NewValue(tftypes.String, "zone"),
+			"attached_subnet_id":  tftypes.NewValue(tftypes.String, "subnet"),
+			"attached_machine_id": tftypes.NewValue(tftypes.String, "machine"),
+			"dr":                  tftypes.NewValue(tftypes.Bool, false),
+			"ip":                  tftypes.NewValue(tftypes.String, "10.0.0.2"),
+			"mac":                 tftypes.NewValue(tftypes.String, "02:00:00:00:00:01"),
+			"status":              tftypes.NewValue(tftypes.String, "active"),
+		},
+		map[string]tftypes.Value{
+			"name":                tftypes.NewValue(tftypes.String, "tf-acc-renamed"),
+			"tags":                tags,
+			"attached_subnet_id":  tftypes.NewValue(tftypes.String, "subnet"),
+			"attached_machine_id": tftypes.NewValue(tftypes.String, "machine"),
+			"dr":                  tftypes.NewValue(tftypes.Bool, false),
+		},
+	)
+
+	if len(resp.RequiresReplace) != 0 {
+		t.Errorf("renaming requires replacement: %v", resp.RequiresReplace)
+	}
+}
+
+// testAccNetworkInterfaceConfig returns the configuration of a network
+// interface attached to a virtual machine. An empty ip lets the portal pick
+// one.
+func testAccNetworkInterfaceConfig(name string, ip string) string {
+	ipAttribute := ""
+	if ip != "" {
+		ipAttribute = fmt.Sprintf("ip                  = %q", ip)
+	}
+
+	return testAccSubnetConfig("tf-acc-subnet", "10.0.0.1/24") +
+		testAccVirtualMachineConfig("tf-acc-vm", "test", "elice") +
+		fmt.Sprintf(`
+resource "eci_network_interface" "test" {
+  name                = %q
+  attached_subnet_id  = eci_subnet.test.id
+  attached_machine_id = eci_virtual_machine.test.id
+  dr                  = false
+  %s
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`, name, ipAttribute)
+}
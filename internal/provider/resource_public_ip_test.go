@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+)
+
+func TestAccResourcePublicIp(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccPublicIpConfig("terraform", false)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair(
+						"eci_public_ip.test", "attached_network_interface_id",
+						"eci_network_interface.test", "id",
+					),
+					resource.TestCheckResourceAttrSet("eci_public_ip.test", "ip"),
+				),
+			},
+			{
+				Config: env.config(testAccPublicIpConfig("acceptance-test", false)),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_public_ip.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_public_ip.test", "tags.created-by", "acceptance-test",
+				),
+			},
+			{
+				Config: env.config(testAccPublicIpConfig("acceptance-test", true)),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_public_ip.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr("eci_public_ip.test", "dr", "true"),
+			},
+			{
+				ResourceName:      "eci_public_ip.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourcePublicIpDeletedOutsideTerraform(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: deletedOutsideTerraformSteps(
+			t,
+			env.config(testAccPublicIpConfig("terraform", false)),
+			"eci_public_ip.test",
+			func(ctx context.Context, id string) error {
+				_, err := env.Client.DeletePublicIp(ctx, id)
+				return err
+			},
+		),
+	})
+}
+
+func testAccPublicIpConfig(createdBy string, dr bool) string {
+	return testAccNetworkInterfaceConfig("tf-acc-nic", "") + fmt.Sprintf(`
+resource "eci_public_ip" "test" {
+  attached_network_interface_id = eci_network_interface.test.id
+  dr                            = %t
+  tags = {
+    "created-by" = %q
+  }
+}
+`, dr, createdBy)
+}
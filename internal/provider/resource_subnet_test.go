@@ -0,0 +1,94 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+)
+
+func TestAccResourceSubnet(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccSubnetConfig("tf-acc-subnet", "10.0.0.1/24")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("eci_subnet.test", "name", "tf-acc-subnet"),
+					resource.TestCheckResourceAttr("eci_subnet.test", "purpose", "virtual_machine"),
+					resource.TestCheckResourceAttr("eci_subnet.test", "network_gw", "10.0.0.1/24"),
+					resource.TestCheckResourceAttrPair(
+						"eci_subnet.test", "attached_network_id", "eci_virtual_network.test", "id",
+					),
+					resource.TestCheckResourceAttr("eci_subnet.test", "status", "active"),
+				),
+			},
+			{
+				Config: env.config(testAccSubnetConfig("tf-acc-renamed", "10.0.0.1/24")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_subnet.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr("eci_subnet.test", "name", "tf-acc-renamed"),
+			},
+			{
+				Config: env.config(testAccSubnetConfig("tf-acc-renamed", "10.0.1.1/24")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_subnet.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_subnet.test", "network_gw", "10.0.1.1/24",
+				),
+			},
+			{
+				ResourceName:      "eci_subnet.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceSubnetDeletedOutsideTerraform(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: deletedOutsideTerraformSteps(
+			t,
+			env.config(testAccSubnetConfig("tf-acc-subnet", "10.0.0.1/24")),
+			"eci_subnet.test",
+			func(ctx context.Context, id string) error {
+				_, err := env.Client.DeleteSubnet(ctx, id)
+				return err
+			},
+		),
+	})
+}
+
+func testAccSubnetConfig(name string, networkGw string) string {
+	return testAccVirtualNetworkConfig("tf-acc-network", "10.0.0.0/16") + fmt.Sprintf(`
+resource "eci_subnet" "test" {
+  name                = %q
+  attached_network_id = eci_virtual_network.test.id
+  purpose             = "virtual_machine"
+  network_gw          = %q
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`, name, networkGw)
+}
@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+)
+
+func TestAccResourceVirtualMachineAllocation(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccVirtualMachineAllocationConfig("terraform")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrPair(
+						"eci_virtual_machine_allocation.test", "machine_id",
+						"eci_virtual_machine.test", "id",
+					),
+					resource.TestCheckResourceAttrSet(
+						"eci_virtual_machine_allocation.test", "status",
+					),
+				),
+			},
+			{
+				// Tags are changed in place, without restarting the virtual machine.
+				Config: env.config(testAccVirtualMachineAllocationConfig("acceptance-test")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_machine_allocation.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"eci_virtual_machine_allocation.test", "tags.created-by", "acceptance-test",
+					),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_machine_allocation.test", "tags_all.created-by",
+						"acceptance-test",
+					),
+				),
+			},
+			{
+				ResourceName:      "eci_virtual_machine_allocation.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceVirtualMachineAllocationDeletedOutsideTerraform(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: deletedOutsideTerraformSteps(
+			t,
+			env.config(testAccVirtualMachineAllocationConfig("terraform")),
+			"eci_virtual_machine_allocation.test",
+			func(ctx context.Context, id string) error {
+				_, err := env.Client.DeleteVirtualMachineAllocation(ctx, id)
+				return err
+			},
+		),
+	})
+}
+
+func testAccVirtualMachineAllocationConfig(createdBy string) string {
+	return testAccVirtualMachineConfig("tf-acc-vm", "test", "elice") + fmt.Sprintf(`
+resource "eci_virtual_machine_allocation" "test" {
+  machine_id = eci_virtual_machine.test.id
+  tags = {
+    "created-by" = %q
+  }
+}
+`, createdBy)
+}
+
+func TestAccResourceVirtualMachineAllocationWaitFor(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(
+					testAccVirtualMachineConfig("tf-acc-vm", "test", "elice") + `
+resource "eci_virtual_machine_allocation" "test" {
+  machine_id = eci_virtual_machine.test.id
+  wait_for   = "heartbeat"
+}
+`),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"eci_virtual_machine_allocation.test", "status", "started",
+					),
+					resource.TestCheckResourceAttrSet(
+						"eci_virtual_machine_allocation.test", "last_heartbeat",
+					),
+				),
+			},
+			{
+				ResourceName:      "eci_virtual_machine_allocation.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				// wait_for only matters when creating, so it is not imported.
+				ImportStateVerifyIgnore: []string{"wait_for"},
+			},
+		},
+	})
+}
+
+func TestAccResourceVirtualMachineAllocationWaitForTimeout(t *testing.T) {
+	env := newTestAccEnvironment(t)
+	client := env.fakeClient(t)
+	config := env.config(
+		testAccVirtualMachineConfig("tf-acc-vm", "test", "elice") + `
+resource "eci_virtual_machine_allocation" "test" {
+  machine_id = eci_virtual_machine.test.id
+  wait_for   = "started"
+
+  timeouts {
+    create = "2s"
+  }
+}
+`)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccVirtualMachineConfig("tf-acc-vm", "test", "elice")),
+			},
+			{
+				PreConfig: func() {
+					client.SetTransitionDelay(time.Minute)
+				},
+				Config:      config,
+				ExpectError: regexp.MustCompile("operation timed out"),
+			},
+			{
+				// The allocation that was not ready in time is kept in the state,
+				// tainted, so it is replaced rather than left running.
+				PreConfig: func() {
+					client.SetTransitionDelay(0)
+				},
+				Config: config,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_machine_allocation.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_machine_allocation.test", "status", "started",
+				),
+			},
+		},
+	})
+}
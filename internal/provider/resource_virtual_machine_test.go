@@ -0,0 +1,245 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func TestAccResourceVirtualMachine(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccVirtualMachineConfig("tf-acc-vm", "test", "elice")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("eci_virtual_machine.test", "name", "tf-acc-vm"),
+					resource.TestCheckResourceAttrPair(
+						"eci_virtual_machine.test", "instance_type_id",
+						"data.eci_instance_type.test", "id",
+					),
+					resource.TestCheckResourceAttr("eci_virtual_machine.test", "username", "elice"),
+					resource.TestCheckResourceAttr("eci_virtual_machine.test", "status", "idle"),
+				),
+			},
+			{
+				Config: env.config(testAccVirtualMachineConfig("tf-acc-renamed", "other", "elice")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_machine.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"eci_virtual_machine.test", "name", "tf-acc-renamed",
+					),
+					resource.TestCheckResourceAttrPair(
+						"eci_virtual_machine.test", "instance_type_id",
+						"data.eci_instance_type.other", "id",
+					),
+				),
+			},
+			{
+				Config: env.config(testAccVirtualMachineConfig("tf-acc-renamed", "other", "admin")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_machine.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_machine.test", "username", "admin",
+				),
+			},
+			{
+				ResourceName:      "eci_virtual_machine.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				// The API never returns the password.
+				ImportStateVerifyIgnore: []string{"password"},
+			},
+			{
+				ResourceName:  "eci_virtual_machine.test",
+				ImportState:   true,
+				ImportStateId: "my-virtual-machine",
+				ExpectError:   regexp.MustCompile("invalid import id"),
+			},
+		},
+	})
+}
+
+func TestAccResourceVirtualMachineDeletedOutsideTerraform(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: deletedOutsideTerraformSteps(
+			t,
+			env.config(testAccVirtualMachineConfig("tf-acc-vm", "test", "elice")),
+			"eci_virtual_machine.test",
+			func(ctx context.Context, id string) error {
+				_, err := env.Client.DeleteVirtualMachine(ctx, id)
+				return err
+			},
+		),
+	})
+}
+
+// testAccVirtualMachineConfig returns the configuration of a virtual machine
+// of the instance type with the given label (either "test" or "other").
+func testAccVirtualMachineConfig(name string, instanceType string, username string) string {
+	return fmt.Sprintf(`
+resource "eci_virtual_machine" "test" {
+  name             = %q
+  instance_type_id = data.eci_instance_type.%s.id
+  always_on        = false
+  dr               = false
+  username         = %q
+  password         = "secretpassword1!"
+  on_init_script   = ""
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`, name, instanceType, username)
+}
+
+func TestAccResourceVirtualMachinePasswordWo(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_11_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccVirtualMachinePasswordWoConfig("secretpassword1!", 1)),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckNoResourceAttr("eci_virtual_machine.test", "password"),
+					resource.TestCheckNoResourceAttr("eci_virtual_machine.test", "password_wo"),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_machine.test", "password_wo_version", "1",
+					),
+				),
+			},
+			{
+				// Without a new version, a new password is not applied.
+				Config: env.config(testAccVirtualMachinePasswordWoConfig("secretpassword2!", 1)),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{plancheck.ExpectEmptyPlan()},
+				},
+			},
+			{
+				Config: env.config(testAccVirtualMachinePasswordWoConfig("secretpassword2!", 2)),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_machine.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckNoResourceAttr("eci_virtual_machine.test", "password_wo"),
+			},
+		},
+	})
+}
+
+func testAccVirtualMachinePasswordWoConfig(password string, version int) string {
+	return fmt.Sprintf(`
+resource "eci_virtual_machine" "test" {
+  name                = "tf-acc-vm"
+  instance_type_id    = data.eci_instance_type.test.id
+  always_on           = false
+  dr                  = false
+  username            = "elice"
+  password_wo         = %q
+  password_wo_version = %d
+  on_init_script      = ""
+}
+`, password, version)
+}
+
+func TestAccResourceVirtualMachinePowerState(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      env.config(testAccVirtualMachinePowerStateConfig(true, "running")),
+				ExpectError: regexp.MustCompile("power_state cannot be set"),
+			},
+			{
+				Config: env.config(testAccVirtualMachinePowerStateConfig(false, "running")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"eci_virtual_machine.test", "power_state", "running",
+					),
+					resource.TestCheckResourceAttr("eci_virtual_machine.test", "status", "running"),
+				),
+			},
+			{
+				Config: env.config(testAccVirtualMachinePowerStateConfig(false, "stopped")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_machine.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"eci_virtual_machine.test", "power_state", "stopped",
+					),
+					resource.TestCheckResourceAttr("eci_virtual_machine.test", "status", "idle"),
+				),
+			},
+			{
+				Config: env.config(testAccVirtualMachinePowerStateConfig(false, "running")),
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_machine.test", "power_state", "running",
+				),
+			},
+			{
+				// Without power_state, the virtual machine is left running.
+				Config: env.config(testAccVirtualMachineConfig("tf-acc-vm", "test", "elice")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{plancheck.ExpectEmptyPlan()},
+				},
+			},
+		},
+	})
+}
+
+func testAccVirtualMachinePowerStateConfig(alwaysOn bool, powerState string) string {
+	return fmt.Sprintf(`
+resource "eci_virtual_machine" "test" {
+  name             = "tf-acc-vm"
+  instance_type_id = data.eci_instance_type.test.id
+  always_on        = %t
+  dr               = false
+  username         = "elice"
+  password         = "secretpassword1!"
+  on_init_script   = ""
+  power_state      = %q
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`, alwaysOn, powerState)
+}
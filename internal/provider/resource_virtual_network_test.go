@@ -0,0 +1,368 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func TestAccResourceVirtualNetwork(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: env.config(testAccVirtualNetworkConfig("tf-acc-network", "10.0.0.0/16")),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"eci_virtual_network.test", "name", "tf-acc-network",
+					),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_network.test", "network_cidr", "10.0.0.0/16",
+					),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_network.test", "tags.created-by", "terraform",
+					),
+					resource.TestCheckResourceAttr("eci_virtual_network.test", "status", "active"),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_network.test", "zone_id", env.ZoneId,
+					),
+					resource.TestCheckResourceAttrSet("eci_virtual_network.test", "id"),
+				),
+			},
+			{
+				Config: env.config(testAccVirtualNetworkConfig("tf-acc-renamed", "10.0.0.0/16")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_network.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_network.test", "name", "tf-acc-renamed",
+				),
+			},
+			{
+				Config: env.config(testAccVirtualNetworkConfig("tf-acc-renamed", "10.1.0.0/16")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_network.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_network.test", "network_cidr", "10.1.0.0/16",
+				),
+			},
+			{
+				ResourceName:      "eci_virtual_network.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceVirtualNetworkDeletedOutsideTerraform(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: deletedOutsideTerraformSteps(
+			t,
+			env.config(testAccVirtualNetworkConfig("tf-acc-network", "10.0.0.0/16")),
+			"eci_virtual_network.test",
+			func(ctx context.Context, id string) error {
+				_, err := env.Client.DeleteVirtualNetwork(ctx, id)
+				return err
+			},
+		),
+	})
+}
+
+func TestAccResourceVirtualNetworkTimeouts(t *testing.T) {
+	env := newTestAccEnvironment(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config:      env.config(testAccVirtualNetworkTimeoutsConfig("forever")),
+				ExpectError: regexp.MustCompile("Invalid Attribute Value"),
+			},
+			{
+				Config: env.config(testAccVirtualNetworkTimeoutsConfig("10m")),
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_network.test", "timeouts.create", "10m",
+				),
+			},
+			{
+				Config: env.config(testAccVirtualNetworkTimeoutsConfig("1h")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_network.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_network.test", "timeouts.create", "1h",
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceVirtualNetworkDefaultTags(t *testing.T) {
+	env := newTestAccEnvironment(t)
+	config := func(team string) string {
+		return env.configWithProvider(fmt.Sprintf(`
+  default_tags {
+    tags = {
+      "team" = %q
+      "env"  = "test"
+    }
+  }
+`, team), `
+resource "eci_virtual_network" "test" {
+  name           = "tf-acc-network"
+  network_cidr   = "10.0.0.0/16"
+  firewall_rules = []
+  tags = {
+    "created-by" = "terraform"
+    "env"        = "production"
+  }
+}
+`)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: config("infra"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("eci_virtual_network.test", "tags.%", "2"),
+					resource.TestCheckResourceAttr("eci_virtual_network.test", "tags_all.%", "3"),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_network.test", "tags_all.team", "infra",
+					),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_network.test", "tags_all.env", "production",
+					),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_network.test", "tags_all.created-by", "terraform",
+					),
+				),
+			},
+			{
+				Config: config("platform"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_network.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("eci_virtual_network.test", "tags.%", "2"),
+					resource.TestCheckResourceAttr(
+						"eci_virtual_network.test", "tags_all.team", "platform",
+					),
+					func(state *terraform.State) error {
+						id := state.RootModule().Resources["eci_virtual_network.test"].Primary.ID
+						network, err := env.Client.GetVirtualNetwork(context.Background(), id)
+						if err != nil {
+							return err
+						}
+						if team := network.Tags["team"]; team != "platform" {
+							return fmt.Errorf("team tag: got %q, want platform", team)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				ResourceName:      "eci_virtual_network.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceVirtualNetworkIgnoreTags(t *testing.T) {
+	env := newTestAccEnvironment(t)
+	config := func(createdBy string) string {
+		return env.configWithProvider(`
+  ignore_tags {
+    keys         = ["backup"]
+    key_prefixes = ["billing:"]
+  }
+`, fmt.Sprintf(`
+resource "eci_virtual_network" "test" {
+  name           = "tf-acc-network"
+  network_cidr   = "10.0.0.0/16"
+  firewall_rules = []
+  tags = {
+    "created-by" = %q
+  }
+}
+`, createdBy))
+	}
+
+	var id string
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: config("terraform"),
+				Check: func(state *terraform.State) error {
+					id = state.RootModule().Resources["eci_virtual_network.test"].Primary.ID
+					return nil
+				},
+			},
+			{
+				// Tags written by other tooling do not show up as a diff.
+				PreConfig: func() {
+					tags := map[string]string{
+						"created-by":   "terraform",
+						"backup":       "daily",
+						"billing:code": "1234",
+					}
+					_, err := env.Client.PatchVirtualNetwork(
+						context.Background(), id, nil, nil, &tags,
+					)
+					if err != nil {
+						t.Fatalf("failed to patch virtual network: %v", err)
+					}
+				},
+				Config: config("terraform"),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{plancheck.ExpectEmptyPlan()},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_network.test", "tags_all.%", "1",
+				),
+			},
+			{
+				// Nor are they removed when the tags are changed.
+				Config: config("someone"),
+				Check: func(state *terraform.State) error {
+					network, err := env.Client.GetVirtualNetwork(context.Background(), id)
+					if err != nil {
+						return err
+					}
+					want := map[string]string{
+						"created-by":   "someone",
+						"backup":       "daily",
+						"billing:code": "1234",
+					}
+					if !maps.Equal(network.Tags, want) {
+						return fmt.Errorf("tags: got %v, want %v", network.Tags, want)
+					}
+					return nil
+				},
+			},
+		},
+	})
+}
+
+func TestAccResourceVirtualNetworkZone(t *testing.T) {
+	env := newTestAccEnvironment(t)
+	if env.OtherZoneId == "" {
+		t.Skip("ECI_ACC_OTHER_ZONE_ID is not set")
+	}
+
+	config := func(zoneId string) string {
+		return env.config(fmt.Sprintf(`
+resource "eci_virtual_network" "test" {
+  name           = "tf-acc-network"
+  network_cidr   = "10.0.0.0/16"
+  firewall_rules = []
+  tags           = {}
+  zone_id        = %q
+}
+`, zoneId))
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy:             env.checkDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: config(env.OtherZoneId),
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_network.test", "zone_id", env.OtherZoneId,
+				),
+			},
+			{
+				Config: config(env.ZoneId),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_network.test", plancheck.ResourceActionReplace,
+						),
+					},
+				},
+				Check: resource.TestCheckResourceAttr(
+					"eci_virtual_network.test", "zone_id", env.ZoneId,
+				),
+			},
+			{
+				// Leaving the zone to the provider keeps the resource where it is.
+				Config: env.config(testAccVirtualNetworkConfig("tf-acc-network", "10.0.0.0/16")),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction(
+							"eci_virtual_network.test", plancheck.ResourceActionUpdate,
+						),
+					},
+				},
+			},
+		},
+	})
+}
+
+func testAccVirtualNetworkConfig(name string, networkCidr string) string {
+	return fmt.Sprintf(`
+resource "eci_virtual_network" "test" {
+  name           = %q
+  network_cidr   = %q
+  firewall_rules = []
+  tags = {
+    "created-by" = "terraform"
+  }
+}
+`, name, networkCidr)
+}
+
+func testAccVirtualNetworkTimeoutsConfig(create string) string {
+	return fmt.Sprintf(`
+resource "eci_virtual_network" "test" {
+  name           = "tf-acc-network"
+  network_cidr   = "10.0.0.0/16"
+  firewall_rules = []
+  tags           = {}
+
+  timeouts {
+    create = %q
+    delete = "10m"
+  }
+}
+`, create)
+}
@@ -0,0 +1,108 @@
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"terraform-provider-eci/internal/api"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const idempotencyKeyPrivateStateKey = "idempotency_key"
+
+// createdLookupSkew tolerates clock differences between terraform and the
+// portal when comparing creation times of lookup candidates.
+const createdLookupSkew = 5 * time.Minute
+
+type privateState interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+func newIdempotencyKey() string {
+	return uuid.NewString()
+}
+
+func setIdempotencyKey(ctx context.Context, private privateState, key string) diag.Diagnostics {
+	value, err := json.Marshal(key)
+	if err != nil {
+		return diag.Diagnostics{
+			diag.NewErrorDiagnostic("failed to encode idempotency key", err.Error()),
+		}
+	}
+
+	return private.SetKey(ctx, idempotencyKeyPrivateStateKey, value)
+}
+
+// recoverCreate is called when a creation request failed. If the failure
+// happened on the transport, the portal may have created the resource anyway,
+// so lookup is used to find it instead of leaving an untracked duplicate
+// behind. When nothing is found, the portal may still be processing the
+// request, so resend sends it again with the same idempotency key, which the
+// portal creates the resource at most once for. The original error is
+// returned when that fails too.
+func recoverCreate(
+	ctx context.Context,
+	err error,
+	lookup func() (*uuid.UUID, error),
+	resend func() (string, error),
+) (string, error) {
+	if !api.IsTransportError(err) || ctx.Err() != nil {
+		return "", err
+	}
+
+	tflog.Warn(ctx, fmt.Sprintf("creation request failed, looking up created resource: %s", err))
+
+	id, lookupErr := lookup()
+	if lookupErr != nil {
+		return "", fmt.Errorf("%w (lookup of created resource failed: %s)", err, lookupErr)
+	}
+
+	if id != nil {
+		tflog.Info(ctx, fmt.Sprintf("found resource created by the failed request: %s", id))
+		return id.String(), nil
+	}
+
+	tflog.Warn(ctx, "no resource created by the failed request was found, sending it again")
+
+	resentId, resendErr := resend()
+	if resendErr != nil {
+		return "", fmt.Errorf("%w (sending the request again failed: %s)", err, resendErr)
+	}
+
+	return resentId, nil
+}
+
+// findCreated returns the id of the only candidate that matches and was
+// created after the creation request was sent.
+func findCreated[T any](
+	candidates iter.Seq2[T, error],
+	startedAt time.Time,
+	match func(candidate T) (id uuid.UUID, created time.Time, ok bool),
+) (*uuid.UUID, error) {
+	var found []uuid.UUID
+
+	for candidate, err := range candidates {
+		if err != nil {
+			return nil, err
+		}
+
+		id, created, ok := match(candidate)
+		if ok && created.After(startedAt.Add(-createdLookupSkew)) {
+			found = append(found, id)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &found[0], nil
+	}
+
+	return nil, fmt.Errorf("multiple resources match the creation request: %v", found)
+}
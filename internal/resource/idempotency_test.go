@@ -0,0 +1,213 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"terraform-provider-eci/internal/api"
+	"terraform-provider-eci/internal/api/fake"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// createBlockStorage creates a block storage named name with r.
+func createBlockStorage(
+	t *testing.T, r *ResourceBlockStorage, name string,
+) resource.CreateResponse {
+	t.Helper()
+	ctx := context.Background()
+
+	state := newState(t, r, &ResourceBlockStorageModel{
+		Name:     types.StringValue(name),
+		SizeGib:  types.Int64Value(10),
+		DR:       types.BoolValue(false),
+		Tags:     types.MapNull(types.StringType),
+		TagsAll:  types.MapNull(types.StringType),
+		Timeouts: nullTimeouts(),
+	})
+	response := newCreateResponse(state)
+
+	r.Create(
+		ctx,
+		resource.CreateRequest{Plan: tfsdk.Plan{Schema: state.Schema, Raw: state.Raw}},
+		&response,
+	)
+
+	return response
+}
+
+// connectionReset is a transport error, after which the portal may or may
+// not have processed the request.
+var connectionReset = &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+
+func TestRecoverCreateAdoptsCreatedResource(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+
+	other, err := client.PostBlockStorage(
+		ctx, "", client.ZoneId.String(), "other", nil, nil, 10, false, nil,
+	)
+	check(t, err)
+
+	// The portal created the block storage, but its response was lost.
+	created, err := client.PostBlockStorage(
+		ctx, "", client.ZoneId.String(), "disk", nil, nil, 10, false, nil,
+	)
+	check(t, err)
+	client.FailNext("PostBlockStorage", connectionReset)
+
+	r := &ResourceBlockStorage{client: client, zoneId: zoneIdOf(client)}
+	response := createBlockStorage(t, r, "disk")
+
+	if response.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", response.Diagnostics)
+	}
+
+	var state ResourceBlockStorageModel
+	response.State.Get(ctx, &state)
+	if state.Id.ValueString() != created.Id.String() {
+		t.Errorf("id: got %s, want %s (other: %s)", state.Id, created.Id, other.Id)
+	}
+
+	storages, err := api.Collect(client.GetBlockStorages(ctx, nil, nil))
+	check(t, err)
+	if len(storages) != 2 {
+		t.Errorf("block storages: got %d, want 2", len(storages))
+	}
+}
+
+func TestRecoverCreateReturnsOriginalError(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+
+	// A block storage of another name is never adopted.
+	_, err := client.PostBlockStorage(
+		ctx, "", client.ZoneId.String(), "other", nil, nil, 10, false, nil,
+	)
+	check(t, err)
+	// The request fails again when it is sent again.
+	client.FailNext("PostBlockStorage", connectionReset)
+	client.FailNext("PostBlockStorage", connectionReset)
+
+	r := &ResourceBlockStorage{client: client, zoneId: zoneIdOf(client)}
+	response := createBlockStorage(t, r, "disk")
+
+	if !response.Diagnostics.HasError() {
+		t.Fatalf("expected an error")
+	}
+	if detail := response.Diagnostics.Errors()[0].Detail(); !strings.Contains(
+		detail, "connection reset",
+	) {
+		t.Errorf("detail does not have the original error: %s", detail)
+	}
+	if !slices.Contains(client.Calls(), "GetBlockStorages") {
+		t.Errorf("created block storage was not looked up: %v", client.Calls())
+	}
+	if !response.State.Raw.IsNull() {
+		t.Errorf("state is set: %v", response.State.Raw)
+	}
+}
+
+func TestRecoverCreateIgnoresRejectedRequests(t *testing.T) {
+	rejected := api.NewAPIError(http.StatusUnprocessableEntity, "invalid", "", nil)
+
+	_, err := recoverCreate(
+		context.Background(),
+		rejected,
+		func() (*uuid.UUID, error) {
+			t.Fatalf("a rejected request was looked up")
+			return nil, nil
+		},
+		func() (string, error) {
+			t.Fatalf("a rejected request was sent again")
+			return "", nil
+		},
+	)
+
+	if err != rejected {
+		t.Errorf("got %v, want %v", err, rejected)
+	}
+}
+
+// processingClient is a portal that loses the response to the first block
+// storage creation request, and that has not listed the block storage that
+// it created yet.
+type processingClient struct {
+	*fake.Client
+
+	idempotencyKeys []string
+}
+
+func (c *processingClient) PostBlockStorage(
+	ctx context.Context,
+	idempotencyKey string,
+	zoneId string,
+	name string,
+	imageId *string,
+	snapshotId *string,
+	sizeGib int,
+	dr bool,
+	tags map[string]string,
+) (*api.ResourceBlockStoragePostResponse, error) {
+	c.idempotencyKeys = append(c.idempotencyKeys, idempotencyKey)
+
+	response, err := c.Client.PostBlockStorage(
+		ctx, idempotencyKey, zoneId, name, imageId, snapshotId, sizeGib, dr, tags,
+	)
+	if len(c.idempotencyKeys) == 1 {
+		return nil, connectionReset
+	}
+	return response, err
+}
+
+func (c *processingClient) GetBlockStorages(
+	ctx context.Context, filterAttachedMachineId *string, filterNameIlike *string,
+) iter.Seq2[api.ResourceBlockStorageGetResponse, error] {
+	return func(yield func(api.ResourceBlockStorageGetResponse, error) bool) {}
+}
+
+func TestRecoverCreateResendsWithTheSameKey(t *testing.T) {
+	ctx := context.Background()
+	client := &processingClient{Client: fake.NewClient()}
+
+	r := &ResourceBlockStorage{client: client, zoneId: zoneIdOf(client.Client)}
+	response := createBlockStorage(t, r, "disk")
+
+	if response.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", response.Diagnostics)
+	}
+
+	keys := client.idempotencyKeys
+	if len(keys) != 2 || keys[0] != keys[1] {
+		t.Fatalf("idempotency keys: got %v, want the same key twice", keys)
+	}
+
+	storages, err := api.Collect(client.Client.GetBlockStorages(ctx, nil, nil))
+	check(t, err)
+	if len(storages) != 1 {
+		t.Fatalf("block storages: got %d, want 1", len(storages))
+	}
+
+	var state ResourceBlockStorageModel
+	response.State.Get(ctx, &state)
+	if state.Id.ValueString() != storages[0].Id.String() {
+		t.Errorf("id: got %s, want %s", state.Id, storages[0].Id)
+	}
+
+	value, diags := response.Private.GetKey(ctx, idempotencyKeyPrivateStateKey)
+	if diags.HasError() {
+		t.Fatalf("failed to get the idempotency key: %v", diags)
+	}
+	if want := fmt.Sprintf("%q", keys[0]); string(value) != want {
+		t.Errorf("idempotency key in private state: got %s, want %s", value, want)
+	}
+}
@@ -0,0 +1,199 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"terraform-provider-eci/internal/api"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Power states of a virtual machine. A virtual machine is running while it
+// has an allocation that is not terminated, and stopped otherwise.
+const (
+	powerStateRunning = "running"
+	powerStateStopped = "stopped"
+)
+
+// activeAllocation returns the allocation of a virtual machine that is not
+// terminated nor being terminated, or nil when it has none.
+func activeAllocation(
+	ctx context.Context, client api.Client, machineId string,
+) (*api.ResourceVirtualMachineAllocationGetResponse, error) {
+	for allocation, err := range client.GetVirtualMachineAllocations(ctx, &machineId, nil) {
+		if err != nil {
+			return nil, err
+		}
+
+		if allocation.Terminating == nil && !isDeletedStatus(allocation.Status) {
+			return &allocation, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// observePowerState returns the power state of a virtual machine.
+func observePowerState(
+	ctx context.Context, client api.Client, machineId string,
+) (types.String, error) {
+	allocation, err := activeAllocation(ctx, client, machineId)
+	if err != nil {
+		return types.StringNull(), err
+	}
+
+	if allocation == nil {
+		return types.StringValue(powerStateStopped), nil
+	}
+	return types.StringValue(powerStateRunning), nil
+}
+
+// startVirtualMachine allocates a virtual machine, unless it is allocated
+// already, and waits for the allocation to start. The allocation has the
+// default tags of the provider.
+func (r *ResourceVirtualMachine) startVirtualMachine(
+	ctx context.Context, machineId string, zoneId string,
+) diag.Diagnostics {
+	diags := diag.Diagnostics{}
+
+	allocation, err := activeAllocation(ctx, r.client, machineId)
+	if err != nil {
+		addResourceError(&diags, "failed to get allocations of a virtual machine", machineId, err)
+		return diags
+	}
+
+	var id string
+	if allocation != nil {
+		id = allocation.Id.String()
+	} else {
+		tags, mergeDiags := r.tags.merge(ctx, types.MapNull(types.StringType))
+		diags.Append(mergeDiags...)
+		if diags.HasError() {
+			return diags
+		}
+
+		idempotencyKey := newIdempotencyKey()
+		startedAt := time.Now()
+
+		create := func() (string, error) {
+			response, err := r.client.PostVirtualMachineAllocation(
+				ctx, idempotencyKey, zoneId, machineId, tags,
+			)
+			if err != nil {
+				return "", err
+			}
+			return response.Id.String(), nil
+		}
+
+		id, err = create()
+		if err != nil {
+			id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+				return findCreated(
+					r.client.GetVirtualMachineAllocations(ctx, &machineId, nil),
+					startedAt,
+					func(
+						allocation api.ResourceVirtualMachineAllocationGetResponse,
+					) (uuid.UUID, time.Time, bool) {
+						return allocation.Id, allocation.Created, allocation.Terminated == nil &&
+							allocation.Terminating == nil
+					},
+				)
+			}, create)
+		}
+
+		if err != nil {
+			addResourceError(&diags, "failed to start a virtual machine", machineId, err)
+			return diags
+		}
+
+		tflog.Info(ctx, fmt.Sprintf(
+			"allocated a virtual machine (virtual machine: %s, allocation: %s)", machineId, id,
+		))
+	}
+
+	waiter := statusWaiter{
+		resource: "virtual machine allocation",
+		target:   []string{"started"},
+		failure:  []string{"terminating", "terminated"},
+	}
+	_, waitDiags := waiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetVirtualMachineAllocation(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
+	diags.Append(waitDiags...)
+
+	return diags
+}
+
+// stopVirtualMachine terminates the allocation of a virtual machine, if it
+// has one, and waits for the virtual machine to be idle.
+func (r *ResourceVirtualMachine) stopVirtualMachine(
+	ctx context.Context, machineId string,
+) diag.Diagnostics {
+	diags := diag.Diagnostics{}
+
+	allocation, err := activeAllocation(ctx, r.client, machineId)
+	if err != nil {
+		addResourceError(&diags, "failed to get allocations of a virtual machine", machineId, err)
+		return diags
+	}
+
+	if allocation == nil {
+		return diags
+	}
+
+	id := allocation.Id.String()
+	_, err = r.client.DeleteVirtualMachineAllocation(ctx, id)
+	successMessage, err := isResourceDeleted(err, "resource_allocation", "terminated")
+
+	if err != nil {
+		addResourceError(&diags, "failed to stop a virtual machine", machineId, err)
+		return diags
+	}
+
+	tflog.Info(ctx, fmt.Sprintf(
+		"%s (virtual machine: %s, allocation: %s)", successMessage, machineId, id,
+	))
+
+	allocationWaiter := statusWaiter{
+		resource:       "virtual machine allocation",
+		target:         []string{"terminated"},
+		targetNotFound: true,
+	}
+	_, waitDiags := allocationWaiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetVirtualMachineAllocation(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
+	diags.Append(waitDiags...)
+
+	if diags.HasError() {
+		return diags
+	}
+
+	// The virtual machine can be allocated again only once it is idle.
+	machineWaiter := statusWaiter{
+		resource: "virtual machine",
+		target:   []string{"idle"},
+		failure:  []string{"deleted"},
+	}
+	_, waitDiags = machineWaiter.wait(ctx, machineId, func() (string, error) {
+		getResponse, err := r.client.GetVirtualMachine(ctx, machineId)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
+	diags.Append(waitDiags...)
+
+	return diags
+}
@@ -0,0 +1,16 @@
+package resource
+
+import (
+	"context"
+	"terraform-provider-eci/internal/api"
+)
+
+// ProviderData is what the provider hands to resources when configuring them.
+type ProviderData struct {
+	Client api.Client
+	Tags   TagPolicy
+
+	// ZoneId returns the zone of resources that do not set their own. The
+	// zone may be looked up on first use, so it is only called when needed.
+	ZoneId func(ctx context.Context) (string, error)
+}
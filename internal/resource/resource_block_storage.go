@@ -2,10 +2,15 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"terraform-provider-eci/internal/api"
 	. "terraform-provider-eci/internal/utils"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -21,52 +26,61 @@ import (
 )
 
 var _ resource.Resource = &ResourceBlockStorage{}
+var _ resource.ResourceWithImportState = &ResourceBlockStorage{}
+var _ resource.ResourceWithModifyPlan = &ResourceBlockStorage{}
 
 func NewResourceBlockStorage() resource.Resource {
 	return &ResourceBlockStorage{}
 }
 
 type ResourceBlockStorage struct {
-	client *api.APIClient
+	client api.Client
+	tags   TagPolicy
+	zoneId func(ctx context.Context) (string, error)
 }
 
 type ResourceBlockStorageModel struct {
-	Id                 types.String `tfsdk:"id"`
-	Tags               types.Map    `tfsdk:"tags"`
-	Name               types.String `tfsdk:"name"`
-	Created            types.String `tfsdk:"created"`
-	Modified           types.String `tfsdk:"modified"`
-	ZoneId             types.String `tfsdk:"zone_id"`
-	OrganizationId     types.String `tfsdk:"organization_id"`
-	AttachedMachineId  types.String `tfsdk:"attached_machine_id"`
-	ImageId            types.String `tfsdk:"image_id"`
-	SnapshotId         types.String `tfsdk:"snapshot_id"`
-	SizeGib            types.Int64  `tfsdk:"size_gib"`
-	DR                 types.Bool   `tfsdk:"dr"`
-	LastSyncedSnapshot types.String `tfsdk:"last_synced_snapshot"`
-	Assigned           types.String `tfsdk:"assigned"`
-	Prepared           types.String `tfsdk:"prepared"`
-	Deleting           types.String `tfsdk:"deleting"`
-	Deleted            types.String `tfsdk:"deleted"`
-	Status             types.String `tfsdk:"status"`
+	Id                 types.String   `tfsdk:"id"`
+	Tags               types.Map      `tfsdk:"tags"`
+	TagsAll            types.Map      `tfsdk:"tags_all"`
+	Name               types.String   `tfsdk:"name"`
+	Created            types.String   `tfsdk:"created"`
+	Modified           types.String   `tfsdk:"modified"`
+	ZoneId             types.String   `tfsdk:"zone_id"`
+	OrganizationId     types.String   `tfsdk:"organization_id"`
+	AttachedMachineId  types.String   `tfsdk:"attached_machine_id"`
+	ImageId            types.String   `tfsdk:"image_id"`
+	SnapshotId         types.String   `tfsdk:"snapshot_id"`
+	SizeGib            types.Int64    `tfsdk:"size_gib"`
+	DR                 types.Bool     `tfsdk:"dr"`
+	LastSyncedSnapshot types.String   `tfsdk:"last_synced_snapshot"`
+	Assigned           types.String   `tfsdk:"assigned"`
+	Prepared           types.String   `tfsdk:"prepared"`
+	Deleting           types.String   `tfsdk:"deleting"`
+	Deleted            types.String   `tfsdk:"deleted"`
+	Status             types.String   `tfsdk:"status"`
+	Timeouts           timeouts.Value `tfsdk:"timeouts"`
 }
 
 func resourceBlockStorageGetResponseToBlockStorageModel(
 	ctx context.Context,
 	response *api.ResourceBlockStorageGetResponse,
 	data *ResourceBlockStorageModel,
+	tagPolicy TagPolicy,
 ) diag.Diagnostics {
 	data.Id = types.StringValue(response.Id.String())
 	data.Name = types.StringValue(response.Name)
 	data.Created = types.StringValue(response.Created.String())
 
-	tags, diags := types.MapValueFrom(ctx, types.StringType, response.Tags)
+	tags, diags := types.MapValueFrom(
+		ctx, types.StringType, tagPolicy.withoutIgnored(response.Tags),
+	)
 
 	if diags.HasError() {
 		return diags
 	}
 
-	data.Tags = tags
+	data.TagsAll = tags
 	data.Modified = StringOrNull(response.Modified)
 	data.ZoneId = types.StringValue(response.ZoneId.String())
 	data.OrganizationId = types.StringValue(response.OrganizationId.String())
@@ -105,7 +119,12 @@ func (r *ResourceBlockStorage) Schema(
 			"tags": schema.MapAttribute{
 				Description: "User-defined metadata of key-value pairs",
 				ElementType: types.StringType,
-				Required:    true,
+				Optional:    true,
+			},
+			"tags_all": schema.MapAttribute{
+				Description: "tags of the resource, including the default tags of the provider",
+				ElementType: types.StringType,
+				Computed:    true,
 			},
 			"name": schema.StringAttribute{
 				Description: "name of the block storage",
@@ -125,11 +144,14 @@ func (r *ResourceBlockStorage) Schema(
 				Computed:    true,
 			},
 			"zone_id": schema.StringAttribute{
-				Description:   "id of zone that the block storage belongs to",
-				Required:      false,
-				Computed:      true,
-				Optional:      false,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "id of zone that the block storage belongs to, " +
+					"which defaults to the zone of the provider",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"organization_id": schema.StringAttribute{
 				Description:   "id of organization that the block storage belongs to",
@@ -141,10 +163,15 @@ func (r *ResourceBlockStorage) Schema(
 				Required:    true,
 			},
 			"image_id": schema.StringAttribute{
-				Description:   "id of image that the block storage will copy from",
-				Optional:      true,
-				Computed:      true,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description: "id of image that the block storage will copy from",
+				Optional:    true,
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					// A block storage created without an image has none in its state,
+					// so only a configured image is one to replace it for.
+					stringplanmodifier.RequiresReplaceIfConfigured(),
+				},
 			},
 			"snapshot_id": schema.StringAttribute{
 				Description:   "id of snapshot that the block storage will copy from",
@@ -190,6 +217,13 @@ func (r *ResourceBlockStorage) Schema(
 				Computed:    true,
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
 	}
 }
 
@@ -200,18 +234,20 @@ func (r *ResourceBlockStorage) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	data, ok := req.ProviderData.(*ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
-			fmt.Sprintf(`expected *api.APIClient, got: %T.`, req.ProviderData),
+			fmt.Sprintf(`expected *resource.ProviderData, got: %T.`, req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Client
+	r.tags = data.Tags
+	r.zoneId = data.ZoneId
 }
 
 func (r *ResourceBlockStorage) Create(
@@ -225,8 +261,17 @@ func (r *ResourceBlockStorage) Create(
 		return
 	}
 
-	tags := map[string]string{}
-	resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	tags, diags := r.tags.merge(ctx, plan.Tags)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -236,27 +281,60 @@ func (r *ResourceBlockStorage) Create(
 		imageIdPtr = plan.ImageId.ValueStringPointer()
 	}
 
-	response, err := r.client.PostBlockStorage(
-		plan.Name.ValueString(),
-		imageIdPtr,
-		plan.SnapshotId.ValueStringPointer(),
-		int(plan.SizeGib.ValueInt64()),
-		plan.DR.ValueBool(),
-		tags,
-	)
+	zoneId, err := zoneIdOrDefault(ctx, plan.ZoneId, r.zoneId)
+	if err != nil {
+		addResourceError(&resp.Diagnostics, "failed to select the zone", "", err)
+		return
+	}
+
+	idempotencyKey := newIdempotencyKey()
+	startedAt := time.Now()
+
+	create := func() (string, error) {
+		response, err := r.client.PostBlockStorage(
+			ctx,
+			idempotencyKey,
+			zoneId,
+			plan.Name.ValueString(),
+			imageIdPtr,
+			plan.SnapshotId.ValueStringPointer(),
+			int(plan.SizeGib.ValueInt64()),
+			plan.DR.ValueBool(),
+			tags,
+		)
+		if err != nil {
+			return "", err
+		}
+		return response.Id.String(), nil
+	}
+
+	id, err := create()
+	if err != nil {
+		id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+			return findCreated(
+				r.client.GetBlockStorages(ctx, nil, plan.Name.ValueStringPointer()),
+				startedAt,
+				func(storage api.ResourceBlockStorageGetResponse) (uuid.UUID, time.Time, bool) {
+					return storage.Id, storage.Created, storage.Status != "deleted" &&
+						storage.Name == plan.Name.ValueString() &&
+						storage.SizeGib == int(plan.SizeGib.ValueInt64()) &&
+						maps.Equal(storage.Tags, tags)
+				},
+			)
+		}, create)
+	}
 
 	if err != nil {
 		addResourceError(&(resp.Diagnostics), "failed to create block stroage", "", err)
 		return
 	}
 
-	id := response.Id.String()
-
 	tflog.Trace(ctx, fmt.Sprintf("created a block storage: %s", id))
+	resp.Diagnostics.Append(setIdempotencyKey(ctx, resp.Private, idempotencyKey)...)
 
 	if !plan.AttachedMachineId.IsNull() {
 		var attachedMachineId = plan.AttachedMachineId.ValueStringPointer()
-		_, err := r.client.PatchBlockStorage(id, nil, &attachedMachineId, nil)
+		_, err := r.client.PatchBlockStorage(ctx, id, nil, &attachedMachineId, nil)
 
 		if err != nil {
 			addResourceError(&resp.Diagnostics, "failed to patch block storage", id, err)
@@ -269,14 +347,14 @@ func (r *ResourceBlockStorage) Create(
 		)
 	}
 
-	getResponse, err := r.client.GetBlockStorage(id)
+	getResponse, err := r.client.GetBlockStorage(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get block storage", id, err)
 		return
 	}
 
-	resourceBlockStorageGetResponseToBlockStorageModel(ctx, getResponse, &plan)
+	resourceBlockStorageGetResponseToBlockStorageModel(ctx, getResponse, &plan, r.tags)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 
@@ -289,23 +367,29 @@ func (r *ResourceBlockStorage) Create(
 		return
 	}
 
-	_, diags := waitStatus(
-		func() (*string, error) {
-			getResponse, err := r.client.GetBlockStorage(id)
-			if err != nil {
-				return nil, err
-			}
-			return &getResponse.Status, nil
-		},
-		[]string{"prepared"},
-		10,
-	)
+	waiter := statusWaiter{
+		resource: "block storage",
+		target:   []string{"prepared"},
+		failure:  []string{"error", "deleting", "deleted"},
+	}
+	_, diags = waiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetBlockStorage(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
 	resp.Diagnostics.Append(diags...)
 }
 
 func (r *ResourceBlockStorage) Read(
 	ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse,
 ) {
+	// Until the provider is configured, the state is kept as it is.
+	if r.client == nil {
+		return
+	}
+
 	var data ResourceBlockStorageModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -315,14 +399,30 @@ func (r *ResourceBlockStorage) Read(
 	}
 
 	id := data.Id.ValueString()
-	response, err := r.client.GetBlockStorage(id)
+	response, err := r.client.GetBlockStorage(ctx, id)
 
+	if errors.Is(err, api.ErrNotFound) {
+		removeDeletedResource(ctx, resp, id, "not found")
+		return
+	}
 	if err != nil {
-		addResourceError(&resp.Diagnostics, "failed to create block stroage", id, err)
+		addResourceError(&resp.Diagnostics, "failed to get block storage", id, err)
+		return
+	}
+
+	if isDeletedStatus(response.Status) {
+		removeDeletedResource(ctx, resp, id, fmt.Sprintf("status %s", response.Status))
 		return
 	}
 
-	resourceBlockStorageGetResponseToBlockStorageModel(ctx, response, &data)
+	resourceBlockStorageGetResponseToBlockStorageModel(ctx, response, &data, r.tags)
+	tags, diags := r.tags.configuredTags(ctx, data.Tags, data.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Tags = tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -339,6 +439,17 @@ func (r *ResourceBlockStorage) Update(
 		return
 	}
 
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
 	id := state.Id.ValueString()
 
 	var attachedMachineIdPtr **string = nil
@@ -346,7 +457,7 @@ func (r *ResourceBlockStorage) Update(
 		if !state.AttachedMachineId.IsNull() && !plan.AttachedMachineId.IsNull() {
 			var nilAttachedMachineId *string = nil
 
-			_, err := r.client.PatchBlockStorage(id, nil, &nilAttachedMachineId, nil)
+			_, err := r.client.PatchBlockStorage(ctx, id, nil, &nilAttachedMachineId, nil)
 			if err != nil {
 				addResourceError(&resp.Diagnostics, "failed to detach block stroage", id, err)
 				return
@@ -369,17 +480,26 @@ func (r *ResourceBlockStorage) Update(
 	}
 
 	var tagsPtr *map[string]string = nil
-	if !plan.Tags.Equal(state.Tags) {
-		tags := map[string]string{}
-		resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	if !plan.Tags.Equal(state.Tags) || !plan.TagsAll.Equal(state.TagsAll) {
+		tags, diags := r.tags.merge(ctx, plan.Tags)
+		resp.Diagnostics.Append(diags...)
 		if resp.Diagnostics.HasError() {
 			return
 		}
 
+		if r.tags.ignoresAny() {
+			current, err := r.client.GetBlockStorage(ctx, id)
+			if err != nil {
+				addResourceError(&resp.Diagnostics, "failed to get block storage", id, err)
+				return
+			}
+			r.tags.keepIgnored(tags, current.Tags)
+		}
+
 		tagsPtr = &tags
 	}
 
-	_, err := r.client.PatchBlockStorage(id, namePtr, attachedMachineIdPtr, tagsPtr)
+	_, err := r.client.PatchBlockStorage(ctx, id, namePtr, attachedMachineIdPtr, tagsPtr)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to patch block stroage", id, err)
@@ -388,14 +508,16 @@ func (r *ResourceBlockStorage) Update(
 
 	tflog.Info(ctx, fmt.Sprintf("successfully patched a block storage: %s", id))
 
-	getResponse, err := r.client.GetBlockStorage(state.Id.ValueString())
+	getResponse, err := r.client.GetBlockStorage(ctx, state.Id.ValueString())
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get block storage", id, err)
 		return
 	}
 
-	resourceBlockStorageGetResponseToBlockStorageModel(ctx, getResponse, &state)
+	resourceBlockStorageGetResponseToBlockStorageModel(ctx, getResponse, &state, r.tags)
+
+	state.Tags = plan.Tags
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
@@ -411,10 +533,19 @@ func (r *ResourceBlockStorage) Delete(
 		return
 	}
 
+	deleteTimeout, diags := plan.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
 	id := plan.Id.ValueString()
 
 	if !plan.AttachedMachineId.IsNull() {
-		virtualMachine, err := r.client.GetVirtualMachine(plan.AttachedMachineId.ValueString())
+		virtualMachine, err := r.client.GetVirtualMachine(ctx, plan.AttachedMachineId.ValueString())
 		if err != nil {
 			addResourceError(
 				&resp.Diagnostics,
@@ -434,7 +565,7 @@ func (r *ResourceBlockStorage) Delete(
 		}
 
 		var nilAttachedMachineId *string = nil
-		_, err = r.client.PatchBlockStorage(id, nil, &nilAttachedMachineId, nil)
+		_, err = r.client.PatchBlockStorage(ctx, id, nil, &nilAttachedMachineId, nil)
 		if err != nil {
 			addResourceError(
 				&resp.Diagnostics,
@@ -448,7 +579,7 @@ func (r *ResourceBlockStorage) Delete(
 		tflog.Trace(ctx, fmt.Sprintf("block storage (%s) detached from a virtual machine", id))
 	}
 
-	_, err := r.client.DeleteBlockStorage(id)
+	_, err := r.client.DeleteBlockStorage(ctx, id)
 	successMessage, err := isResourceDeleted(err, "resource_block_storage", "deleted")
 
 	if err != nil {
@@ -458,3 +589,15 @@ func (r *ResourceBlockStorage) Delete(
 
 	tflog.Info(ctx, fmt.Sprintf("%s (block storage: %s)", successMessage, id))
 }
+
+func (r *ResourceBlockStorage) ModifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	r.tags.modifyPlan(ctx, req, resp)
+}
+
+func (r *ResourceBlockStorage) ImportState(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	importStateById(ctx, req, resp)
+}
@@ -2,10 +2,15 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"terraform-provider-eci/internal/api"
 	. "terraform-provider-eci/internal/utils"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -21,50 +26,59 @@ import (
 )
 
 var _ resource.Resource = &ResourceBlockStorageSnapshot{}
+var _ resource.ResourceWithImportState = &ResourceBlockStorageSnapshot{}
+var _ resource.ResourceWithModifyPlan = &ResourceBlockStorageSnapshot{}
 
 func NewResourceBlockStorageSnapshot() resource.Resource {
 	return &ResourceBlockStorageSnapshot{}
 }
 
 type ResourceBlockStorageSnapshot struct {
-	client *api.APIClient
+	client api.Client
+	tags   TagPolicy
+	zoneId func(ctx context.Context) (string, error)
 }
 
 type ResourceBlockStorageSnapshotModel struct {
-	Id             types.String `tfsdk:"id"`
-	Name           types.String `tfsdk:"name"`
-	Tags           types.Map    `tfsdk:"tags"`
-	Created        types.String `tfsdk:"created"`
-	Modified       types.String `tfsdk:"modified"`
-	ZoneId         types.String `tfsdk:"zone_id"`
-	OrganizationId types.String `tfsdk:"organization_id"`
-	BlockStorageId types.String `tfsdk:"block_storage_id"`
-	ImageId        types.String `tfsdk:"image_id"`
-	SizeGib        types.Int64  `tfsdk:"size_gib"`
-	Assigned       types.String `tfsdk:"assigned"`
-	Prepared       types.String `tfsdk:"prepared"`
-	Deleting       types.String `tfsdk:"deleting"`
-	Deleted        types.String `tfsdk:"deleted"`
-	DR             types.Bool   `tfsdk:"dr"`
-	Status         types.String `tfsdk:"status"`
+	Id             types.String   `tfsdk:"id"`
+	Name           types.String   `tfsdk:"name"`
+	Tags           types.Map      `tfsdk:"tags"`
+	TagsAll        types.Map      `tfsdk:"tags_all"`
+	Created        types.String   `tfsdk:"created"`
+	Modified       types.String   `tfsdk:"modified"`
+	ZoneId         types.String   `tfsdk:"zone_id"`
+	OrganizationId types.String   `tfsdk:"organization_id"`
+	BlockStorageId types.String   `tfsdk:"block_storage_id"`
+	ImageId        types.String   `tfsdk:"image_id"`
+	SizeGib        types.Int64    `tfsdk:"size_gib"`
+	Assigned       types.String   `tfsdk:"assigned"`
+	Prepared       types.String   `tfsdk:"prepared"`
+	Deleting       types.String   `tfsdk:"deleting"`
+	Deleted        types.String   `tfsdk:"deleted"`
+	DR             types.Bool     `tfsdk:"dr"`
+	Status         types.String   `tfsdk:"status"`
+	Timeouts       timeouts.Value `tfsdk:"timeouts"`
 }
 
 func resourceBlockStorageSnapshotGetResponseToBlockStorageSnapshotModel(
 	ctx context.Context,
 	response *api.ResourceBlockStorageSnapshotGetResponse,
 	data *ResourceBlockStorageSnapshotModel,
+	tagPolicy TagPolicy,
 ) diag.Diagnostics {
 	data.Id = types.StringValue(response.Id.String())
 	data.Name = types.StringValue(response.Name)
 	data.Created = types.StringValue(response.Created.String())
 
-	tags, diags := types.MapValueFrom(ctx, types.StringType, response.Tags)
+	tags, diags := types.MapValueFrom(
+		ctx, types.StringType, tagPolicy.withoutIgnored(response.Tags),
+	)
 
 	if diags.HasError() {
 		return diags
 	}
 
-	data.Tags = tags
+	data.TagsAll = tags
 	data.Modified = StringOrNull(response.Modified)
 	data.ZoneId = types.StringValue(response.ZoneId.String())
 	data.OrganizationId = types.StringValue(response.OrganizationId.String())
@@ -101,7 +115,12 @@ func (r *ResourceBlockStorageSnapshot) Schema(
 			"tags": schema.MapAttribute{
 				Description: "User-defined metadata of key-value pairs",
 				ElementType: types.StringType,
-				Required:    true,
+				Optional:    true,
+			},
+			"tags_all": schema.MapAttribute{
+				Description: "tags of the resource, including the default tags of the provider",
+				ElementType: types.StringType,
+				Computed:    true,
 			},
 			"name": schema.StringAttribute{
 				Description: "name of the block storage snapshot",
@@ -121,9 +140,14 @@ func (r *ResourceBlockStorageSnapshot) Schema(
 				Computed:    true,
 			},
 			"zone_id": schema.StringAttribute{
-				Description:   "id of zone that the block storage snapshot belongs to",
-				Computed:      true,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "id of zone that the block storage snapshot belongs to, " +
+					"which defaults to the zone of the provider",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"organization_id": schema.StringAttribute{
 				Description:   "id of organization that the block storage snapshot belongs to",
@@ -131,18 +155,25 @@ func (r *ResourceBlockStorageSnapshot) Schema(
 				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
 			},
 			"block_storage_id": schema.StringAttribute{
-				Description: "id of the block storage this blocks storage snapshot was taken from",
-				Required:    true,
+				Description:   "id of the block storage this blocks storage snapshot was taken from",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
 			},
 			"image_id": schema.StringAttribute{
-				Description:   "id of the image that the block storage of this snapshot was created from",
-				Computed:      true,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description: "id of the image that the block storage of this snapshot was created from",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"size_gib": schema.Int64Attribute{
-				Description:   "size of the block storage snapshot (GiB)",
-				Computed:      true,
-				PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+				Description: "size of the block storage snapshot (GiB)",
+				Computed:    true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+					int64planmodifier.RequiresReplace(),
+				},
 			},
 			"assigned": schema.StringAttribute{
 				Description:   "the time when the block storage snapshot enters `assigned` status",
@@ -165,15 +196,25 @@ func (r *ResourceBlockStorageSnapshot) Schema(
 				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
 			},
 			"dr": schema.BoolAttribute{
-				Description:   "whether to enable DR support",
-				Computed:      true,
-				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description: "whether to enable DR support",
+				Computed:    true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.UseStateForUnknown(),
+					boolplanmodifier.RequiresReplace(),
+				},
 			},
 			"status": schema.StringAttribute{
 				Description: "status of the block storage snapshot",
 				Computed:    true,
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
 	}
 }
 
@@ -184,18 +225,20 @@ func (r *ResourceBlockStorageSnapshot) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	data, ok := req.ProviderData.(*ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
-			fmt.Sprintf(`expected *api.APIClient, got: %T.`, req.ProviderData),
+			fmt.Sprintf(`expected *resource.ProviderData, got: %T.`, req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Client
+	r.tags = data.Tags
+	r.zoneId = data.ZoneId
 }
 
 func (r *ResourceBlockStorageSnapshot) Create(
@@ -209,35 +252,89 @@ func (r *ResourceBlockStorageSnapshot) Create(
 		return
 	}
 
-	tags := map[string]string{}
-	resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	response, err := r.client.PostBlockStorageSnapshot(
-		plan.Name.ValueString(),
-		plan.BlockStorageId.ValueString(),
-		tags,
-	)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	tags, diags := r.tags.merge(ctx, plan.Tags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
+	zoneId, err := zoneIdOrDefault(ctx, plan.ZoneId, r.zoneId)
 	if err != nil {
-		addResourceError(&(resp.Diagnostics), "failed to create block stroage snapshot", "", err)
+		addResourceError(&resp.Diagnostics, "failed to select the zone", "", err)
 		return
 	}
 
-	id := response.Id.String()
+	idempotencyKey := newIdempotencyKey()
+	startedAt := time.Now()
+
+	create := func() (string, error) {
+		response, err := r.client.PostBlockStorageSnapshot(
+			ctx,
+			idempotencyKey,
+			zoneId,
+			plan.Name.ValueString(),
+			plan.BlockStorageId.ValueString(),
+			tags,
+		)
+		if err != nil {
+			return "", err
+		}
+		return response.Id.String(), nil
+	}
+
+	id, err := create()
+	if err != nil {
+		id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+			return findCreated(
+				r.client.GetBlockStorageSnapshots(
+					ctx,
+					nil,
+					nil,
+					plan.Name.ValueStringPointer(),
+					plan.BlockStorageId.ValueStringPointer(),
+					nil,
+					nil,
+					nil,
+				),
+				startedAt,
+				func(
+					snapshot api.ResourceBlockStorageSnapshotGetResponse,
+				) (uuid.UUID, time.Time, bool) {
+					return snapshot.Id, snapshot.Created, snapshot.Status != "deleted" &&
+						snapshot.Name == plan.Name.ValueString() &&
+						maps.Equal(snapshot.Tags, tags)
+				},
+			)
+		}, create)
+	}
+
+	if err != nil {
+		addResourceError(&(resp.Diagnostics), "failed to create block stroage snapshot", "", err)
+		return
+	}
 
 	tflog.Trace(ctx, fmt.Sprintf("created a block storage snapshot: %s", id))
+	resp.Diagnostics.Append(setIdempotencyKey(ctx, resp.Private, idempotencyKey)...)
 
-	getResponse, err := r.client.GetBlockStorageSnapshot(id)
+	getResponse, err := r.client.GetBlockStorageSnapshot(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get block storage snapshot", id, err)
 		return
 	}
 
-	resourceBlockStorageSnapshotGetResponseToBlockStorageSnapshotModel(ctx, getResponse, &plan)
+	resourceBlockStorageSnapshotGetResponseToBlockStorageSnapshotModel(
+		ctx, getResponse, &plan, r.tags,
+	)
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 
@@ -250,23 +347,29 @@ func (r *ResourceBlockStorageSnapshot) Create(
 		return
 	}
 
-	_, diags := waitStatus(
-		func() (*string, error) {
-			getResponse, err := r.client.GetBlockStorageSnapshot(id)
-			if err != nil {
-				return nil, err
-			}
-			return &getResponse.Status, nil
-		},
-		[]string{"prepared"},
-		10,
-	)
+	waiter := statusWaiter{
+		resource: "block storage snapshot",
+		target:   []string{"prepared"},
+		failure:  []string{"error", "deleting", "deleted"},
+	}
+	_, diags = waiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetBlockStorageSnapshot(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
 	resp.Diagnostics.Append(diags...)
 }
 
 func (r *ResourceBlockStorageSnapshot) Read(
 	ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse,
 ) {
+	// Until the provider is configured, the state is kept as it is.
+	if r.client == nil {
+		return
+	}
+
 	var data ResourceBlockStorageSnapshotModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -276,14 +379,32 @@ func (r *ResourceBlockStorageSnapshot) Read(
 	}
 
 	id := data.Id.ValueString()
-	response, err := r.client.GetBlockStorageSnapshot(id)
+	response, err := r.client.GetBlockStorageSnapshot(ctx, id)
 
+	if errors.Is(err, api.ErrNotFound) {
+		removeDeletedResource(ctx, resp, id, "not found")
+		return
+	}
 	if err != nil {
-		addResourceError(&resp.Diagnostics, "failed to create block stroage snapshot", id, err)
+		addResourceError(&resp.Diagnostics, "failed to get block storage snapshot", id, err)
 		return
 	}
 
-	resourceBlockStorageSnapshotGetResponseToBlockStorageSnapshotModel(ctx, response, &data)
+	if isDeletedStatus(response.Status) {
+		removeDeletedResource(ctx, resp, id, fmt.Sprintf("status %s", response.Status))
+		return
+	}
+
+	resourceBlockStorageSnapshotGetResponseToBlockStorageSnapshotModel(
+		ctx, response, &data, r.tags,
+	)
+	tags, diags := r.tags.configuredTags(ctx, data.Tags, data.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	data.Tags = tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
@@ -300,6 +421,17 @@ func (r *ResourceBlockStorageSnapshot) Update(
 		return
 	}
 
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
 	id := state.Id.ValueString()
 
 	var namePtr *string = nil
@@ -308,17 +440,26 @@ func (r *ResourceBlockStorageSnapshot) Update(
 	}
 
 	var tagsPtr *map[string]string = nil
-	if !plan.Tags.Equal(state.Tags) {
-		tags := map[string]string{}
-		resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	if !plan.Tags.Equal(state.Tags) || !plan.TagsAll.Equal(state.TagsAll) {
+		tags, diags := r.tags.merge(ctx, plan.Tags)
+		resp.Diagnostics.Append(diags...)
 		if resp.Diagnostics.HasError() {
 			return
 		}
 
+		if r.tags.ignoresAny() {
+			current, err := r.client.GetBlockStorageSnapshot(ctx, id)
+			if err != nil {
+				addResourceError(&resp.Diagnostics, "failed to get block storage snapshot", id, err)
+				return
+			}
+			r.tags.keepIgnored(tags, current.Tags)
+		}
+
 		tagsPtr = &tags
 	}
 
-	_, err := r.client.PatchBlockStorageSnapshot(id, namePtr, tagsPtr)
+	_, err := r.client.PatchBlockStorageSnapshot(ctx, id, namePtr, tagsPtr)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to patch block stroage snapshot", id, err)
@@ -327,14 +468,18 @@ func (r *ResourceBlockStorageSnapshot) Update(
 
 	tflog.Info(ctx, fmt.Sprintf("successfully patched a block storage snapshot: %s", id))
 
-	getResponse, err := r.client.GetBlockStorageSnapshot(state.Id.ValueString())
+	getResponse, err := r.client.GetBlockStorageSnapshot(ctx, state.Id.ValueString())
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get block storage snapshot", id, err)
 		return
 	}
 
-	resourceBlockStorageSnapshotGetResponseToBlockStorageSnapshotModel(ctx, getResponse, &state)
+	resourceBlockStorageSnapshotGetResponseToBlockStorageSnapshotModel(
+		ctx, getResponse, &state, r.tags,
+	)
+
+	state.Tags = plan.Tags
 
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
@@ -350,9 +495,18 @@ func (r *ResourceBlockStorageSnapshot) Delete(
 		return
 	}
 
+	deleteTimeout, diags := plan.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
 	id := plan.Id.ValueString()
 
-	_, err := r.client.DeleteBlockStorageSnapshot(id)
+	_, err := r.client.DeleteBlockStorageSnapshot(ctx, id)
 	successMessage, err := isResourceDeleted(err, "resource_block_storage_snapshot", "deleted")
 
 	if err != nil {
@@ -362,3 +516,15 @@ func (r *ResourceBlockStorageSnapshot) Delete(
 
 	tflog.Info(ctx, fmt.Sprintf("%s (block storage snapshot: %s)", successMessage, id))
 }
+
+func (r *ResourceBlockStorageSnapshot) ModifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	r.tags.modifyPlan(ctx, req, resp)
+}
+
+func (r *ResourceBlockStorageSnapshot) ImportState(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	importStateById(ctx, req, resp)
+}
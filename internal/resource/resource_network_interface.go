@@ -2,10 +2,15 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"terraform-provider-eci/internal/api"
 	. "terraform-provider-eci/internal/utils"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -17,41 +22,50 @@ import (
 )
 
 type ResourceNetworkInterfaceModel struct {
-	Id                types.String `tfsdk:"id"`
-	Tags              types.Map    `tfsdk:"tags"`
-	Created           types.String `tfsdk:"created"`
-	Modified          types.String `tfsdk:"modified"`
-	ZoneId            types.String `tfsdk:"zone_id"`
-	OrganizationId    types.String `tfsdk:"organization_id"`
-	AttachedSubnetId  types.String `tfsdk:"attached_subnet_id"`
-	AttachedMachineId types.String `tfsdk:"attached_machine_id"`
-	DR                types.Bool   `tfsdk:"dr"`
-	Deleted           types.String `tfsdk:"deleted"`
-	Status            types.String `tfsdk:"status"`
-	Name              types.String `tfsdk:"name"`
-	Ip                types.String `tfsdk:"ip"`
-	Mac               types.String `tfsdk:"mac"`
+	Id                types.String   `tfsdk:"id"`
+	Tags              types.Map      `tfsdk:"tags"`
+	TagsAll           types.Map      `tfsdk:"tags_all"`
+	Created           types.String   `tfsdk:"created"`
+	Modified          types.String   `tfsdk:"modified"`
+	ZoneId            types.String   `tfsdk:"zone_id"`
+	OrganizationId    types.String   `tfsdk:"organization_id"`
+	AttachedSubnetId  types.String   `tfsdk:"attached_subnet_id"`
+	AttachedMachineId types.String   `tfsdk:"attached_machine_id"`
+	DR                types.Bool     `tfsdk:"dr"`
+	Deleted           types.String   `tfsdk:"deleted"`
+	Status            types.String   `tfsdk:"status"`
+	Name              types.String   `tfsdk:"name"`
+	Ip                types.String   `tfsdk:"ip"`
+	Mac               types.String   `tfsdk:"mac"`
+	Timeouts          timeouts.Value `tfsdk:"timeouts"`
 }
 
 var _ resource.Resource = &ResourceNetworkInterface{}
+var _ resource.ResourceWithImportState = &ResourceNetworkInterface{}
+var _ resource.ResourceWithModifyPlan = &ResourceNetworkInterface{}
 
 type ResourceNetworkInterface struct {
-	client *api.APIClient
+	client api.Client
+	tags   TagPolicy
+	zoneId func(ctx context.Context) (string, error)
 }
 
 func resourceNetworkInterfaceGetResponseToNetworkInterfaceModel(
 	ctx context.Context,
 	response *api.ResourceNetworkInterfaceGetResponse,
 	data *ResourceNetworkInterfaceModel,
+	tagPolicy TagPolicy,
 ) diag.Diagnostics {
 	data.Id = types.StringValue(response.Id.String())
-	tags, diags := types.MapValueFrom(ctx, types.StringType, response.Tags)
+	tags, diags := types.MapValueFrom(
+		ctx, types.StringType, tagPolicy.withoutIgnored(response.Tags),
+	)
 
 	if diags.HasError() {
 		return diags
 	}
 
-	data.Tags = tags
+	data.TagsAll = tags
 	data.Created = types.StringValue(response.Created.String())
 	data.Modified = StringOrNull(response.Modified)
 	data.ZoneId = types.StringValue(response.ZoneId.String())
@@ -93,7 +107,12 @@ func (r *ResourceNetworkInterface) Schema(
 			"tags": schema.MapAttribute{
 				Description: "User-defined metadata of key-value pairs",
 				ElementType: types.StringType,
-				Required:    true,
+				Optional:    true,
+			},
+			"tags_all": schema.MapAttribute{
+				Description: "tags of the resource, including the default tags of the provider",
+				ElementType: types.StringType,
+				Computed:    true,
 			},
 			"created": schema.StringAttribute{
 				Description:   "the time when the network interface is created",
@@ -105,9 +124,14 @@ func (r *ResourceNetworkInterface) Schema(
 				Computed:    true,
 			},
 			"zone_id": schema.StringAttribute{
-				Description:   "id of zone that the network interface belongs to",
-				Computed:      true,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "id of zone that the network interface belongs to, " +
+					"which defaults to the zone of the provider",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"organization_id": schema.StringAttribute{
 				Description:   "id of organization that the network interface belongs to",
@@ -146,8 +170,8 @@ func (r *ResourceNetworkInterface) Schema(
 				Optional:    true,
 				Computed:    true,
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
 					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
 				},
 			},
 			"mac": schema.StringAttribute{
@@ -155,11 +179,18 @@ func (r *ResourceNetworkInterface) Schema(
 				Optional:    true,
 				Computed:    true,
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
 					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
 				},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
 	}
 }
 
@@ -170,18 +201,20 @@ func (r *ResourceNetworkInterface) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	data, ok := req.ProviderData.(*ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
-			fmt.Sprintf(`expected *api.APIClient, got: %T.`, req.ProviderData),
+			fmt.Sprintf(`expected *resource.ProviderData, got: %T.`, req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Client
+	r.tags = data.Tags
+	r.zoneId = data.ZoneId
 }
 
 func (r *ResourceNetworkInterface) Create(
@@ -196,6 +229,17 @@ func (r *ResourceNetworkInterface) Create(
 		return
 	}
 
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
 	var ipPtr *string = nil
 	if !plan.Ip.IsUnknown() {
 		ipPtr = plan.Ip.ValueStringPointer()
@@ -206,33 +250,72 @@ func (r *ResourceNetworkInterface) Create(
 		macPtr = plan.Mac.ValueStringPointer()
 	}
 
-	tagsPtr := map[string]string{}
-	resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tagsPtr, false)...)
+	tagsPtr, diags := r.tags.merge(ctx, plan.Tags)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	response, err := r.client.PostNetworkInterface(
-		plan.Name.ValueString(),
-		plan.AttachedSubnetId.ValueString(),
-		plan.DR.ValueBool(),
-		ipPtr,
-		macPtr,
-		tagsPtr,
-	)
+	zoneId, err := zoneIdOrDefault(ctx, plan.ZoneId, r.zoneId)
+	if err != nil {
+		addResourceError(&resp.Diagnostics, "failed to select the zone", "", err)
+		return
+	}
+
+	idempotencyKey := newIdempotencyKey()
+	startedAt := time.Now()
+
+	create := func() (string, error) {
+		response, err := r.client.PostNetworkInterface(
+			ctx,
+			idempotencyKey,
+			zoneId,
+			plan.Name.ValueString(),
+			plan.AttachedSubnetId.ValueString(),
+			plan.DR.ValueBool(),
+			ipPtr,
+			macPtr,
+			tagsPtr,
+		)
+		if err != nil {
+			return "", err
+		}
+		return response.Id.String(), nil
+	}
+
+	id, err := create()
+	if err != nil {
+		id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+			return findCreated(
+				r.client.GetNetworkInterfaces(ctx, nil),
+				startedAt,
+				func(
+					networkInterface api.ResourceNetworkInterfaceGetResponse,
+				) (uuid.UUID, time.Time, bool) {
+					return networkInterface.Id,
+						networkInterface.Created,
+						networkInterface.Status != "deleted" &&
+							networkInterface.Name == plan.Name.ValueString() &&
+							networkInterface.AttachedSubnetId.String() ==
+								plan.AttachedSubnetId.ValueString() &&
+							maps.Equal(networkInterface.Tags, tagsPtr)
+				},
+			)
+		}, create)
+	}
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to create network interface", "", err)
 		return
 	}
 
-	id := response.Id.String()
 	tflog.Info(ctx, fmt.Sprintf("successfully created a network interface: %s", id))
+	resp.Diagnostics.Append(setIdempotencyKey(ctx, resp.Private, idempotencyKey)...)
 
 	if !plan.AttachedMachineId.IsNull() {
 		attachedMachineIdPtr := plan.AttachedMachineId.ValueStringPointer()
 
-		vmResponse, err := r.client.GetVirtualMachine(*attachedMachineIdPtr)
+		vmResponse, err := r.client.GetVirtualMachine(ctx, *attachedMachineIdPtr)
 		if err != nil {
 			addResourceError(
 				&resp.Diagnostics,
@@ -255,7 +338,7 @@ func (r *ResourceNetworkInterface) Create(
 			return
 		}
 
-		_, err = r.client.PatchNetworkInterface(id, nil, &attachedMachineIdPtr, nil)
+		_, err = r.client.PatchNetworkInterface(ctx, id, nil, &attachedMachineIdPtr, nil)
 
 		if err != nil {
 			addResourceError(&resp.Diagnostics, "failed to patch network interface", id, err)
@@ -272,7 +355,7 @@ func (r *ResourceNetworkInterface) Create(
 		)
 	}
 
-	getResponse, err := r.client.GetNetworkInterface(id)
+	getResponse, err := r.client.GetNetworkInterface(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a network interface", id, err)
@@ -280,29 +363,34 @@ func (r *ResourceNetworkInterface) Create(
 	}
 
 	resp.Diagnostics.Append(
-		resourceNetworkInterfaceGetResponseToNetworkInterfaceModel(ctx, getResponse, &state)...,
+		resourceNetworkInterfaceGetResponseToNetworkInterfaceModel(
+			ctx, getResponse, &state, r.tags,
+		)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	state.Tags = plan.Tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 
 	if resp.Diagnostics.HasError() || getResponse.Status == "active" {
 		return
 	}
 
-	_, diags := waitStatus(
-		func() (*string, error) {
-			getResponse, err := r.client.GetNetworkInterface(id)
-			if err != nil {
-				return nil, err
-			}
-			return &getResponse.Status, nil
-		},
-		[]string{"active"},
-		10,
-	)
+	waiter := statusWaiter{
+		resource: "network interface",
+		target:   []string{"active"},
+		failure:  []string{"error", "deleted"},
+	}
+	_, diags = waiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetNetworkInterface(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
 	resp.Diagnostics.Append(diags...)
 }
 
@@ -311,6 +399,11 @@ func (r *ResourceNetworkInterface) Read(
 	req resource.ReadRequest,
 	resp *resource.ReadResponse,
 ) {
+	// Until the provider is configured, the state is kept as it is.
+	if r.client == nil {
+		return
+	}
+
 	var state ResourceNetworkInterfaceModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
@@ -319,20 +412,38 @@ func (r *ResourceNetworkInterface) Read(
 		return
 	}
 	id := state.Id.ValueString()
-	response, err := r.client.GetNetworkInterface(id)
+	response, err := r.client.GetNetworkInterface(ctx, id)
 
+	if errors.Is(err, api.ErrNotFound) {
+		removeDeletedResource(ctx, resp, id, "not found")
+		return
+	}
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a network interface", id, err)
 		return
 	}
 
+	if isDeletedStatus(response.Status) {
+		removeDeletedResource(ctx, resp, id, fmt.Sprintf("status %s", response.Status))
+		return
+	}
+
 	resp.Diagnostics.Append(
-		resourceNetworkInterfaceGetResponseToNetworkInterfaceModel(ctx, response, &state)...,
+		resourceNetworkInterfaceGetResponseToNetworkInterfaceModel(
+			ctx, response, &state, r.tags,
+		)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	tags, diags := r.tags.configuredTags(ctx, state.Tags, state.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	state.Tags = tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -349,6 +460,17 @@ func (r *ResourceNetworkInterface) Update(
 		return
 	}
 
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
 	id := state.Id.ValueString()
 
 	var namePtr *string = nil
@@ -361,7 +483,7 @@ func (r *ResourceNetworkInterface) Update(
 		if !state.AttachedMachineId.IsNull() && !plan.AttachedMachineId.IsNull() {
 			var nilMachineIdPtr *string = nil
 
-			_, err := r.client.PatchNetworkInterface(id, nil, &nilMachineIdPtr, nil)
+			_, err := r.client.PatchNetworkInterface(ctx, id, nil, &nilMachineIdPtr, nil)
 			if err != nil {
 				addResourceError(&resp.Diagnostics, "failed to patch a network interface", id, err)
 				return
@@ -378,17 +500,26 @@ func (r *ResourceNetworkInterface) Update(
 	}
 
 	var tagsPtr *map[string]string = nil
-	if !plan.Tags.Equal(state.Tags) {
-		tags := map[string]string{}
-		resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	if !plan.Tags.Equal(state.Tags) || !plan.TagsAll.Equal(state.TagsAll) {
+		tags, diags := r.tags.merge(ctx, plan.Tags)
+		resp.Diagnostics.Append(diags...)
 		if resp.Diagnostics.HasError() {
 			return
 		}
 
+		if r.tags.ignoresAny() {
+			current, err := r.client.GetNetworkInterface(ctx, id)
+			if err != nil {
+				addResourceError(&resp.Diagnostics, "failed to get a network interface", id, err)
+				return
+			}
+			r.tags.keepIgnored(tags, current.Tags)
+		}
+
 		tagsPtr = &tags
 	}
 
-	_, err := r.client.PatchNetworkInterface(id, namePtr, attachedMachineIdPtr, tagsPtr)
+	_, err := r.client.PatchNetworkInterface(ctx, id, namePtr, attachedMachineIdPtr, tagsPtr)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to patch a network interface", id, err)
@@ -396,7 +527,7 @@ func (r *ResourceNetworkInterface) Update(
 	}
 
 	tflog.Info(ctx, fmt.Sprintf("successfully patched a network interface: %s", id))
-	getResponse, err := r.client.GetNetworkInterface(id)
+	getResponse, err := r.client.GetNetworkInterface(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to patch network interface", id, err)
@@ -404,12 +535,16 @@ func (r *ResourceNetworkInterface) Update(
 	}
 
 	resp.Diagnostics.Append(
-		resourceNetworkInterfaceGetResponseToNetworkInterfaceModel(ctx, getResponse, &state)...,
+		resourceNetworkInterfaceGetResponseToNetworkInterfaceModel(
+			ctx, getResponse, &state, r.tags,
+		)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	state.Tags = plan.Tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -424,11 +559,20 @@ func (r *ResourceNetworkInterface) Delete(
 		return
 	}
 
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
 	id := state.Id.ValueString()
 
 	if !state.AttachedMachineId.IsNull() {
 		var attachedMachineIdPtr *string = nil
-		_, err := r.client.PatchNetworkInterface(id, nil, &attachedMachineIdPtr, nil)
+		_, err := r.client.PatchNetworkInterface(ctx, id, nil, &attachedMachineIdPtr, nil)
 
 		if err != nil {
 			addResourceError(&resp.Diagnostics, "failed to detach from a virtual machine", id, err)
@@ -436,7 +580,7 @@ func (r *ResourceNetworkInterface) Delete(
 		}
 	}
 
-	publicIps, err := r.client.GetPublicIps(&id)
+	publicIps, err := api.Collect(r.client.GetPublicIps(ctx, &id))
 	if err != nil {
 		addResourceError(
 			&resp.Diagnostics,
@@ -449,7 +593,9 @@ func (r *ResourceNetworkInterface) Delete(
 
 	var nilAttachedNetworkInterface *string = nil
 	for _, publicIp := range publicIps {
-		_, err = r.client.PatchPublicIp(publicIp.Id.String(), &nilAttachedNetworkInterface, nil)
+		_, err = r.client.PatchPublicIp(
+			ctx, publicIp.Id.String(), &nilAttachedNetworkInterface, nil,
+		)
 		if err != nil {
 			addResourceError(
 				&resp.Diagnostics,
@@ -460,7 +606,7 @@ func (r *ResourceNetworkInterface) Delete(
 		}
 	}
 
-	_, err = r.client.DeleteNetworkInterface(id)
+	_, err = r.client.DeleteNetworkInterface(ctx, id)
 	successMessage, err := isResourceDeleted(err, "resource_network_interface", "deleted")
 
 	if err != nil {
@@ -470,3 +616,15 @@ func (r *ResourceNetworkInterface) Delete(
 
 	tflog.Info(ctx, fmt.Sprintf("%s (network interface: %s)", successMessage, id))
 }
+
+func (r *ResourceNetworkInterface) ModifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	r.tags.modifyPlan(ctx, req, resp)
+}
+
+func (r *ResourceNetworkInterface) ImportState(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	importStateById(ctx, req, resp)
+}
@@ -2,10 +2,15 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"terraform-provider-eci/internal/api"
 	. "terraform-provider-eci/internal/utils"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -16,44 +21,51 @@ import (
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
-const maxRetry = 10
-
 type ResourcePublicIpModel struct {
-	Id                         types.String `tfsdk:"id"`
-	Tags                       types.Map    `tfsdk:"tags"`
-	Created                    types.String `tfsdk:"created"`
-	Modified                   types.String `tfsdk:"modified"`
-	ZoneId                     types.String `tfsdk:"zone_id"`
-	OrganizationId             types.String `tfsdk:"organization_id"`
-	DR                         types.Bool   `tfsdk:"dr"`
-	AttachedNetworkInterfaceId types.String `tfsdk:"attached_network_interface_id"`
-	PoolId                     types.String `tfsdk:"pool_id"`
-	DrPoolId                   types.String `tfsdk:"dr_pool_id"`
-	Ip                         types.String `tfsdk:"ip"`
-	DrIp                       types.String `tfsdk:"dr_ip"`
-	Deleted                    types.String `tfsdk:"deleted"`
-	Status                     types.String `tfsdk:"status"`
+	Id                         types.String   `tfsdk:"id"`
+	Tags                       types.Map      `tfsdk:"tags"`
+	TagsAll                    types.Map      `tfsdk:"tags_all"`
+	Created                    types.String   `tfsdk:"created"`
+	Modified                   types.String   `tfsdk:"modified"`
+	ZoneId                     types.String   `tfsdk:"zone_id"`
+	OrganizationId             types.String   `tfsdk:"organization_id"`
+	DR                         types.Bool     `tfsdk:"dr"`
+	AttachedNetworkInterfaceId types.String   `tfsdk:"attached_network_interface_id"`
+	PoolId                     types.String   `tfsdk:"pool_id"`
+	DrPoolId                   types.String   `tfsdk:"dr_pool_id"`
+	Ip                         types.String   `tfsdk:"ip"`
+	DrIp                       types.String   `tfsdk:"dr_ip"`
+	Deleted                    types.String   `tfsdk:"deleted"`
+	Status                     types.String   `tfsdk:"status"`
+	Timeouts                   timeouts.Value `tfsdk:"timeouts"`
 }
 
 var _ resource.Resource = &ResourcePublicIp{}
+var _ resource.ResourceWithImportState = &ResourcePublicIp{}
+var _ resource.ResourceWithModifyPlan = &ResourcePublicIp{}
 
 type ResourcePublicIp struct {
-	client *api.APIClient
+	client api.Client
+	tags   TagPolicy
+	zoneId func(ctx context.Context) (string, error)
 }
 
 func resourcePublicIpGetResponseToPublicIpModel(
 	ctx context.Context,
 	response *api.ResourcePublicIpGetResponse,
 	data *ResourcePublicIpModel,
+	tagPolicy TagPolicy,
 ) diag.Diagnostics {
 	data.Id = types.StringValue(response.Id.String())
-	tags, diags := types.MapValueFrom(ctx, types.StringType, response.Tags)
+	tags, diags := types.MapValueFrom(
+		ctx, types.StringType, tagPolicy.withoutIgnored(response.Tags),
+	)
 
 	if diags.HasError() {
 		return diags
 	}
 
-	data.Tags = tags
+	data.TagsAll = tags
 	data.Created = types.StringValue(response.Created.String())
 	data.Modified = StringOrNull(response.Modified)
 	data.ZoneId = types.StringValue(response.ZoneId.String())
@@ -97,7 +109,12 @@ func (r *ResourcePublicIp) Schema(
 			"tags": schema.MapAttribute{
 				Description: "User-defined metadata of key-value pairs",
 				ElementType: types.StringType,
-				Required:    true,
+				Optional:    true,
+			},
+			"tags_all": schema.MapAttribute{
+				Description: "tags of the resource, including the default tags of the provider",
+				ElementType: types.StringType,
+				Computed:    true,
 			},
 			"created": schema.StringAttribute{
 				Description:   "the time when the public ip is created",
@@ -111,11 +128,14 @@ func (r *ResourcePublicIp) Schema(
 				Computed:    true,
 			},
 			"zone_id": schema.StringAttribute{
-				Description:   "id of zone that the public ip belongs to",
-				Required:      false,
-				Computed:      true,
-				Optional:      false,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "id of zone that the public ip belongs to, " +
+					"which defaults to the zone of the provider",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"organization_id": schema.StringAttribute{
 				Description:   "id of organization that the public ip belongs to",
@@ -152,6 +172,13 @@ func (r *ResourcePublicIp) Schema(
 				Computed:    true,
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
 	}
 }
 
@@ -162,18 +189,20 @@ func (r *ResourcePublicIp) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	data, ok := req.ProviderData.(*ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
-			fmt.Sprintf(`expected *api.APIClient, got: %T.`, req.ProviderData),
+			fmt.Sprintf(`expected *resource.ProviderData, got: %T.`, req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Client
+	r.tags = data.Tags
+	r.zoneId = data.ZoneId
 }
 
 func (r *ResourcePublicIp) Create(
@@ -188,13 +217,56 @@ func (r *ResourcePublicIp) Create(
 		return
 	}
 
-	tags := map[string]string{}
-	resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
+	tags, diags := r.tags.merge(ctx, plan.Tags)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	response, err := r.client.PostPublicIp(plan.DR.ValueBool(), tags)
+	zoneId, err := zoneIdOrDefault(ctx, plan.ZoneId, r.zoneId)
+	if err != nil {
+		addResourceError(&resp.Diagnostics, "failed to select the zone", "", err)
+		return
+	}
+
+	idempotencyKey := newIdempotencyKey()
+	startedAt := time.Now()
+
+	create := func() (string, error) {
+		response, err := r.client.PostPublicIp(
+			ctx, idempotencyKey, zoneId, plan.DR.ValueBool(), tags,
+		)
+		if err != nil {
+			return "", err
+		}
+		return response.Id.String(), nil
+	}
+
+	id, err := create()
+	if err != nil {
+		id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+			return findCreated(
+				r.client.GetPublicIps(ctx, nil),
+				startedAt,
+				func(publicIp api.ResourcePublicIpGetResponse) (uuid.UUID, time.Time, bool) {
+					return publicIp.Id, publicIp.Created, publicIp.Status != "deleted" &&
+						publicIp.DR == plan.DR.ValueBool() &&
+						maps.Equal(publicIp.Tags, tags)
+				},
+			)
+		}, create)
+	}
 
 	if err != nil {
 		addResourceError(
@@ -206,12 +278,12 @@ func (r *ResourcePublicIp) Create(
 		return
 	}
 
-	id := response.Id.String()
 	tflog.Info(ctx, fmt.Sprintf("created a public ip: %s", id))
+	resp.Diagnostics.Append(setIdempotencyKey(ctx, resp.Private, idempotencyKey)...)
 
 	if !plan.AttachedNetworkInterfaceId.IsNull() {
 		attachedNetworkInterfaceIdPtr := plan.AttachedNetworkInterfaceId.ValueStringPointer()
-		_, err := r.client.PatchPublicIp(id, &attachedNetworkInterfaceIdPtr, nil)
+		_, err := r.client.PatchPublicIp(ctx, id, &attachedNetworkInterfaceIdPtr, nil)
 
 		if err != nil {
 			addResourceError(&resp.Diagnostics, "failed to patch public ip", id, err)
@@ -228,7 +300,7 @@ func (r *ResourcePublicIp) Create(
 		)
 	}
 
-	getResponse, err := r.client.GetPublicIp(id)
+	getResponse, err := r.client.GetPublicIp(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get public ip", id, err)
@@ -236,12 +308,14 @@ func (r *ResourcePublicIp) Create(
 	}
 
 	resp.Diagnostics.Append(
-		resourcePublicIpGetResponseToPublicIpModel(ctx, getResponse, &state)...,
+		resourcePublicIpGetResponseToPublicIpModel(ctx, getResponse, &state, r.tags)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	state.Tags = plan.Tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 
 	if resp.Diagnostics.HasError() {
@@ -253,23 +327,29 @@ func (r *ResourcePublicIp) Create(
 		return
 	}
 
-	_, diags := waitStatus(
-		func() (*string, error) {
-			getResponse, err := r.client.GetPublicIp(id)
-			if err != nil {
-				return nil, err
-			}
-			return &getResponse.Status, nil
-		},
-		[]string{"active"},
-		maxRetry,
-	)
+	waiter := statusWaiter{
+		resource: "public ip",
+		target:   []string{"active"},
+		failure:  []string{"error", "deleted"},
+	}
+	_, diags = waiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetPublicIp(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
 	resp.Diagnostics.Append(diags...)
 }
 
 func (r *ResourcePublicIp) Read(
 	ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse,
 ) {
+	// Until the provider is configured, the state is kept as it is.
+	if r.client == nil {
+		return
+	}
+
 	var state ResourcePublicIpModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
@@ -279,20 +359,36 @@ func (r *ResourcePublicIp) Read(
 	}
 
 	id := state.Id.ValueString()
-	response, err := r.client.GetPublicIp(id)
+	response, err := r.client.GetPublicIp(ctx, id)
 
+	if errors.Is(err, api.ErrNotFound) {
+		removeDeletedResource(ctx, resp, id, "not found")
+		return
+	}
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a public ip", id, err)
 		return
 	}
 
+	if isDeletedStatus(response.Status) {
+		removeDeletedResource(ctx, resp, id, fmt.Sprintf("status %s", response.Status))
+		return
+	}
+
 	resp.Diagnostics.Append(
-		resourcePublicIpGetResponseToPublicIpModel(ctx, response, &state)...,
+		resourcePublicIpGetResponseToPublicIpModel(ctx, response, &state, r.tags)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	tags, diags := r.tags.configuredTags(ctx, state.Tags, state.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	state.Tags = tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -309,33 +405,56 @@ func (r *ResourcePublicIp) Update(
 		return
 	}
 
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
 	id := state.Id.ValueString()
 
-	var attachedMachineIdPtr *string = nil
+	var attachedNetworkInterfaceIdPtr **string = nil
 	if !plan.AttachedNetworkInterfaceId.Equal(state.AttachedNetworkInterfaceId) {
 		if !state.AttachedNetworkInterfaceId.IsNull() && !plan.AttachedNetworkInterfaceId.IsNull() {
-			_, err := r.client.PatchPublicIp(id, &attachedMachineIdPtr, nil)
+			var nilNetworkInterfaceId *string = nil
+
+			_, err := r.client.PatchPublicIp(ctx, id, &nilNetworkInterfaceId, nil)
 			if err != nil {
 				addResourceError(&resp.Diagnostics, "failed to patch a public ip", id, err)
 				return
 			}
 		}
 
-		attachedMachineIdPtr = plan.AttachedNetworkInterfaceId.ValueStringPointer()
+		var attachedNetworkInterfaceId = plan.AttachedNetworkInterfaceId.ValueStringPointer()
+		attachedNetworkInterfaceIdPtr = &attachedNetworkInterfaceId
 	}
 
 	var tagsPtr *map[string]string = nil
-	if !plan.Tags.Equal(state.Tags) {
-		tags := map[string]string{}
-		resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	if !plan.Tags.Equal(state.Tags) || !plan.TagsAll.Equal(state.TagsAll) {
+		tags, diags := r.tags.merge(ctx, plan.Tags)
+		resp.Diagnostics.Append(diags...)
 		if resp.Diagnostics.HasError() {
 			return
 		}
 
+		if r.tags.ignoresAny() {
+			current, err := r.client.GetPublicIp(ctx, id)
+			if err != nil {
+				addResourceError(&resp.Diagnostics, "failed to get public ip", id, err)
+				return
+			}
+			r.tags.keepIgnored(tags, current.Tags)
+		}
+
 		tagsPtr = &tags
 	}
 
-	_, err := r.client.PatchPublicIp(id, &attachedMachineIdPtr, tagsPtr)
+	_, err := r.client.PatchPublicIp(ctx, id, attachedNetworkInterfaceIdPtr, tagsPtr)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to patch a public ip", id, err)
@@ -343,7 +462,7 @@ func (r *ResourcePublicIp) Update(
 	}
 	tflog.Info(ctx, fmt.Sprintf("successfully patched a public ip: %s", id))
 
-	getResponse, err := r.client.GetPublicIp(id)
+	getResponse, err := r.client.GetPublicIp(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a public ip", id, err)
@@ -351,12 +470,14 @@ func (r *ResourcePublicIp) Update(
 	}
 
 	resp.Diagnostics.Append(
-		resourcePublicIpGetResponseToPublicIpModel(ctx, getResponse, &state)...,
+		resourcePublicIpGetResponseToPublicIpModel(ctx, getResponse, &state, r.tags)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	state.Tags = plan.Tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -371,11 +492,20 @@ func (r *ResourcePublicIp) Delete(
 		return
 	}
 
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
 	id := state.Id.ValueString()
 
 	if !state.AttachedNetworkInterfaceId.IsNull() {
 		var attachedNetworkInterfaceId *string = nil
-		_, err := r.client.PatchPublicIp(id, &attachedNetworkInterfaceId, nil)
+		_, err := r.client.PatchPublicIp(ctx, id, &attachedNetworkInterfaceId, nil)
 
 		if err != nil {
 			addResourceError(
@@ -388,7 +518,7 @@ func (r *ResourcePublicIp) Delete(
 		}
 	}
 
-	_, err := r.client.DeletePublicIp(id)
+	_, err := r.client.DeletePublicIp(ctx, id)
 	successMessage, err := isResourceDeleted(err, "resource_public_ip", "deleted")
 
 	if err != nil {
@@ -398,3 +528,15 @@ func (r *ResourcePublicIp) Delete(
 
 	tflog.Info(ctx, fmt.Sprintf("%s (public ip: %s)", successMessage, id))
 }
+
+func (r *ResourcePublicIp) ModifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	r.tags.modifyPlan(ctx, req, resp)
+}
+
+func (r *ResourcePublicIp) ImportState(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	importStateById(ctx, req, resp)
+}
@@ -2,12 +2,15 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math"
+	"maps"
 	"terraform-provider-eci/internal/api"
 	. "terraform-provider-eci/internal/utils"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
@@ -18,38 +21,47 @@ import (
 )
 
 type ResourceSubnetModel struct {
-	Id                types.String `tfsdk:"id"`
-	Tags              types.Map    `tfsdk:"tags"`
-	Created           types.String `tfsdk:"created"`
-	Modified          types.String `tfsdk:"modified"`
-	ZoneId            types.String `tfsdk:"zone_id"`
-	OrganizationId    types.String `tfsdk:"organization_id"`
-	AttachedNetworkId types.String `tfsdk:"attached_network_id"`
-	Name              types.String `tfsdk:"name"`
-	Purpose           types.String `tfsdk:"purpose"`
-	NetworkGw         types.String `tfsdk:"network_gw"`
-	Activated         types.String `tfsdk:"activated"`
-	Deleted           types.String `tfsdk:"deleted"`
-	Status            types.String `tfsdk:"status"`
+	Id                types.String   `tfsdk:"id"`
+	Tags              types.Map      `tfsdk:"tags"`
+	TagsAll           types.Map      `tfsdk:"tags_all"`
+	Created           types.String   `tfsdk:"created"`
+	Modified          types.String   `tfsdk:"modified"`
+	ZoneId            types.String   `tfsdk:"zone_id"`
+	OrganizationId    types.String   `tfsdk:"organization_id"`
+	AttachedNetworkId types.String   `tfsdk:"attached_network_id"`
+	Name              types.String   `tfsdk:"name"`
+	Purpose           types.String   `tfsdk:"purpose"`
+	NetworkGw         types.String   `tfsdk:"network_gw"`
+	Activated         types.String   `tfsdk:"activated"`
+	Deleted           types.String   `tfsdk:"deleted"`
+	Status            types.String   `tfsdk:"status"`
+	Timeouts          timeouts.Value `tfsdk:"timeouts"`
 }
 
 var _ resource.Resource = &ResourceSubnet{}
+var _ resource.ResourceWithImportState = &ResourceSubnet{}
+var _ resource.ResourceWithModifyPlan = &ResourceSubnet{}
 
 type ResourceSubnet struct {
-	client *api.APIClient
+	client api.Client
+	tags   TagPolicy
+	zoneId func(ctx context.Context) (string, error)
 }
 
 func resourceSubnetGetResponseToSubnetModel(
 	ctx context.Context, response *api.ResourceSubnetGetResponse, data *ResourceSubnetModel,
+	tagPolicy TagPolicy,
 ) diag.Diagnostics {
 	data.Id = types.StringValue(response.Id.String())
-	tags, diags := types.MapValueFrom(ctx, types.StringType, response.Tags)
+	tags, diags := types.MapValueFrom(
+		ctx, types.StringType, tagPolicy.withoutIgnored(response.Tags),
+	)
 
 	if diags.HasError() {
 		return diags
 	}
 
-	data.Tags = tags
+	data.TagsAll = tags
 	data.Created = types.StringValue(response.Created.String())
 	data.Modified = StringOrNull(response.Modified)
 	data.ZoneId = types.StringValue(response.ZoneId.String())
@@ -95,7 +107,12 @@ func (r *ResourceSubnet) Schema(
 			"tags": schema.MapAttribute{
 				Description: "User-defined metadata of key-value pairs",
 				ElementType: types.StringType,
-				Required:    true,
+				Optional:    true,
+			},
+			"tags_all": schema.MapAttribute{
+				Description: "tags of the resource, including the default tags of the provider",
+				ElementType: types.StringType,
+				Computed:    true,
 			},
 			"created": schema.StringAttribute{
 				Description:   "the time when the subnet is created",
@@ -111,11 +128,14 @@ func (r *ResourceSubnet) Schema(
 				Optional:    false,
 			},
 			"zone_id": schema.StringAttribute{
-				Description:   "id of zone that the subnet belongs to",
-				Required:      false,
-				Computed:      true,
-				Optional:      false,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "id of zone that the subnet belongs to, " +
+					"which defaults to the zone of the provider",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"organization_id": schema.StringAttribute{
 				Description:   "id of zone that the organization belongs to",
@@ -152,6 +172,13 @@ func (r *ResourceSubnet) Schema(
 				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
 	}
 }
 
@@ -162,18 +189,20 @@ func (r *ResourceSubnet) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	data, ok := req.ProviderData.(*ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
-			fmt.Sprintf(`expected *api.APIClient, got: %T.`, req.ProviderData),
+			fmt.Sprintf(`expected *resource.ProviderData, got: %T.`, req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Client
+	r.tags = data.Tags
+	r.zoneId = data.ZoneId
 }
 
 func (r *ResourceSubnet) Create(
@@ -188,31 +217,76 @@ func (r *ResourceSubnet) Create(
 		return
 	}
 
-	tags := map[string]string{}
-	resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	response, err := r.client.PostSubnet(
-		plan.Name.ValueString(),
-		plan.AttachedNetworkId.ValueString(),
-		plan.Purpose.ValueString(),
-		plan.NetworkGw.ValueString(),
-		tags,
-	)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
+	tags, diags := r.tags.merge(ctx, plan.Tags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	zoneId, err := zoneIdOrDefault(ctx, plan.ZoneId, r.zoneId)
+	if err != nil {
+		addResourceError(&resp.Diagnostics, "failed to select the zone", "", err)
+		return
+	}
+
+	idempotencyKey := newIdempotencyKey()
+	startedAt := time.Now()
+
+	create := func() (string, error) {
+		response, err := r.client.PostSubnet(
+			ctx,
+			idempotencyKey,
+			zoneId,
+			plan.Name.ValueString(),
+			plan.AttachedNetworkId.ValueString(),
+			plan.Purpose.ValueString(),
+			plan.NetworkGw.ValueString(),
+			tags,
+		)
+		if err != nil {
+			return "", err
+		}
+		return response.Id.String(), nil
+	}
+
+	id, err := create()
+	if err != nil {
+		id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+			return findCreated(
+				r.client.GetSubnets(ctx, plan.AttachedNetworkId.ValueStringPointer()),
+				startedAt,
+				func(subnet api.ResourceSubnetGetResponse) (uuid.UUID, time.Time, bool) {
+					return subnet.Id, subnet.Created, subnet.Status != "deleted" &&
+						subnet.Name == plan.Name.ValueString() &&
+						subnet.NetworkGw == plan.NetworkGw.ValueString() &&
+						maps.Equal(subnet.Tags, tags)
+				},
+			)
+		}, create)
+	}
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to create subnet", "", err)
 		return
 	}
 
-	id := response.Id.String()
 	plan.Id = types.StringValue(id)
 
 	tflog.Info(ctx, fmt.Sprintf("successfully created a virtual subnet: %s", id))
+	resp.Diagnostics.Append(setIdempotencyKey(ctx, resp.Private, idempotencyKey)...)
 
-	getResponse, err := r.client.GetSubnet(id)
+	getResponse, err := r.client.GetSubnet(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get subnet", id, err)
@@ -220,19 +294,26 @@ func (r *ResourceSubnet) Create(
 	}
 
 	resp.Diagnostics.Append(
-		resourceSubnetGetResponseToSubnetModel(ctx, getResponse, &state)...,
+		resourceSubnetGetResponseToSubnetModel(ctx, getResponse, &state, r.tags)...,
 	)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	state.Tags = plan.Tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
 func (r *ResourceSubnet) Read(
 	ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse,
 ) {
+	// Until the provider is configured, the state is kept as it is.
+	if r.client == nil {
+		return
+	}
+
 	var state ResourceSubnetModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
@@ -242,21 +323,37 @@ func (r *ResourceSubnet) Read(
 	}
 
 	id := state.Id.ValueString()
-	response, err := r.client.GetSubnet(id)
+	response, err := r.client.GetSubnet(ctx, id)
 
+	if errors.Is(err, api.ErrNotFound) {
+		removeDeletedResource(ctx, resp, id, "not found")
+		return
+	}
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get subnet", id, err)
 		return
 	}
 
+	if isDeletedStatus(response.Status) {
+		removeDeletedResource(ctx, resp, id, fmt.Sprintf("status %s", response.Status))
+		return
+	}
+
 	resp.Diagnostics.Append(
-		resourceSubnetGetResponseToSubnetModel(ctx, response, &state)...,
+		resourceSubnetGetResponseToSubnetModel(ctx, response, &state, r.tags)...,
 	)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	tags, diags := r.tags.configuredTags(ctx, state.Tags, state.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	state.Tags = tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -273,6 +370,17 @@ func (r *ResourceSubnet) Update(
 		return
 	}
 
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
 	id := state.Id.ValueString()
 
 	var namePtr *string = nil
@@ -281,17 +389,26 @@ func (r *ResourceSubnet) Update(
 	}
 
 	var tagsPtr *map[string]string = nil
-	if !plan.Tags.Equal(state.Tags) {
-		tags := map[string]string{}
-		resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	if !plan.Tags.Equal(state.Tags) || !plan.TagsAll.Equal(state.TagsAll) {
+		tags, diags := r.tags.merge(ctx, plan.Tags)
+		resp.Diagnostics.Append(diags...)
 		if resp.Diagnostics.HasError() {
 			return
 		}
 
+		if r.tags.ignoresAny() {
+			current, err := r.client.GetSubnet(ctx, id)
+			if err != nil {
+				addResourceError(&resp.Diagnostics, "failed to get subnet", id, err)
+				return
+			}
+			r.tags.keepIgnored(tags, current.Tags)
+		}
+
 		tagsPtr = &tags
 	}
 
-	_, err := r.client.PatchSubnet(id, namePtr, tagsPtr)
+	_, err := r.client.PatchSubnet(ctx, id, namePtr, tagsPtr)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to patch a subnet", id, err)
@@ -299,7 +416,7 @@ func (r *ResourceSubnet) Update(
 	}
 	tflog.Info(ctx, fmt.Sprintf("successfully patched a subnet: %s", id))
 
-	getResponse, err := r.client.GetSubnet(id)
+	getResponse, err := r.client.GetSubnet(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a subnet", id, err)
@@ -307,13 +424,15 @@ func (r *ResourceSubnet) Update(
 	}
 
 	resp.Diagnostics.Append(
-		resourceSubnetGetResponseToSubnetModel(ctx, getResponse, &state)...,
+		resourceSubnetGetResponseToSubnetModel(ctx, getResponse, &state, r.tags)...,
 	)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	state.Tags = plan.Tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -327,19 +446,54 @@ func (r *ResourceSubnet) Delete(
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
 	id := state.Id.ValueString()
 
-	var err error
-	for retryIndex := 0; retryIndex < 10; retryIndex += 1 {
-		_, err = r.client.DeleteSubnet(id)
-		successMessage, err := isResourceDeleted(err, "resource_subnet", "deleted")
-		if err == nil {
-			tflog.Info(ctx, fmt.Sprintf("%s (subnet: %s)", successMessage, id))
-			return
+	// The subnet stays in use until detached network interfaces are released,
+	// so deleting it is retried until then, within the delete timeout.
+	var successMessage string
+	waiter := statusWaiter{
+		resource: "subnet",
+		pending:  []string{"in use"},
+		target:   []string{"deleted"},
+	}
+	_, diags = waiter.wait(ctx, id, func() (string, error) {
+		_, err := r.client.DeleteSubnet(ctx, id)
+		message, err := isResourceDeleted(err, "resource_subnet", "deleted")
+		if errors.Is(err, api.ErrConflict) {
+			return "in use", nil
+		}
+		if err != nil {
+			return "", err
 		}
 
-		time.Sleep(time.Duration(min(0.5+math.Pow(2, float64(retryIndex)), 10)) * time.Second)
+		successMessage = message
+		return "deleted", nil
+	})
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	addResourceError(&resp.Diagnostics, "failed to delete a subnet", id, err)
+	tflog.Info(ctx, fmt.Sprintf("%s (subnet: %s)", successMessage, id))
+}
+
+func (r *ResourceSubnet) ModifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	r.tags.modifyPlan(ctx, req, resp)
+}
+
+func (r *ResourceSubnet) ImportState(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	importStateById(ctx, req, resp)
 }
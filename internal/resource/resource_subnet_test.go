@@ -0,0 +1,98 @@
+package resource
+
+import (
+	"context"
+	"net/http"
+	"terraform-provider-eci/internal/api"
+	"terraform-provider-eci/internal/api/fake"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// newTestSubnet creates a subnet in a new virtual network of client.
+func newTestSubnet(t *testing.T, client *fake.Client) string {
+	t.Helper()
+	ctx := context.Background()
+
+	network, err := client.PostVirtualNetwork(
+		ctx, "", client.ZoneId.String(), "network", "10.0.0.0/16", nil,
+	)
+	check(t, err)
+	subnet, err := client.PostSubnet(
+		ctx, "", client.ZoneId.String(),
+		"subnet", network.Id.String(), "virtual_machine", "10.0.0.1/24", nil,
+	)
+	check(t, err)
+
+	return subnet.Id.String()
+}
+
+// deleteSubnet deletes the subnet of id, within deleteTimeout if set.
+func deleteSubnet(
+	t *testing.T, client api.Client, id string, deleteTimeout string,
+) resource.DeleteResponse {
+	t.Helper()
+
+	r := &ResourceSubnet{client: client}
+	request := resource.DeleteRequest{
+		State: newState(t, r, &ResourceSubnetModel{
+			Id:       types.StringValue(id),
+			Tags:     types.MapNull(types.StringType),
+			TagsAll:  types.MapNull(types.StringType),
+			Timeouts: newTimeouts("", "", deleteTimeout),
+		}),
+	}
+	response := resource.DeleteResponse{}
+
+	r.Delete(context.Background(), request, &response)
+
+	return response
+}
+
+func TestResourceSubnetDeleteWaitsWhileInUse(t *testing.T) {
+	client := fake.NewClient()
+	id := newTestSubnet(t, client)
+	client.FailNext(
+		"DeleteSubnet", api.NewAPIError(http.StatusConflict, "conflict", "subnet in use", nil),
+	)
+
+	response := deleteSubnet(t, client, id, "")
+
+	if response.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", response.Diagnostics)
+	}
+
+	subnet, err := client.GetSubnet(context.Background(), id)
+	check(t, err)
+	if subnet.Status != "deleted" {
+		t.Errorf("subnet status: got %s, want deleted", subnet.Status)
+	}
+}
+
+func TestResourceSubnetDeleteTimesOut(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+	id := newTestSubnet(t, client)
+
+	// The network interface keeps the subnet in use.
+	_, err := client.PostNetworkInterface(
+		ctx, "", client.ZoneId.String(), "nic", id, false, nil, nil, nil,
+	)
+	check(t, err)
+
+	startedAt := time.Now()
+	response := deleteSubnet(t, client, id, "100ms")
+
+	if elapsed := time.Since(startedAt); elapsed > 2*time.Second {
+		t.Errorf("returned %s after the delete timeout", elapsed)
+	}
+	if !response.Diagnostics.HasError() {
+		t.Fatalf("expected an error")
+	}
+	if summary := response.Diagnostics.Errors()[0].Summary(); summary != "operation timed out" {
+		t.Errorf("summary: got %q, want %q", summary, "operation timed out")
+	}
+}
@@ -2,24 +2,39 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"terraform-provider-eci/internal/api"
 	. "terraform-provider-eci/internal/utils"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 var _ resource.Resource = &ResourceVirtualMachine{}
+var _ resource.ResourceWithImportState = &ResourceVirtualMachine{}
+var _ resource.ResourceWithModifyPlan = &ResourceVirtualMachine{}
+var _ resource.ResourceWithValidateConfig = &ResourceVirtualMachine{}
 
 type ResourceVirtualMachine struct {
-	client *api.APIClient
+	client api.Client
+	tags   TagPolicy
+	zoneId func(ctx context.Context) (string, error)
 }
 
 func NewResourceVirtualMachine() resource.Resource {
@@ -29,6 +44,7 @@ func NewResourceVirtualMachine() resource.Resource {
 type ResourceVirtualMachineModel struct {
 	Id             types.String `tfsdk:"id"`
 	Tags           types.Map    `tfsdk:"tags"`
+	TagsAll        types.Map    `tfsdk:"tags_all"`
 	Created        types.String `tfsdk:"created"`
 	Modified       types.String `tfsdk:"modified"`
 	ZoneId         types.String `tfsdk:"zone_id"`
@@ -42,24 +58,34 @@ type ResourceVirtualMachineModel struct {
 	Password     types.String `tfsdk:"password"`
 	OnInitScript types.String `tfsdk:"on_init_script"`
 
-	Allocated types.String `tfsdk:"allocated"`
-	Deleted   types.String `tfsdk:"deleted"`
-	Status    types.String `tfsdk:"status"`
+	// PasswordWo is never planned nor stored, only configured.
+	PasswordWo        types.String `tfsdk:"password_wo"`
+	PasswordWoVersion types.Int64  `tfsdk:"password_wo_version"`
+
+	Allocated types.String   `tfsdk:"allocated"`
+	Deleted   types.String   `tfsdk:"deleted"`
+	Status    types.String   `tfsdk:"status"`
+	Timeouts  timeouts.Value `tfsdk:"timeouts"`
+
+	PowerState types.String `tfsdk:"power_state"`
 }
 
 func resourceVirtualMachineGetResponseToVirtualMachineModel(
 	ctx context.Context,
 	response *api.ResourceVirtualMachineGetResponse,
 	data *ResourceVirtualMachineModel,
+	tagPolicy TagPolicy,
 ) diag.Diagnostics {
 	data.Id = types.StringValue(response.Id.String())
-	tags, diags := types.MapValueFrom(ctx, types.StringType, response.Tags)
+	tags, diags := types.MapValueFrom(
+		ctx, types.StringType, tagPolicy.withoutIgnored(response.Tags),
+	)
 
 	if diags.HasError() {
 		return diags
 	}
 
-	data.Tags = tags
+	data.TagsAll = tags
 	data.Name = types.StringValue(response.Name)
 	data.Created = types.StringValue(response.Created.String())
 	data.Modified = StringOrNull(response.Modified)
@@ -100,7 +126,12 @@ func (r *ResourceVirtualMachine) Schema(
 			"tags": schema.MapAttribute{
 				Description: "User-defined metadata of key-value pairs",
 				ElementType: types.StringType,
-				Required:    true,
+				Optional:    true,
+			},
+			"tags_all": schema.MapAttribute{
+				Description: "tags of the resource, including the default tags of the provider",
+				ElementType: types.StringType,
+				Computed:    true,
 			},
 			"created": schema.StringAttribute{
 				Description:   "time when the virtual machine is created",
@@ -112,9 +143,14 @@ func (r *ResourceVirtualMachine) Schema(
 				Computed:    true,
 			},
 			"zone_id": schema.StringAttribute{
-				Description:   "id of zone that the virtual machine belongs to",
-				Computed:      true,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "id of zone that the virtual machine belongs to, " +
+					"which defaults to the zone of the provider",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"organization_id": schema.StringAttribute{
 				Description:   "id of organization that the virtual machine belongs to",
@@ -144,6 +180,18 @@ func (r *ResourceVirtualMachine) Schema(
 				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
 			},
 			"status": schema.StringAttribute{Computed: true, Required: false, Optional: false},
+			"power_state": schema.StringAttribute{
+				Description: "whether the virtual machine is `running` or `stopped`. When set, " +
+					"the virtual machine is started by allocating it and stopped by terminating " +
+					"its allocation, so it must not be combined with " +
+					"eci_virtual_machine_allocation. Otherwise it is only read",
+				Optional: true,
+				Computed: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(powerStateRunning, powerStateStopped),
+				},
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
 			"name": schema.StringAttribute{
 				Description: "human-readable name of the virtual machine",
 				Required:    true,
@@ -154,10 +202,45 @@ func (r *ResourceVirtualMachine) Schema(
 				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
 			},
 			"password": schema.StringAttribute{
-				Description:   "password of first user that the virtual machine will generate",
-				Required:      true,
-				Sensitive:     true,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description: "password of first user that the virtual machine will generate. " +
+					"The API never returns it, so an imported virtual machine adopts the " +
+					"configured password instead of being replaced. It is stored in the state, " +
+					"unlike password_wo",
+				Optional:  true,
+				Sensitive: true,
+				Validators: []validator.String{
+					stringvalidator.ExactlyOneOf(path.MatchRoot("password_wo")),
+				},
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplaceIf(
+						requiresReplaceUnlessImported,
+						"changing the password replaces the virtual machine, unless it was imported",
+						"changing the password replaces the virtual machine, unless it was imported",
+					),
+				},
+			},
+			"password_wo": schema.StringAttribute{
+				Description: "password of first user that the virtual machine will generate, " +
+					"which is never stored in the state. Requires Terraform 1.11 or later. " +
+					"Changing it takes effect only along with password_wo_version",
+				Optional:  true,
+				Sensitive: true,
+				WriteOnly: true,
+			},
+			"password_wo_version": schema.Int64Attribute{
+				Description: "version of password_wo. Changing it replaces the virtual machine " +
+					"with one of the new password, unless no version was set before",
+				Optional: true,
+				Validators: []validator.Int64{
+					int64validator.AlsoRequires(path.MatchRoot("password_wo")),
+				},
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplaceIf(
+						requiresReplaceIfVersionChanged,
+						"changing the version of the password replaces the virtual machine",
+						"changing the version of the password replaces the virtual machine",
+					),
+				},
 			},
 			"on_init_script": schema.StringAttribute{
 				Description:   "script to run on the first boot of the virtual machine",
@@ -165,7 +248,47 @@ func (r *ResourceVirtualMachine) Schema(
 				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
+	}
+}
+
+// requiresReplaceUnlessImported replaces a virtual machine whose password
+// changes, except when the previous password is unknown: the API never
+// returns it, so it is null right after an import. Moving to password_wo,
+// which makes the password null, does not replace it either.
+func requiresReplaceUnlessImported(
+	ctx context.Context,
+	req planmodifier.StringRequest,
+	resp *stringplanmodifier.RequiresReplaceIfFuncResponse,
+) {
+	resp.RequiresReplace = !req.StateValue.IsNull() && !req.PlanValue.IsNull()
+}
+
+// requiresReplaceIfVersionChanged replaces a virtual machine whose
+// password_wo_version changes from a previous version. Setting it for the
+// first time, e.g. after an import or when moving from password, keeps the
+// virtual machine with the password it has.
+func requiresReplaceIfVersionChanged(
+	ctx context.Context,
+	req planmodifier.Int64Request,
+	resp *int64planmodifier.RequiresReplaceIfFuncResponse,
+) {
+	resp.RequiresReplace = !req.StateValue.IsNull()
+}
+
+// passwordOf returns the password that a virtual machine is created with.
+func passwordOf(plan ResourceVirtualMachineModel) string {
+	if !plan.PasswordWo.IsNull() {
+		return plan.PasswordWo.ValueString()
 	}
+
+	return plan.Password.ValueString()
 }
 
 func (r *ResourceVirtualMachine) Configure(
@@ -175,18 +298,20 @@ func (r *ResourceVirtualMachine) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	data, ok := req.ProviderData.(*ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
-			fmt.Sprintf(`expected *api.APIClient, got: %T.`, req.ProviderData),
+			fmt.Sprintf(`expected *resource.ProviderData, got: %T.`, req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Client
+	r.tags = data.Tags
+	r.zoneId = data.ZoneId
 }
 
 func (r *ResourceVirtualMachine) Create(
@@ -197,50 +322,151 @@ func (r *ResourceVirtualMachine) Create(
 
 	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 
+	resp.Diagnostics.Append(
+		req.Config.GetAttribute(ctx, path.Root("password_wo"), &plan.PasswordWo)...,
+	)
+
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	tags := map[string]string{}
-	resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	response, err := r.client.PostVirtualMachine(
-		plan.InstanceTypeId.ValueString(),
-		plan.Name.ValueString(),
-		plan.AlwaysOn.ValueBool(),
-		plan.DR.ValueBool(),
-		plan.Username.ValueString(),
-		plan.Password.ValueString(),
-		plan.OnInitScript.ValueString(),
-		tags,
-	)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
+	tags, diags := r.tags.merge(ctx, plan.Tags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	zoneId, err := zoneIdOrDefault(ctx, plan.ZoneId, r.zoneId)
+	if err != nil {
+		addResourceError(&resp.Diagnostics, "failed to select the zone", "", err)
+		return
+	}
+
+	idempotencyKey := newIdempotencyKey()
+	startedAt := time.Now()
+
+	create := func() (string, error) {
+		response, err := r.client.PostVirtualMachine(
+			ctx,
+			idempotencyKey,
+			zoneId,
+			plan.InstanceTypeId.ValueString(),
+			plan.Name.ValueString(),
+			plan.AlwaysOn.ValueBool(),
+			plan.DR.ValueBool(),
+			plan.Username.ValueString(),
+			passwordOf(plan),
+			plan.OnInitScript.ValueString(),
+			tags,
+		)
+		if err != nil {
+			return "", err
+		}
+		return response.Id.String(), nil
+	}
+
+	id, err := create()
+	if err != nil {
+		id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+			return findCreated(
+				r.client.GetVirtualMachines(ctx, plan.Name.ValueStringPointer()),
+				startedAt,
+				func(machine api.ResourceVirtualMachineGetResponse) (uuid.UUID, time.Time, bool) {
+					return machine.Id, machine.Created, machine.Status != "deleted" &&
+						machine.Name == plan.Name.ValueString() &&
+						maps.Equal(machine.Tags, tags)
+				},
+			)
+		}, create)
+	}
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to create a virtual machine", "", err)
 		return
 	}
 
-	id := response.Id.String()
 	tflog.Trace(ctx, fmt.Sprintf("successfully created a virtual machine: %s", id))
+	resp.Diagnostics.Append(setIdempotencyKey(ctx, resp.Private, idempotencyKey)...)
 
-	getResponse, err := r.client.GetVirtualMachine(id)
+	resp.Diagnostics.Append(r.refresh(ctx, id, plan, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	if err != nil {
-		addResourceError(&resp.Diagnostics, "failed to get a virtual machine", id, err)
+	// The virtual machine is saved before it is started, so that one that
+	// fails to start in time is replaced rather than left untracked.
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+	if resp.Diagnostics.HasError() || plan.PowerState.ValueString() != powerStateRunning {
+		return
+	}
+
+	resp.Diagnostics.Append(r.startVirtualMachine(ctx, id, zoneId)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.refresh(ctx, id, plan, &state)...)
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	resourceVirtualMachineGetResponseToVirtualMachineModel(ctx, getResponse, &state)
-	state.Password = plan.Password
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
+// refresh sets state to the virtual machine of id as it is after applying
+// plan.
+func (r *ResourceVirtualMachine) refresh(
+	ctx context.Context, id string, plan ResourceVirtualMachineModel,
+	state *ResourceVirtualMachineModel,
+) diag.Diagnostics {
+	diags := diag.Diagnostics{}
+
+	getResponse, err := r.client.GetVirtualMachine(ctx, id)
+
+	if err != nil {
+		addResourceError(&diags, "failed to get a virtual machine", id, err)
+		return diags
+	}
+
+	diags.Append(
+		resourceVirtualMachineGetResponseToVirtualMachineModel(ctx, getResponse, state, r.tags)...,
+	)
+	if diags.HasError() {
+		return diags
+	}
+
+	state.PowerState, err = observePowerState(ctx, r.client, id)
+	if err != nil {
+		addResourceError(&diags, "failed to get allocations of a virtual machine", id, err)
+		return diags
+	}
+
+	state.Password = plan.Password
+	state.PasswordWoVersion = plan.PasswordWoVersion
+	state.Tags = plan.Tags
+
+	return diags
+}
+
 func (r *ResourceVirtualMachine) Read(
 	ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse,
 ) {
+	// Until the provider is configured, the state is kept as it is.
+	if r.client == nil {
+		return
+	}
+
 	var state ResourceVirtualMachineModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
@@ -250,20 +476,44 @@ func (r *ResourceVirtualMachine) Read(
 	}
 
 	id := state.Id.ValueString()
-	response, err := r.client.GetVirtualMachine(id)
+	response, err := r.client.GetVirtualMachine(ctx, id)
 
+	if errors.Is(err, api.ErrNotFound) {
+		removeDeletedResource(ctx, resp, id, "not found")
+		return
+	}
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a virtual machine", id, err)
 		return
 	}
 
+	if isDeletedStatus(response.Status) {
+		removeDeletedResource(ctx, resp, id, fmt.Sprintf("status %s", response.Status))
+		return
+	}
+
 	resp.Diagnostics.Append(
-		resourceVirtualMachineGetResponseToVirtualMachineModel(ctx, response, &state)...,
+		resourceVirtualMachineGetResponseToVirtualMachineModel(ctx, response, &state, r.tags)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	tags, diags := r.tags.configuredTags(ctx, state.Tags, state.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	state.Tags = tags
+
+	state.PowerState, err = observePowerState(ctx, r.client, id)
+	if err != nil {
+		addResourceError(
+			&resp.Diagnostics, "failed to get allocations of a virtual machine", id, err,
+		)
+		return
+	}
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -280,6 +530,32 @@ func (r *ResourceVirtualMachine) Update(
 		return
 	}
 
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+	id := state.Id.ValueString()
+
+	// power_state is planned as it was when it is not set, so it is left as it is.
+	powerState := plan.PowerState.ValueString()
+	if plan.PowerState.Equal(state.PowerState) {
+		powerState = ""
+	}
+
+	// The virtual machine is stopped before, and started after, it is patched.
+	if powerState == powerStateStopped {
+		resp.Diagnostics.Append(r.stopVirtualMachine(ctx, id)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
 	var namePtr *string = nil
 	if !plan.Name.Equal(state.Name) {
 		namePtr = plan.Name.ValueStringPointer()
@@ -296,18 +572,27 @@ func (r *ResourceVirtualMachine) Update(
 	}
 
 	var tagsPtr *map[string]string = nil
-	if !plan.Tags.Equal(state.Tags) {
-		tags := map[string]string{}
-		resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	if !plan.Tags.Equal(state.Tags) || !plan.TagsAll.Equal(state.TagsAll) {
+		tags, diags := r.tags.merge(ctx, plan.Tags)
+		resp.Diagnostics.Append(diags...)
 		if resp.Diagnostics.HasError() {
 			return
 		}
 
+		if r.tags.ignoresAny() {
+			current, err := r.client.GetVirtualMachine(ctx, id)
+			if err != nil {
+				addResourceError(&resp.Diagnostics, "failed to get a virtual machine", id, err)
+				return
+			}
+			r.tags.keepIgnored(tags, current.Tags)
+		}
+
 		tagsPtr = &tags
 	}
 
-	id := state.Id.ValueString()
 	_, err := r.client.PatchVirtualMachine(
+		ctx,
 		id, instanceTypeIdPtr, namePtr, alwaysOnPtr, tagsPtr,
 	)
 
@@ -318,21 +603,28 @@ func (r *ResourceVirtualMachine) Update(
 
 	tflog.Info(ctx, fmt.Sprintf("successfully patched a virtual machine: %s", id))
 
-	getResponse, err := r.client.GetVirtualMachine(state.Id.ValueString())
+	resp.Diagnostics.Append(r.refresh(ctx, id, plan, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
-	if err != nil {
-		addResourceError(&resp.Diagnostics, "failed to get a virtual machine", id, err)
+	// The patched virtual machine is saved before it is started, so that the
+	// changes are kept when it fails to start in time.
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+	if resp.Diagnostics.HasError() || powerState != powerStateRunning {
 		return
 	}
 
-	resp.Diagnostics.Append(
-		resourceVirtualMachineGetResponseToVirtualMachineModel(ctx, getResponse, &state)...,
-	)
+	resp.Diagnostics.Append(r.startVirtualMachine(ctx, id, state.ZoneId.ValueString())...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(r.refresh(ctx, id, plan, &state)...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	state.Password = plan.Password
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -347,9 +639,18 @@ func (r *ResourceVirtualMachine) Delete(
 		return
 	}
 
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
 	id := state.Id.ValueString()
 
-	storages, err := r.client.GetBlockStorages(&id)
+	storages, err := api.Collect(r.client.GetBlockStorages(ctx, &id, nil))
 	if err != nil {
 		addResourceError(
 			&resp.Diagnostics,
@@ -362,7 +663,9 @@ func (r *ResourceVirtualMachine) Delete(
 
 	var attachedMachineIdPtr *string = nil
 	for _, storage := range storages {
-		_, err = r.client.PatchBlockStorage(storage.Id.String(), nil, &attachedMachineIdPtr, nil)
+		_, err = r.client.PatchBlockStorage(
+			ctx, storage.Id.String(), nil, &attachedMachineIdPtr, nil,
+		)
 		if err != nil {
 			addResourceError(
 				&resp.Diagnostics,
@@ -373,7 +676,7 @@ func (r *ResourceVirtualMachine) Delete(
 		}
 	}
 
-	networkInterfaces, err := r.client.GetNetworkInterfaces(&id)
+	networkInterfaces, err := api.Collect(r.client.GetNetworkInterfaces(ctx, &id))
 	if err != nil {
 		addResourceError(
 			&resp.Diagnostics,
@@ -386,6 +689,7 @@ func (r *ResourceVirtualMachine) Delete(
 
 	for _, networkInterface := range networkInterfaces {
 		_, err = r.client.PatchNetworkInterface(
+			ctx,
 			networkInterface.Id.String(),
 			nil,
 			&attachedMachineIdPtr,
@@ -404,7 +708,7 @@ func (r *ResourceVirtualMachine) Delete(
 
 	if state.AlwaysOn.ValueBool() {
 		var falsePtr = false
-		_, err = r.client.PatchVirtualMachine(id, nil, nil, &falsePtr, nil)
+		_, err = r.client.PatchVirtualMachine(ctx, id, nil, nil, &falsePtr, nil)
 		if err != nil {
 			addResourceError(
 				&resp.Diagnostics,
@@ -415,7 +719,8 @@ func (r *ResourceVirtualMachine) Delete(
 		}
 	}
 
-	allocations, err := r.client.GetVirtualMachineAllocations(&id, nil)
+	// Terminated allocations are listed too, so only the active one is terminated.
+	allocation, err := activeAllocation(ctx, r.client, id)
 
 	if err != nil {
 		addResourceError(
@@ -426,9 +731,8 @@ func (r *ResourceVirtualMachine) Delete(
 		)
 	}
 
-	if len(allocations) > 0 {
-		allocation := allocations[0]
-		_, err = r.client.DeleteVirtualMachineAllocation(allocation.Id.String())
+	if allocation != nil {
+		_, err = r.client.DeleteVirtualMachineAllocation(ctx, allocation.Id.String())
 		successMessage, err := isResourceDeleted(err, "resource_allocation", "terminated")
 
 		if err != nil {
@@ -460,17 +764,18 @@ func (r *ResourceVirtualMachine) Delete(
 		),
 	)
 
-	status, diags := waitStatus(
-		func() (*string, error) {
-			getResponse, err := r.client.GetVirtualMachine(id)
-			if err != nil {
-				return nil, err
-			}
-			return &getResponse.Status, nil
-		},
-		[]string{"deleted", "idle"},
-		10,
-	)
+	waiter := statusWaiter{
+		resource:       "virtual machine",
+		target:         []string{"deleted", "idle"},
+		targetNotFound: true,
+	}
+	status, diags := waiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetVirtualMachine(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
 
 	resp.Diagnostics.Append(diags...)
 
@@ -478,7 +783,7 @@ func (r *ResourceVirtualMachine) Delete(
 		return
 	}
 
-	if *status == "deleted" {
+	if status == "deleted" {
 		tflog.Info(
 			ctx,
 			fmt.Sprintf("a virtual machine is already deleted (virtual machine: %s)", id),
@@ -486,7 +791,7 @@ func (r *ResourceVirtualMachine) Delete(
 		return
 	}
 
-	_, err = r.client.DeleteVirtualMachine(id)
+	_, err = r.client.DeleteVirtualMachine(ctx, id)
 	successMessage, err := isResourceDeleted(err, "resource_virtual_machine", "deleted")
 
 	if err != nil {
@@ -496,3 +801,34 @@ func (r *ResourceVirtualMachine) Delete(
 
 	tflog.Info(ctx, fmt.Sprintf("%s (virtual machine: %s)", successMessage, id))
 }
+
+func (r *ResourceVirtualMachine) ModifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	r.tags.modifyPlan(ctx, req, resp)
+}
+
+func (r *ResourceVirtualMachine) ValidateConfig(
+	ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse,
+) {
+	var alwaysOn types.Bool
+	var powerState types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("always_on"), &alwaysOn)...)
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("power_state"), &powerState)...)
+
+	// Like its allocation, the power state of an always-on virtual machine is
+	// not managed by Terraform.
+	if alwaysOn.ValueBool() && !powerState.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("power_state"),
+			"Virtual machine has invalid configuration",
+			"power_state cannot be set for a virtual machine with `always_on` enabled",
+		)
+	}
+}
+
+func (r *ResourceVirtualMachine) ImportState(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	importStateById(ctx, req, resp)
+}
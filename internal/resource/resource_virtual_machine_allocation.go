@@ -2,26 +2,36 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"terraform-provider-eci/internal/api"
 	. "terraform-provider-eci/internal/utils"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
-	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 var _ resource.Resource = &ResourceVirtualMachineAllocation{}
+var _ resource.ResourceWithImportState = &ResourceVirtualMachineAllocation{}
+var _ resource.ResourceWithModifyPlan = &ResourceVirtualMachineAllocation{}
 
 type ResourceVirtualMachineAllocation struct {
-	client *api.APIClient
+	client api.Client
+	tags   TagPolicy
+	zoneId func(ctx context.Context) (string, error)
 }
 
 func NewResourceVirtualMachineAllocation() resource.Resource {
@@ -29,40 +39,46 @@ func NewResourceVirtualMachineAllocation() resource.Resource {
 }
 
 type ResourceVirtualMachineAllocationModel struct {
-	Id   types.String `tfsdk:"id"`
-	Tags types.Map    `tfsdk:"tags"`
+	Id      types.String `tfsdk:"id"`
+	Tags    types.Map    `tfsdk:"tags"`
+	TagsAll types.Map    `tfsdk:"tags_all"`
 
 	Created        types.String `tfsdk:"created"`
 	Modified       types.String `tfsdk:"modified"`
 	ZoneId         types.String `tfsdk:"zone_id"`
 	OrganizationId types.String `tfsdk:"organization_id"`
 
-	MachineId          types.String `tfsdk:"machine_id"`
-	RequestedCpuVcore  types.Int64  `tfsdk:"requested_cpu_vcore"`
-	RequestedMemoryGib types.Int64  `tfsdk:"requested_memory_gib"`
-	RequestedDevices   types.List   `tfsdk:"requested_devices"`
-	LastHeartbeat      types.String `tfsdk:"last_heartbeat"`
-	Assigned           types.String `tfsdk:"assigned"`
-	Taken              types.String `tfsdk:"taken"`
-	Started            types.String `tfsdk:"started"`
-	Terminating        types.String `tfsdk:"terminating"`
-	Terminated         types.String `tfsdk:"terminated"`
-	Status             types.String `tfsdk:"status"`
+	MachineId          types.String   `tfsdk:"machine_id"`
+	RequestedCpuVcore  types.Int64    `tfsdk:"requested_cpu_vcore"`
+	RequestedMemoryGib types.Int64    `tfsdk:"requested_memory_gib"`
+	RequestedDevices   types.List     `tfsdk:"requested_devices"`
+	LastHeartbeat      types.String   `tfsdk:"last_heartbeat"`
+	Assigned           types.String   `tfsdk:"assigned"`
+	Taken              types.String   `tfsdk:"taken"`
+	Started            types.String   `tfsdk:"started"`
+	Terminating        types.String   `tfsdk:"terminating"`
+	Terminated         types.String   `tfsdk:"terminated"`
+	Status             types.String   `tfsdk:"status"`
+	WaitFor            types.String   `tfsdk:"wait_for"`
+	Timeouts           timeouts.Value `tfsdk:"timeouts"`
 }
 
 func resourceVirtualMachineAllocationGetResponseToVirtualMachineAllocationModel(
 	ctx context.Context,
 	response *api.ResourceVirtualMachineAllocationGetResponse,
 	data *ResourceVirtualMachineAllocationModel,
+	tagPolicy TagPolicy,
 ) diag.Diagnostics {
 	data.Id = types.StringValue(response.Id.String())
-	tags, diags := types.MapValueFrom(ctx, types.StringType, response.Tags)
+	tags, diags := types.MapValueFrom(
+		ctx, types.StringType, tagPolicy.withoutIgnored(response.Tags),
+	)
 
 	if diags.HasError() {
 		return diags
 	}
 
-	data.Tags = tags
+	data.TagsAll = tags
 	data.Created = types.StringValue(response.Created.String())
 	data.Modified = StringOrNull(response.Modified)
 	data.ZoneId = types.StringValue(response.ZoneId.String())
@@ -108,10 +124,14 @@ func (r *ResourceVirtualMachineAllocation) Schema(
 				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
 			},
 			"tags": schema.MapAttribute{
-				Description:   "User-defined metadata of key-value pairs",
-				ElementType:   types.StringType,
-				Required:      true,
-				PlanModifiers: []planmodifier.Map{mapplanmodifier.RequiresReplace()},
+				Description: "User-defined metadata of key-value pairs",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"tags_all": schema.MapAttribute{
+				Description: "tags of the resource, including the default tags of the provider",
+				ElementType: types.StringType,
+				Computed:    true,
 			},
 			"created": schema.StringAttribute{
 				Description:   "time when the virtual machine allocation is created",
@@ -123,9 +143,14 @@ func (r *ResourceVirtualMachineAllocation) Schema(
 				Computed:    true,
 			},
 			"zone_id": schema.StringAttribute{
-				Description:   "id of zone that the virtual machine allocation belongs to",
-				Computed:      true,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "id of zone that the virtual machine allocation belongs to, " +
+					"which defaults to the zone of the provider",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"organization_id": schema.StringAttribute{
 				Description:   "id of zone that the organization allocation belongs to",
@@ -153,10 +178,10 @@ func (r *ResourceVirtualMachineAllocation) Schema(
 				Computed:      true,
 				PlanModifiers: []planmodifier.List{listplanmodifier.UseStateForUnknown()},
 			},
+			// Heartbeats keep coming, so the last one is unknown until updated.
 			"last_heartbeat": schema.StringAttribute{
-				Description:   "last time when a heartbeat from the virtual machine allocation is received",
-				Computed:      true,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "last time when a heartbeat from the virtual machine allocation is received",
+				Computed:    true,
 			},
 			"assigned": schema.StringAttribute{
 				Description:   "the time when the virtual machine allocation is assigned to a host machine",
@@ -187,6 +212,23 @@ func (r *ResourceVirtualMachineAllocation) Schema(
 				Description: "status of the virtual machine allocation",
 				Computed:    true,
 			},
+			"wait_for": schema.StringAttribute{
+				Description: "what to wait for when creating the virtual machine allocation: " +
+					"`assigned` to a host machine, `started`, or `heartbeat`, i.e. started with " +
+					"a heartbeat received in the last 2 minutes, so that the guest is up. " +
+					"By default, creating does not wait",
+				Optional: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(waitForAssigned, waitForStarted, waitForHeartbeat),
+				},
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
 		},
 	}
 }
@@ -198,18 +240,20 @@ func (r *ResourceVirtualMachineAllocation) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	data, ok := req.ProviderData.(*ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
-			fmt.Sprintf(`expected *api.APIClient, got: %T.`, req.ProviderData),
+			fmt.Sprintf(`expected *resource.ProviderData, got: %T.`, req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Client
+	r.tags = data.Tags
+	r.zoneId = data.ZoneId
 }
 
 func (r *ResourceVirtualMachineAllocation) Create(
@@ -224,8 +268,19 @@ func (r *ResourceVirtualMachineAllocation) Create(
 		return
 	}
 
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
 	machineId := plan.MachineId.ValueString()
-	machine, err := r.client.GetVirtualMachine(machineId)
+	machine, err := r.client.GetVirtualMachine(ctx, machineId)
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get virtual machine", machineId, err)
 		return
@@ -239,13 +294,47 @@ func (r *ResourceVirtualMachineAllocation) Create(
 		return
 	}
 
-	tagsPtr := map[string]string{}
-	resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tagsPtr, false)...)
+	tagsPtr, diags := r.tags.merge(ctx, plan.Tags)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	response, err := r.client.PostVirtualMachineAllocation(machineId, tagsPtr)
+	zoneId, err := zoneIdOrDefault(ctx, plan.ZoneId, r.zoneId)
+	if err != nil {
+		addResourceError(&resp.Diagnostics, "failed to select the zone", "", err)
+		return
+	}
+
+	idempotencyKey := newIdempotencyKey()
+	startedAt := time.Now()
+
+	create := func() (string, error) {
+		response, err := r.client.PostVirtualMachineAllocation(
+			ctx, idempotencyKey, zoneId, machineId, tagsPtr,
+		)
+		if err != nil {
+			return "", err
+		}
+		return response.Id.String(), nil
+	}
+
+	id, err := create()
+	if err != nil {
+		id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+			return findCreated(
+				r.client.GetVirtualMachineAllocations(ctx, &machineId, nil),
+				startedAt,
+				func(
+					allocation api.ResourceVirtualMachineAllocationGetResponse,
+				) (uuid.UUID, time.Time, bool) {
+					return allocation.Id, allocation.Created, allocation.Terminated == nil &&
+						allocation.Terminating == nil &&
+						maps.Equal(allocation.Tags, tagsPtr)
+				},
+			)
+		}, create)
+	}
 
 	if err != nil {
 		addResourceError(
@@ -254,10 +343,10 @@ func (r *ResourceVirtualMachineAllocation) Create(
 		return
 	}
 
-	id := response.Id.String()
 	tflog.Trace(ctx, fmt.Sprintf("successfully created a virtual machine allocation: %s", id))
+	resp.Diagnostics.Append(setIdempotencyKey(ctx, resp.Private, idempotencyKey)...)
 
-	getResponse, err := r.client.GetVirtualMachineAllocation(id)
+	getResponse, err := r.client.GetVirtualMachineAllocation(ctx, id)
 
 	if err != nil {
 		addResourceError(
@@ -268,7 +357,32 @@ func (r *ResourceVirtualMachineAllocation) Create(
 
 	resp.Diagnostics.Append(
 		resourceVirtualMachineAllocationGetResponseToVirtualMachineAllocationModel(
-			ctx, getResponse, &state,
+			ctx, getResponse, &state, r.tags,
+		)...,
+	)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.Tags = plan.Tags
+	state.WaitFor = plan.WaitFor
+
+	// The allocation is saved before waiting for it, so that one that is not
+	// ready in time is replaced rather than left running untracked.
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+	if resp.Diagnostics.HasError() || plan.WaitFor.IsNull() {
+		return
+	}
+
+	allocation, diags := r.waitFor(ctx, id, plan.WaitFor.ValueString())
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(
+		resourceVirtualMachineAllocationGetResponseToVirtualMachineAllocationModel(
+			ctx, allocation, &state, r.tags,
 		)...,
 	)
 	if resp.Diagnostics.HasError() {
@@ -281,6 +395,11 @@ func (r *ResourceVirtualMachineAllocation) Create(
 func (r *ResourceVirtualMachineAllocation) Read(
 	ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse,
 ) {
+	// Until the provider is configured, the state is kept as it is.
+	if r.client == nil {
+		return
+	}
+
 	var state ResourceVirtualMachineAllocationModel
 
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
@@ -290,8 +409,12 @@ func (r *ResourceVirtualMachineAllocation) Read(
 	}
 
 	id := state.Id.ValueString()
-	allocation, err := r.client.GetVirtualMachineAllocation(id)
+	allocation, err := r.client.GetVirtualMachineAllocation(ctx, id)
 
+	if errors.Is(err, api.ErrNotFound) {
+		removeDeletedResource(ctx, resp, id, "not found")
+		return
+	}
 	if err != nil {
 		addResourceError(
 			&resp.Diagnostics, "failed to get a virtual machine allocation", id, err,
@@ -299,22 +422,107 @@ func (r *ResourceVirtualMachineAllocation) Read(
 		return
 	}
 
+	if isDeletedStatus(allocation.Status) {
+		removeDeletedResource(ctx, resp, id, fmt.Sprintf("status %s", allocation.Status))
+		return
+	}
+
 	resp.Diagnostics.Append(
 		resourceVirtualMachineAllocationGetResponseToVirtualMachineAllocationModel(
-			ctx, allocation, &state,
+			ctx, allocation, &state, r.tags,
 		)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	tags, diags := r.tags.configuredTags(ctx, state.Tags, state.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	state.Tags = tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
 func (r *ResourceVirtualMachineAllocation) Update(
 	ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse,
 ) {
-	resp.Diagnostics.AddError("update not supported", "")
+	var plan ResourceVirtualMachineAllocationModel
+	var state ResourceVirtualMachineAllocationModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+	id := state.Id.ValueString()
+
+	// Every other attribute requires replacement, so only tags can change.
+	if !plan.Tags.Equal(state.Tags) || !plan.TagsAll.Equal(state.TagsAll) {
+		tags, diags := r.tags.merge(ctx, plan.Tags)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if r.tags.ignoresAny() {
+			current, err := r.client.GetVirtualMachineAllocation(ctx, id)
+			if err != nil {
+				addResourceError(
+					&resp.Diagnostics, "failed to get a virtual machine allocation", id, err,
+				)
+				return
+			}
+			r.tags.keepIgnored(tags, current.Tags)
+		}
+
+		_, err := r.client.PatchVirtualMachineAllocation(ctx, id, &tags)
+		if err != nil {
+			addResourceError(
+				&resp.Diagnostics, "failed to patch a virtual machine allocation", id, err,
+			)
+			return
+		}
+
+		tflog.Info(ctx, fmt.Sprintf("successfully patched a virtual machine allocation: %s", id))
+	}
+
+	getResponse, err := r.client.GetVirtualMachineAllocation(ctx, id)
+
+	if err != nil {
+		addResourceError(
+			&resp.Diagnostics, "failed to get a virtual machine allocation", id, err,
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(
+		resourceVirtualMachineAllocationGetResponseToVirtualMachineAllocationModel(
+			ctx, getResponse, &state, r.tags,
+		)...,
+	)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state.Tags = plan.Tags
+	state.WaitFor = plan.WaitFor
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
 func (r *ResourceVirtualMachineAllocation) Delete(
@@ -328,8 +536,17 @@ func (r *ResourceVirtualMachineAllocation) Delete(
 		return
 	}
 
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
 	id := state.Id.ValueString()
-	deleteResponse, err := r.client.DeleteVirtualMachineAllocation(id)
+	deleteResponse, err := r.client.DeleteVirtualMachineAllocation(ctx, id)
 	successMessage, err := isResourceDeleted(err, "resource_allocation", "terminated")
 
 	if err != nil {
@@ -344,17 +561,117 @@ func (r *ResourceVirtualMachineAllocation) Delete(
 		return
 	}
 
-	_, diags := waitStatus(
-		func() (*string, error) {
-			getResponse, err := r.client.GetVirtualMachineAllocation(id)
-			if err != nil {
-				return nil, err
+	waiter := statusWaiter{
+		resource:       "virtual machine allocation",
+		target:         []string{"terminated"},
+		targetNotFound: true,
+	}
+	_, diags = waiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetVirtualMachineAllocation(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return getResponse.Status, nil
+	})
+
+	resp.Diagnostics.Append(diags...)
+}
+
+// Values of `wait_for`. heartbeat is not a status of the API: an allocation
+// reaches it once it is started and a heartbeat of its guest was received
+// within heartbeatMaxAge.
+const (
+	waitForAssigned  = "assigned"
+	waitForStarted   = "started"
+	waitForHeartbeat = "heartbeat"
+
+	heartbeatMaxAge = 2 * time.Minute
+)
+
+// allocationWaitStatus returns waitFor once an allocation has reached it, and
+// the status of the allocation otherwise.
+func allocationWaitStatus(
+	allocation *api.ResourceVirtualMachineAllocationGetResponse, waitFor string, now time.Time,
+) string {
+	if allocation.Terminating != nil || isDeletedStatus(allocation.Status) {
+		return allocation.Status
+	}
+
+	var reached bool
+	switch waitFor {
+	case waitForAssigned:
+		reached = allocation.Assigned != nil
+	case waitForStarted:
+		reached = allocation.Started != nil
+	case waitForHeartbeat:
+		reached = allocation.Started != nil && allocation.LastHeartbeat != nil &&
+			now.Sub(*allocation.LastHeartbeat) <= heartbeatMaxAge
+	}
+
+	if reached {
+		return waitFor
+	}
+	return allocation.Status
+}
+
+// describeAllocationTimeline returns when an allocation reached each status.
+func describeAllocationTimeline(
+	allocation *api.ResourceVirtualMachineAllocationGetResponse,
+) string {
+	format := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	return fmt.Sprintf(
+		"Timeline of the allocation: created: %s, assigned: %s, taken: %s, started: %s, "+
+			"last heartbeat: %s",
+		allocation.Created.Format(time.RFC3339), format(allocation.Assigned),
+		format(allocation.Taken), format(allocation.Started), format(allocation.LastHeartbeat),
+	)
+}
+
+// waitFor waits for an allocation to reach waitFor, one of the values of
+// `wait_for`. Diagnostics of a failed wait tell when the allocation reached
+// each status. It returns the allocation as it was last seen.
+func (r *ResourceVirtualMachineAllocation) waitFor(
+	ctx context.Context, id string, waitFor string,
+) (*api.ResourceVirtualMachineAllocationGetResponse, diag.Diagnostics) {
+	var allocation *api.ResourceVirtualMachineAllocationGetResponse
+
+	waiter := statusWaiter{
+		resource: "virtual machine allocation",
+		target:   []string{waitFor},
+		failure:  []string{"terminating", "terminated"},
+		describe: func() string {
+			if allocation == nil {
+				return ""
 			}
-			return &getResponse.Status, nil
+			return describeAllocationTimeline(allocation)
 		},
-		[]string{"terminated"},
-		10,
-	)
+	}
+	_, diags := waiter.wait(ctx, id, func() (string, error) {
+		getResponse, err := r.client.GetVirtualMachineAllocation(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		allocation = getResponse
+		return allocationWaitStatus(getResponse, waitFor, time.Now()), nil
+	})
+
+	return allocation, diags
+}
 
-	resp.Diagnostics.Append(diags...)
+func (r *ResourceVirtualMachineAllocation) ModifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	r.tags.modifyPlan(ctx, req, resp)
+}
+
+func (r *ResourceVirtualMachineAllocation) ImportState(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	importStateById(ctx, req, resp)
 }
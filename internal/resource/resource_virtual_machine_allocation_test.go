@@ -0,0 +1,89 @@
+package resource
+
+import (
+	"strings"
+	"terraform-provider-eci/internal/api"
+	"testing"
+	"time"
+)
+
+func TestAllocationWaitStatus(t *testing.T) {
+	now := time.Now()
+	assigned := now.Add(-10 * time.Minute)
+	started := now.Add(-5 * time.Minute)
+	recent := now.Add(-time.Minute)
+
+	for name, test := range map[string]struct {
+		allocation api.ResourceVirtualMachineAllocationGetResponse
+		waitFor    string
+		want       string
+	}{
+		"pending": {
+			allocation: api.ResourceVirtualMachineAllocationGetResponse{Status: "pending"},
+			waitFor:    waitForAssigned,
+			want:       "pending",
+		},
+		"assigned": {
+			allocation: api.ResourceVirtualMachineAllocationGetResponse{
+				Status: "assigned", Assigned: &assigned,
+			},
+			waitFor: waitForAssigned,
+			want:    waitForAssigned,
+		},
+		"started, waiting for assigned": {
+			allocation: api.ResourceVirtualMachineAllocationGetResponse{
+				Status: "started", Assigned: &assigned, Started: &started,
+			},
+			waitFor: waitForAssigned,
+			want:    waitForAssigned,
+		},
+		"assigned, waiting for started": {
+			allocation: api.ResourceVirtualMachineAllocationGetResponse{
+				Status: "assigned", Assigned: &assigned,
+			},
+			waitFor: waitForStarted,
+			want:    "assigned",
+		},
+		"started with an old heartbeat": {
+			allocation: api.ResourceVirtualMachineAllocationGetResponse{
+				Status: "started", Started: &started, LastHeartbeat: &started,
+			},
+			waitFor: waitForHeartbeat,
+			want:    "started",
+		},
+		"started with a recent heartbeat": {
+			allocation: api.ResourceVirtualMachineAllocationGetResponse{
+				Status: "started", Started: &started, LastHeartbeat: &recent,
+			},
+			waitFor: waitForHeartbeat,
+			want:    waitForHeartbeat,
+		},
+		"terminating": {
+			allocation: api.ResourceVirtualMachineAllocationGetResponse{
+				Status: "terminating", Assigned: &assigned, Terminating: &recent,
+			},
+			waitFor: waitForAssigned,
+			want:    "terminating",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := allocationWaitStatus(&test.allocation, test.waitFor, now); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDescribeAllocationTimeline(t *testing.T) {
+	assigned := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	description := describeAllocationTimeline(&api.ResourceVirtualMachineAllocationGetResponse{
+		Created:  assigned.Add(-time.Minute),
+		Assigned: &assigned,
+	})
+
+	for _, want := range []string{"assigned: 2024-05-01T12:00:00Z", "started: -"} {
+		if !strings.Contains(description, want) {
+			t.Errorf("%q does not contain %q", description, want)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package resource
+
+import (
+	"context"
+	"net/http"
+	"terraform-provider-eci/internal/api"
+	"terraform-provider-eci/internal/api/fake"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// newState returns the state of resource r holding model.
+func newState(t *testing.T, r resource.Resource, model any) tfsdk.State {
+	t.Helper()
+	ctx := context.Background()
+
+	schemaResponse := resource.SchemaResponse{}
+	r.Schema(ctx, resource.SchemaRequest{}, &schemaResponse)
+
+	state := tfsdk.State{
+		Schema: schemaResponse.Schema,
+		Raw:    tftypes.NewValue(schemaResponse.Schema.Type().TerraformType(ctx), nil),
+	}
+	if diags := state.Set(ctx, model); diags.HasError() {
+		t.Fatalf("failed to set state: %v", diags)
+	}
+
+	return state
+}
+
+// newCreateResponse returns the response to creating a resource whose plan is
+// plan, as the framework passes it: without state, and with an empty private
+// state.
+func newCreateResponse(plan tfsdk.State) resource.CreateResponse {
+	response := resource.CreateResponse{State: tfsdk.State{
+		Schema: plan.Schema,
+		Raw:    tftypes.NewValue(plan.Schema.Type().TerraformType(context.Background()), nil),
+	}}
+	// The type of the private state is internal to the framework.
+	response.Private = newZero(response.Private)
+
+	return response
+}
+
+// newZero returns a pointer to a new zero value of the type that its argument
+// points to.
+func newZero[T any](*T) *T {
+	return new(T)
+}
+
+// nullTimeouts returns a timeouts block that is not configured.
+func nullTimeouts() timeouts.Value {
+	return timeouts.Value{Object: types.ObjectNull(map[string]attr.Type{
+		"create": types.StringType,
+		"update": types.StringType,
+		"delete": types.StringType,
+	})}
+}
+
+// newTimeouts returns a timeouts block with the given timeouts, of which the
+// empty ones are not configured.
+func newTimeouts(create string, update string, delete string) timeouts.Value {
+	value := func(timeout string) attr.Value {
+		if timeout == "" {
+			return types.StringNull()
+		}
+		return types.StringValue(timeout)
+	}
+
+	return timeouts.Value{Object: types.ObjectValueMust(
+		nullTimeouts().AttributeTypes(context.Background()),
+		map[string]attr.Value{
+			"create": value(create),
+			"update": value(update),
+			"delete": value(delete),
+		},
+	)}
+}
+
+// zoneIdOf returns the zone of the provider, as resources get it, when it is
+// the zone of client.
+func zoneIdOf(client *fake.Client) func(ctx context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		return client.ZoneId.String(), nil
+	}
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResourceVirtualMachineDeleteReleasesAttachments(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+
+	instanceType := client.AddInstanceType(api.InfraInstanceTypeGetResponse{
+		Name: "small", CpuVcore: 2, MemoryGib: 4, Activated: true,
+	})
+	machine, err := client.PostVirtualMachine(
+		ctx, "", client.ZoneId.String(),
+		instanceType.Id.String(), "vm", false, false, "elice", "secret", "", nil,
+	)
+	check(t, err)
+	machineId := machine.Id.String()
+	attachedMachineIdPtr := &machineId
+
+	storage, err := client.PostBlockStorage(
+		ctx, "", client.ZoneId.String(), "disk", nil, nil, 10, false, nil,
+	)
+	check(t, err)
+	_, err = client.PatchBlockStorage(ctx, storage.Id.String(), nil, &attachedMachineIdPtr, nil)
+	check(t, err)
+
+	network, err := client.PostVirtualNetwork(
+		ctx, "", client.ZoneId.String(), "network", "10.0.0.0/16", nil,
+	)
+	check(t, err)
+	subnet, err := client.PostSubnet(
+		ctx, "", client.ZoneId.String(),
+		"subnet", network.Id.String(), "virtual_machine", "10.0.0.1/24", nil,
+	)
+	check(t, err)
+	networkInterface, err := client.PostNetworkInterface(
+		ctx, "", client.ZoneId.String(), "nic", subnet.Id.String(), false, nil, nil, nil,
+	)
+	check(t, err)
+	_, err = client.PatchNetworkInterface(
+		ctx, networkInterface.Id.String(), nil, &attachedMachineIdPtr, nil,
+	)
+	check(t, err)
+
+	allocation, err := client.PostVirtualMachineAllocation(
+		ctx, "", client.ZoneId.String(), machineId, nil,
+	)
+	check(t, err)
+
+	r := &ResourceVirtualMachine{client: client}
+	request := resource.DeleteRequest{
+		State: newState(t, r, &ResourceVirtualMachineModel{
+			Id:       types.StringValue(machineId),
+			Tags:     types.MapNull(types.StringType),
+			TagsAll:  types.MapNull(types.StringType),
+			AlwaysOn: types.BoolValue(false),
+			Timeouts: nullTimeouts(),
+		}),
+	}
+	response := resource.DeleteResponse{}
+
+	r.Delete(ctx, request, &response)
+
+	if response.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", response.Diagnostics)
+	}
+
+	storageAfter, err := client.GetBlockStorage(ctx, storage.Id.String())
+	check(t, err)
+	if storageAfter.AttachedMachineId != nil {
+		t.Errorf("block storage is still attached to %s", storageAfter.AttachedMachineId)
+	}
+
+	networkInterfaceAfter, err := client.GetNetworkInterface(ctx, networkInterface.Id.String())
+	check(t, err)
+	if networkInterfaceAfter.AttachedMachineId != nil {
+		t.Errorf(
+			"network interface is still attached to %s", networkInterfaceAfter.AttachedMachineId,
+		)
+	}
+
+	allocationAfter, err := client.GetVirtualMachineAllocation(ctx, allocation.Id.String())
+	check(t, err)
+	if allocationAfter.Status != "terminated" {
+		t.Errorf("allocation status: got %s, want terminated", allocationAfter.Status)
+	}
+
+	machineAfter, err := client.GetVirtualMachine(ctx, machineId)
+	check(t, err)
+	if machineAfter.Status != "deleted" {
+		t.Errorf("virtual machine status: got %s, want deleted", machineAfter.Status)
+	}
+}
+
+func TestResourceVirtualMachineDeleteOfDeletedMachine(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+
+	instanceType := client.AddInstanceType(api.InfraInstanceTypeGetResponse{Name: "small"})
+	machine, err := client.PostVirtualMachine(
+		ctx, "", client.ZoneId.String(),
+		instanceType.Id.String(), "vm", false, false, "elice", "secret", "", nil,
+	)
+	check(t, err)
+	_, err = client.DeleteVirtualMachine(ctx, machine.Id.String())
+	check(t, err)
+
+	r := &ResourceVirtualMachine{client: client}
+	request := resource.DeleteRequest{
+		State: newState(t, r, &ResourceVirtualMachineModel{
+			Id:       types.StringValue(machine.Id.String()),
+			Tags:     types.MapNull(types.StringType),
+			TagsAll:  types.MapNull(types.StringType),
+			AlwaysOn: types.BoolValue(false),
+			Timeouts: nullTimeouts(),
+		}),
+	}
+	response := resource.DeleteResponse{}
+
+	r.Delete(ctx, request, &response)
+
+	if response.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", response.Diagnostics)
+	}
+}
+
+// read reads the virtual machine of id from client.
+func read(t *testing.T, client api.Client, id string) resource.ReadResponse {
+	t.Helper()
+
+	r := &ResourceVirtualMachine{client: client}
+	state := newState(t, r, &ResourceVirtualMachineModel{
+		Id:       types.StringValue(id),
+		Tags:     types.MapNull(types.StringType),
+		TagsAll:  types.MapNull(types.StringType),
+		AlwaysOn: types.BoolValue(false),
+		Timeouts: nullTimeouts(),
+	})
+	response := resource.ReadResponse{State: state}
+
+	r.Read(context.Background(), resource.ReadRequest{State: state}, &response)
+
+	return response
+}
+
+func TestResourceVirtualMachineReadOfDeletedMachine(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+
+	instanceType := client.AddInstanceType(api.InfraInstanceTypeGetResponse{Name: "small"})
+	machine, err := client.PostVirtualMachine(
+		ctx, "", client.ZoneId.String(),
+		instanceType.Id.String(), "vm", false, false, "elice", "secret", "", nil,
+	)
+	check(t, err)
+	_, err = client.DeleteVirtualMachine(ctx, machine.Id.String())
+	check(t, err)
+
+	for name, id := range map[string]string{
+		"deleted":   machine.Id.String(),
+		"not found": uuid.NewString(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			response := read(t, client, id)
+
+			if response.Diagnostics.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", response.Diagnostics)
+			}
+			if !response.State.Raw.IsNull() {
+				t.Errorf("virtual machine is still in the state")
+			}
+		})
+	}
+}
+
+func TestResourceVirtualMachineReadFailure(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+
+	instanceType := client.AddInstanceType(api.InfraInstanceTypeGetResponse{Name: "small"})
+	machine, err := client.PostVirtualMachine(
+		ctx, "", client.ZoneId.String(),
+		instanceType.Id.String(), "vm", false, false, "elice", "secret", "", nil,
+	)
+	check(t, err)
+	client.FailNext("GetVirtualMachine", api.NewAPIError(
+		http.StatusInternalServerError, "internal_error", "internal server error", nil,
+	))
+
+	response := read(t, client, machine.Id.String())
+
+	if !response.Diagnostics.HasError() {
+		t.Fatalf("expected an error")
+	}
+	if response.State.Raw.IsNull() {
+		t.Errorf("virtual machine was removed from the state")
+	}
+}
+
+// newTestVirtualMachineModel returns the plan of a virtual machine named name
+// of instanceTypeId that is running.
+func newTestVirtualMachineModel(name string, instanceTypeId string) ResourceVirtualMachineModel {
+	return ResourceVirtualMachineModel{
+		Name:           types.StringValue(name),
+		InstanceTypeId: types.StringValue(instanceTypeId),
+		Tags:           types.MapNull(types.StringType),
+		TagsAll:        types.MapNull(types.StringType),
+		AlwaysOn:       types.BoolValue(false),
+		DR:             types.BoolValue(false),
+		Username:       types.StringValue("elice"),
+		Password:       types.StringValue("secret"),
+		OnInitScript:   types.StringValue(""),
+		PowerState:     types.StringValue(powerStateRunning),
+		Timeouts:       newTimeouts("1s", "1s", ""),
+	}
+}
+
+func TestResourceVirtualMachineCreateSavesMachineThatFailsToStart(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+	instanceType := client.AddInstanceType(api.InfraInstanceTypeGetResponse{Name: "small"})
+	// Allocations take longer to start than the create timeout.
+	client.SetTransitionDelay(time.Minute)
+
+	r := &ResourceVirtualMachine{client: client, zoneId: zoneIdOf(client)}
+	planModel := newTestVirtualMachineModel("vm", instanceType.Id.String())
+	plan := newState(t, r, &planModel)
+	response := newCreateResponse(plan)
+
+	r.Create(ctx, resource.CreateRequest{
+		Plan:   tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw},
+		Config: tfsdk.Config{Schema: plan.Schema, Raw: plan.Raw},
+	}, &response)
+
+	if !response.Diagnostics.HasError() {
+		t.Fatalf("expected the virtual machine to fail to start")
+	}
+
+	machines, err := api.Collect(client.GetVirtualMachines(ctx, nil))
+	check(t, err)
+	if len(machines) != 1 {
+		t.Fatalf("virtual machines: got %d, want 1", len(machines))
+	}
+
+	var state ResourceVirtualMachineModel
+	if diags := response.State.Get(ctx, &state); diags.HasError() {
+		t.Fatalf("virtual machine is not in the state: %v", diags)
+	}
+	if id := state.Id.ValueString(); id != machines[0].Id.String() {
+		t.Errorf("id: got %q, want %s", id, machines[0].Id)
+	}
+}
+
+func TestResourceVirtualMachineUpdateSavesPatchWhenFailingToStart(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClient()
+	instanceType := client.AddInstanceType(api.InfraInstanceTypeGetResponse{Name: "small"})
+	machine, err := client.PostVirtualMachine(
+		ctx, "", client.ZoneId.String(),
+		instanceType.Id.String(), "vm", false, false, "elice", "secret", "", nil,
+	)
+	check(t, err)
+	// Allocations take longer to start than the update timeout.
+	client.SetTransitionDelay(time.Minute)
+
+	r := &ResourceVirtualMachine{client: client}
+	stateModel := newTestVirtualMachineModel("vm", instanceType.Id.String())
+	stateModel.Id = types.StringValue(machine.Id.String())
+	stateModel.ZoneId = types.StringValue(client.ZoneId.String())
+	stateModel.PowerState = types.StringValue(powerStateStopped)
+	planModel := stateModel
+	planModel.Name = types.StringValue("renamed")
+	planModel.PowerState = types.StringValue(powerStateRunning)
+
+	state := newState(t, r, &stateModel)
+	plan := newState(t, r, &planModel)
+	response := resource.UpdateResponse{State: state}
+
+	r.Update(ctx, resource.UpdateRequest{
+		Plan:  tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw},
+		State: state,
+	}, &response)
+
+	if !response.Diagnostics.HasError() {
+		t.Fatalf("expected the virtual machine to fail to start")
+	}
+
+	var updated ResourceVirtualMachineModel
+	if diags := response.State.Get(ctx, &updated); diags.HasError() {
+		t.Fatalf("failed to get the state: %v", diags)
+	}
+	if name := updated.Name.ValueString(); name != "renamed" {
+		t.Errorf("name: got %q, want renamed", name)
+	}
+}
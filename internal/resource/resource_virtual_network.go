@@ -2,10 +2,15 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"terraform-provider-eci/internal/api"
 	. "terraform-provider-eci/internal/utils"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
 	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
 	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
 	"github.com/hashicorp/terraform-plugin-framework/attr"
@@ -20,17 +25,19 @@ import (
 )
 
 type ResourceVirtualNetworkModel struct {
-	Id             types.String `tfsdk:"id"`
-	Tags           types.Map    `tfsdk:"tags"`
-	Created        types.String `tfsdk:"created"`
-	Modified       types.String `tfsdk:"modified"`
-	ZoneId         types.String `tfsdk:"zone_id"`
-	OrganizationId types.String `tfsdk:"organization_id"`
-	Deleted        types.String `tfsdk:"deleted"`
-	Status         types.String `tfsdk:"status"`
-	Name           types.String `tfsdk:"name"`
-	NetworkCidr    types.String `tfsdk:"network_cidr"`
-	FirewallRules  types.List   `tfsdk:"firewall_rules"`
+	Id             types.String   `tfsdk:"id"`
+	Tags           types.Map      `tfsdk:"tags"`
+	TagsAll        types.Map      `tfsdk:"tags_all"`
+	Created        types.String   `tfsdk:"created"`
+	Modified       types.String   `tfsdk:"modified"`
+	ZoneId         types.String   `tfsdk:"zone_id"`
+	OrganizationId types.String   `tfsdk:"organization_id"`
+	Deleted        types.String   `tfsdk:"deleted"`
+	Status         types.String   `tfsdk:"status"`
+	Name           types.String   `tfsdk:"name"`
+	NetworkCidr    types.String   `tfsdk:"network_cidr"`
+	FirewallRules  types.List     `tfsdk:"firewall_rules"`
+	Timeouts       timeouts.Value `tfsdk:"timeouts"`
 }
 
 type FirewallRuleModel struct {
@@ -44,24 +51,31 @@ type FirewallRuleModel struct {
 }
 
 var _ resource.Resource = &ResourceVirtualNetwork{}
+var _ resource.ResourceWithImportState = &ResourceVirtualNetwork{}
+var _ resource.ResourceWithModifyPlan = &ResourceVirtualNetwork{}
 
 type ResourceVirtualNetwork struct {
-	client *api.APIClient
+	client api.Client
+	tags   TagPolicy
+	zoneId func(ctx context.Context) (string, error)
 }
 
 func resourceVirtualNetworkGetResponseToVirtualNetworkModel(
 	ctx context.Context,
 	response *api.ResourceVirtualNetworkGetResponse,
 	data *ResourceVirtualNetworkModel,
+	tagPolicy TagPolicy,
 ) diag.Diagnostics {
 	data.Id = types.StringValue(response.Id.String())
-	tags, diags := types.MapValueFrom(ctx, types.StringType, response.Tags)
+	tags, diags := types.MapValueFrom(
+		ctx, types.StringType, tagPolicy.withoutIgnored(response.Tags),
+	)
 
 	if diags.HasError() {
 		return diags
 	}
 
-	data.Tags = tags
+	data.TagsAll = tags
 	data.Name = types.StringValue(response.Name)
 	data.Created = types.StringValue(response.Created.String())
 	data.Modified = StringOrNull(response.Modified)
@@ -116,7 +130,12 @@ func (r *ResourceVirtualNetwork) Schema(
 			"tags": schema.MapAttribute{
 				Description: "User-defined metadata of key-value pairs",
 				ElementType: types.StringType,
-				Required:    true,
+				Optional:    true,
+			},
+			"tags_all": schema.MapAttribute{
+				Description: "tags of the resource, including the default tags of the provider",
+				ElementType: types.StringType,
+				Computed:    true,
 			},
 			"created": schema.StringAttribute{
 				Description:   "the time when the virtual network is created",
@@ -132,11 +151,14 @@ func (r *ResourceVirtualNetwork) Schema(
 				Required:    false,
 			},
 			"zone_id": schema.StringAttribute{
-				Description:   "id of the zone that the virtual network belongs to",
-				Required:      false,
-				Computed:      true,
-				Optional:      false,
-				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description: "id of the zone that the virtual network belongs to, " +
+					"which defaults to the zone of the provider",
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 			"organization_id": schema.StringAttribute{
 				Description:   "id of the organization that the virtual network belongs to",
@@ -215,6 +237,13 @@ func (r *ResourceVirtualNetwork) Schema(
 				},
 			},
 		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create: true,
+				Update: true,
+				Delete: true,
+			}),
+		},
 	}
 }
 
@@ -225,18 +254,20 @@ func (r *ResourceVirtualNetwork) Configure(
 		return
 	}
 
-	client, ok := req.ProviderData.(*api.APIClient)
+	data, ok := req.ProviderData.(*ProviderData)
 
 	if !ok {
 		resp.Diagnostics.AddError(
 			"unexpected resource configure type",
-			fmt.Sprintf(`expected *api.APIClient, got: %T.`, req.ProviderData),
+			fmt.Sprintf(`expected *resource.ProviderData, got: %T.`, req.ProviderData),
 		)
 
 		return
 	}
 
-	r.client = client
+	r.client = data.Client
+	r.tags = data.Tags
+	r.zoneId = data.ZoneId
 }
 
 func (r *ResourceVirtualNetwork) Create(
@@ -251,25 +282,70 @@ func (r *ResourceVirtualNetwork) Create(
 		return
 	}
 
-	tags := map[string]string{}
-	resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	response, err := r.client.PostVirtualNetwork(
-		plan.Name.ValueString(),
-		plan.NetworkCidr.ValueString(),
-		tags,
-	)
+	ctx, cancel := context.WithTimeout(ctx, createTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
+
+	tags, diags := r.tags.merge(ctx, plan.Tags)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	zoneId, err := zoneIdOrDefault(ctx, plan.ZoneId, r.zoneId)
+	if err != nil {
+		addResourceError(&resp.Diagnostics, "failed to select the zone", "", err)
+		return
+	}
+
+	idempotencyKey := newIdempotencyKey()
+	startedAt := time.Now()
+
+	create := func() (string, error) {
+		response, err := r.client.PostVirtualNetwork(
+			ctx,
+			idempotencyKey,
+			zoneId,
+			plan.Name.ValueString(),
+			plan.NetworkCidr.ValueString(),
+			tags,
+		)
+		if err != nil {
+			return "", err
+		}
+		return response.Id.String(), nil
+	}
+
+	id, err := create()
+	if err != nil {
+		id, err = recoverCreate(ctx, err, func() (*uuid.UUID, error) {
+			return findCreated(
+				r.client.GetVirtualNetworks(ctx, plan.Name.ValueStringPointer()),
+				startedAt,
+				func(network api.ResourceVirtualNetworkGetResponse) (uuid.UUID, time.Time, bool) {
+					return network.Id, network.Created, network.Status != "deleted" &&
+						network.Name == plan.Name.ValueString() &&
+						network.NetworkCidr == plan.NetworkCidr.ValueString() &&
+						maps.Equal(network.Tags, tags)
+				},
+			)
+		}, create)
+	}
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to create a virtual network", "", err)
 		return
 	}
 
-	id := response.Id.String()
 	tflog.Info(ctx, fmt.Sprintf("successfully created a virtual network: %s", id))
+	resp.Diagnostics.Append(setIdempotencyKey(ctx, resp.Private, idempotencyKey)...)
 
 	var rules = []api.NetworkFirewallRule{}
 	if !plan.FirewallRules.IsUnknown() {
@@ -282,14 +358,14 @@ func (r *ResourceVirtualNetwork) Create(
 		}
 	}
 
-	_, err = r.client.PatchVirtualNetwork(id, nil, &rules, nil)
+	_, err = r.client.PatchVirtualNetwork(ctx, id, nil, &rules, nil)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to patch a virtual network", "", err)
 		return
 	}
 
-	getResponse, err := r.client.GetVirtualNetwork(id)
+	getResponse, err := r.client.GetVirtualNetwork(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a virtual network", id, err)
@@ -297,19 +373,26 @@ func (r *ResourceVirtualNetwork) Create(
 	}
 
 	resp.Diagnostics.Append(
-		resourceVirtualNetworkGetResponseToVirtualNetworkModel(ctx, getResponse, &state)...,
+		resourceVirtualNetworkGetResponseToVirtualNetworkModel(ctx, getResponse, &state, r.tags)...,
 	)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	state.Tags = plan.Tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
 func (r *ResourceVirtualNetwork) Read(
 	ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse,
 ) {
+	// Until the provider is configured, the state is kept as it is.
+	if r.client == nil {
+		return
+	}
+
 	var state ResourceVirtualNetworkModel
 	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
 
@@ -318,21 +401,37 @@ func (r *ResourceVirtualNetwork) Read(
 	}
 
 	id := state.Id.ValueString()
-	response, err := r.client.GetVirtualNetwork(id)
+	response, err := r.client.GetVirtualNetwork(ctx, id)
 
+	if errors.Is(err, api.ErrNotFound) {
+		removeDeletedResource(ctx, resp, id, "not found")
+		return
+	}
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a virtual network", id, err)
 		return
 	}
 
+	if isDeletedStatus(response.Status) {
+		removeDeletedResource(ctx, resp, id, fmt.Sprintf("status %s", response.Status))
+		return
+	}
+
 	resp.Diagnostics.Append(
-		resourceVirtualNetworkGetResponseToVirtualNetworkModel(ctx, response, &state)...,
+		resourceVirtualNetworkGetResponseToVirtualNetworkModel(ctx, response, &state, r.tags)...,
 	)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	tags, diags := r.tags.configuredTags(ctx, state.Tags, state.TagsAll)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	state.Tags = tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -348,6 +447,17 @@ func (r *ResourceVirtualNetwork) Update(
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	updateTimeout, diags := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateTimeout)
+	defer cancel()
+
+	state.Timeouts = plan.Timeouts
 	id := state.Id.ValueString()
 
 	var namePtr *string = nil
@@ -368,17 +478,26 @@ func (r *ResourceVirtualNetwork) Update(
 	}
 
 	var tagsPtr *map[string]string = nil
-	if !plan.Tags.Equal(state.Tags) {
-		tags := map[string]string{}
-		resp.Diagnostics.Append(plan.Tags.ElementsAs(ctx, &tags, false)...)
+	if !plan.Tags.Equal(state.Tags) || !plan.TagsAll.Equal(state.TagsAll) {
+		tags, diags := r.tags.merge(ctx, plan.Tags)
+		resp.Diagnostics.Append(diags...)
 		if resp.Diagnostics.HasError() {
 			return
 		}
 
+		if r.tags.ignoresAny() {
+			current, err := r.client.GetVirtualNetwork(ctx, id)
+			if err != nil {
+				addResourceError(&resp.Diagnostics, "failed to get a virtual network", id, err)
+				return
+			}
+			r.tags.keepIgnored(tags, current.Tags)
+		}
+
 		tagsPtr = &tags
 	}
 
-	_, err := r.client.PatchVirtualNetwork(id, namePtr, firewallRulesPtr, tagsPtr)
+	_, err := r.client.PatchVirtualNetwork(ctx, id, namePtr, firewallRulesPtr, tagsPtr)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to patch a virtual network", id, err)
@@ -387,7 +506,7 @@ func (r *ResourceVirtualNetwork) Update(
 
 	tflog.Info(ctx, fmt.Sprintf("successfully patched a virtual network: %s", id))
 
-	getResponse, err := r.client.GetVirtualNetwork(id)
+	getResponse, err := r.client.GetVirtualNetwork(ctx, id)
 
 	if err != nil {
 		addResourceError(&resp.Diagnostics, "failed to get a virtual network", id, err)
@@ -396,13 +515,15 @@ func (r *ResourceVirtualNetwork) Update(
 
 	resp.Diagnostics.Append(
 		resourceVirtualNetworkGetResponseToVirtualNetworkModel(
-			ctx, getResponse, &state,
+			ctx, getResponse, &state, r.tags,
 		)...,
 	)
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	state.Tags = plan.Tags
+
 	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
 }
 
@@ -417,8 +538,17 @@ func (r *ResourceVirtualNetwork) Delete(
 		return
 	}
 
+	deleteTimeout, diags := state.Timeouts.Delete(ctx, defaultDeleteTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deleteTimeout)
+	defer cancel()
+
 	id := state.Id.ValueString()
-	_, err := r.client.DeleteVirtualNetwork(id)
+	_, err := r.client.DeleteVirtualNetwork(ctx, id)
 
 	successMessage, err := isResourceDeleted(err, "resource_virtual_network", "deleted")
 	if err != nil {
@@ -428,3 +558,15 @@ func (r *ResourceVirtualNetwork) Delete(
 
 	tflog.Info(ctx, fmt.Sprintf("%s (virtual network: %s)", successMessage, id))
 }
+
+func (r *ResourceVirtualNetwork) ModifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	r.tags.modifyPlan(ctx, req, resp)
+}
+
+func (r *ResourceVirtualNetwork) ImportState(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	importStateById(ctx, req, resp)
+}
@@ -0,0 +1,231 @@
+package resource
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// TagPolicy is how tags are handled across resources.
+//
+// Resources send their tags merged over the default ones, which `tags_all`
+// holds, while `tags` holds the tags as configured so that the default ones
+// do not show up as a diff.
+//
+// Tags that are managed outside Terraform are ignored: they are left out of
+// what is read, and kept as they are when the tags of a resource are patched.
+//
+// The zero TagPolicy is that of a provider that is not configured yet.
+type TagPolicy struct {
+	// Default are the tags of every resource, unless it sets them itself.
+	Default map[string]string
+
+	// IgnoreKeys and IgnoreKeyPrefixes are the keys of the ignored tags, and
+	// the prefixes of them.
+	IgnoreKeys        []string
+	IgnoreKeyPrefixes []string
+
+	configured bool
+}
+
+// NewTagPolicy returns the TagPolicy of a configured provider.
+func NewTagPolicy(
+	defaultTags map[string]string, ignoreKeys []string, ignoreKeyPrefixes []string,
+) TagPolicy {
+	return TagPolicy{
+		Default:           defaultTags,
+		IgnoreKeys:        ignoreKeys,
+		IgnoreKeyPrefixes: ignoreKeyPrefixes,
+		configured:        true,
+	}
+}
+
+// ignoresAny reports whether any tag may be ignored.
+func (p TagPolicy) ignoresAny() bool {
+	return len(p.IgnoreKeys) > 0 || len(p.IgnoreKeyPrefixes) > 0
+}
+
+// ignores reports whether the tag of key is ignored.
+func (p TagPolicy) ignores(key string) bool {
+	if slices.Contains(p.IgnoreKeys, key) {
+		return true
+	}
+
+	for _, prefix := range p.IgnoreKeyPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutIgnored returns tags without the ignored ones.
+func (p TagPolicy) withoutIgnored(tags map[string]string) map[string]string {
+	if tags == nil || !p.ignoresAny() {
+		return tags
+	}
+
+	filtered := map[string]string{}
+	for key, value := range tags {
+		if !p.ignores(key) {
+			filtered[key] = value
+		}
+	}
+
+	return filtered
+}
+
+// keepIgnored adds to tags the ignored ones of current, the tags the resource
+// has, so that patching tags does not remove them. Tags that are set in tags
+// are left as they are.
+func (p TagPolicy) keepIgnored(tags map[string]string, current map[string]string) {
+	for key, value := range current {
+		if _, ok := tags[key]; !ok && p.ignores(key) {
+			tags[key] = value
+		}
+	}
+}
+
+// merge returns the tags of a resource merged over the default ones.
+func (p TagPolicy) merge(
+	ctx context.Context, tags types.Map,
+) (map[string]string, diag.Diagnostics) {
+	merged := maps.Clone(p.Default)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+
+	if tags.IsNull() || tags.IsUnknown() {
+		return merged, nil
+	}
+
+	resourceTags := map[string]string{}
+	diags := tags.ElementsAs(ctx, &resourceTags, false)
+	maps.Copy(merged, resourceTags)
+
+	return merged, diags
+}
+
+// configuredTags returns the tags of a resource as configured, given all of
+// its tags: default tags are left out unless they are configured as well.
+// Ignored tags are never read, so the configured ones are kept.
+func (p TagPolicy) configuredTags(
+	ctx context.Context, configured types.Map, all types.Map,
+) (types.Map, diag.Diagnostics) {
+	diags := diag.Diagnostics{}
+
+	if all.IsNull() || all.IsUnknown() {
+		return configured, diags
+	}
+
+	allTags := map[string]string{}
+	diags.Append(all.ElementsAs(ctx, &allTags, false)...)
+
+	configuredTags := map[string]string{}
+	if !configured.IsNull() && !configured.IsUnknown() {
+		diags.Append(configured.ElementsAs(ctx, &configuredTags, false)...)
+	}
+
+	if diags.HasError() {
+		return configured, diags
+	}
+
+	tags := map[string]string{}
+	for key, value := range allTags {
+		_, isConfigured := configuredTags[key]
+		if defaultValue, ok := p.Default[key]; ok && defaultValue == value && !isConfigured {
+			continue
+		}
+		tags[key] = value
+	}
+
+	for key, value := range configuredTags {
+		if p.ignores(key) {
+			tags[key] = value
+		}
+	}
+
+	if len(tags) == 0 && configured.IsNull() {
+		return types.MapNull(types.StringType), diags
+	}
+
+	result, mapDiags := types.MapValueFrom(ctx, types.StringType, tags)
+	diags.Append(mapDiags...)
+
+	return result, diags
+}
+
+// modifyPlan plans `tags_all` as the planned `tags` merged over the default
+// ones, without the ignored ones. Until the provider is configured, the
+// default tags are not known: `tags_all` of an existing resource whose `tags`
+// are unchanged is kept as it is, and is unknown otherwise.
+func (p TagPolicy) modifyPlan(
+	ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse,
+) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var tags types.Map
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("tags"), &tags)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !p.configured && !req.State.Raw.IsNull() {
+		var stateTags, stateTagsAll types.Map
+		resp.Diagnostics.Append(req.State.GetAttribute(ctx, path.Root("tags"), &stateTags)...)
+		resp.Diagnostics.Append(
+			req.State.GetAttribute(ctx, path.Root("tags_all"), &stateTagsAll)...,
+		)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		if tags.Equal(stateTags) {
+			resp.Diagnostics.Append(
+				resp.Plan.SetAttribute(ctx, path.Root("tags_all"), stateTagsAll)...,
+			)
+			return
+		}
+	}
+
+	tagsAll := types.MapUnknown(types.StringType)
+	if p.configured && isKnownMap(tags) {
+		merged, diags := p.merge(ctx, tags)
+		resp.Diagnostics.Append(diags...)
+
+		tagsAll, diags = types.MapValueFrom(ctx, types.StringType, p.withoutIgnored(merged))
+		resp.Diagnostics.Append(diags...)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("tags_all"), tagsAll)...)
+}
+
+// isKnownMap reports whether a map and all of its elements are known.
+func isKnownMap(m types.Map) bool {
+	if m.IsUnknown() {
+		return false
+	}
+
+	for _, element := range m.Elements() {
+		if element.IsUnknown() {
+			return false
+		}
+	}
+
+	return true
+}
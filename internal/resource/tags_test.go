@@ -0,0 +1,214 @@
+package resource
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func tagsValue(t *testing.T, tags map[string]string) types.Map {
+	t.Helper()
+
+	if tags == nil {
+		return types.MapNull(types.StringType)
+	}
+
+	value, diags := types.MapValueFrom(context.Background(), types.StringType, tags)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	return value
+}
+
+func TestTagPolicyMerge(t *testing.T) {
+	policy := TagPolicy{Default: map[string]string{"team": "infra", "env": "test"}}
+
+	merged, diags := policy.merge(
+		context.Background(), tagsValue(t, map[string]string{"env": "production"}),
+	)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	want := map[string]string{"team": "infra", "env": "production"}
+	if !maps.Equal(merged, want) {
+		t.Errorf("got %v, want %v", merged, want)
+	}
+	if policy.Default["env"] != "test" {
+		t.Errorf("default tags were modified: %v", policy.Default)
+	}
+}
+
+func TestTagPolicyConfiguredTags(t *testing.T) {
+	policy := TagPolicy{Default: map[string]string{"team": "infra", "env": "test"}}
+
+	for name, test := range map[string]struct {
+		configured map[string]string
+		all        map[string]string
+		want       map[string]string
+	}{
+		"default tags are left out": {
+			configured: map[string]string{"created-by": "terraform"},
+			all:        map[string]string{"created-by": "terraform", "team": "infra"},
+			want:       map[string]string{"created-by": "terraform"},
+		},
+		"overridden default tags are kept": {
+			configured: map[string]string{"env": "production"},
+			all:        map[string]string{"env": "production", "team": "infra"},
+			want:       map[string]string{"env": "production"},
+		},
+		"configured default tags are kept": {
+			configured: map[string]string{"team": "infra"},
+			all:        map[string]string{"team": "infra"},
+			want:       map[string]string{"team": "infra"},
+		},
+		"tags changed outside terraform are kept": {
+			configured: map[string]string{},
+			all:        map[string]string{"team": "billing", "owner": "someone"},
+			want:       map[string]string{"team": "billing", "owner": "someone"},
+		},
+		"no tags are null when not configured": {
+			all: map[string]string{"team": "infra"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tags, diags := policy.configuredTags(
+				context.Background(), tagsValue(t, test.configured), tagsValue(t, test.all),
+			)
+
+			if diags.HasError() {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+			if want := tagsValue(t, test.want); !tags.Equal(want) {
+				t.Errorf("got %v, want %v", tags, want)
+			}
+		})
+	}
+}
+
+func TestTagPolicyIgnoredTags(t *testing.T) {
+	policy := TagPolicy{
+		IgnoreKeys:        []string{"backup"},
+		IgnoreKeyPrefixes: []string{"billing:"},
+	}
+
+	read := policy.withoutIgnored(map[string]string{
+		"created-by": "terraform", "backup": "daily", "billing:code": "1234",
+	})
+	if want := map[string]string{"created-by": "terraform"}; !maps.Equal(read, want) {
+		t.Errorf("read: got %v, want %v", read, want)
+	}
+
+	patched := map[string]string{"created-by": "terraform", "backup": "weekly"}
+	policy.keepIgnored(patched, map[string]string{
+		"created-by": "someone", "backup": "daily", "billing:code": "1234", "owner": "someone",
+	})
+	want := map[string]string{"created-by": "terraform", "backup": "weekly", "billing:code": "1234"}
+	if !maps.Equal(patched, want) {
+		t.Errorf("patched: got %v, want %v", patched, want)
+	}
+
+	tags, diags := policy.configuredTags(
+		context.Background(),
+		tagsValue(t, map[string]string{"created-by": "terraform", "backup": "weekly"}),
+		tagsValue(t, read),
+	)
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	configured := tagsValue(t, map[string]string{"created-by": "terraform", "backup": "weekly"})
+	if !tags.Equal(configured) {
+		t.Errorf("configured: got %v, want %v", tags, configured)
+	}
+}
+
+// plannedTagsAll returns `tags_all` of a subnet as policy plans it, given its
+// planned tags and, unless it is being created, its tags and `tags_all` in
+// the state.
+func plannedTagsAll(
+	t *testing.T, policy TagPolicy, planned types.Map, state *ResourceSubnetModel,
+) types.Map {
+	t.Helper()
+	ctx := context.Background()
+
+	r := &ResourceSubnet{}
+	plan := newState(t, r, &ResourceSubnetModel{
+		Id:       types.StringUnknown(),
+		Tags:     planned,
+		TagsAll:  types.MapUnknown(types.StringType),
+		Timeouts: nullTimeouts(),
+	})
+	request := resource.ModifyPlanRequest{
+		Plan: tfsdk.Plan{Schema: plan.Schema, Raw: plan.Raw},
+		State: tfsdk.State{
+			Schema: plan.Schema,
+			Raw:    tftypes.NewValue(plan.Schema.Type().TerraformType(ctx), nil),
+		},
+	}
+	if state != nil {
+		state.Timeouts = nullTimeouts()
+		request.State = newState(t, r, state)
+	}
+	response := resource.ModifyPlanResponse{Plan: request.Plan}
+
+	policy.modifyPlan(ctx, request, &response)
+
+	if response.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", response.Diagnostics)
+	}
+
+	var tagsAll types.Map
+	response.Plan.GetAttribute(ctx, path.Root("tags_all"), &tagsAll)
+	return tagsAll
+}
+
+func TestTagPolicyModifyPlan(t *testing.T) {
+	configured := map[string]string{"created-by": "terraform"}
+	all := map[string]string{"created-by": "terraform", "team": "infra"}
+	existing := &ResourceSubnetModel{
+		Id:      types.StringValue("id"),
+		Tags:    tagsValue(t, configured),
+		TagsAll: tagsValue(t, all),
+	}
+
+	for name, test := range map[string]struct {
+		policy  TagPolicy
+		planned map[string]string
+		state   *ResourceSubnetModel
+		want    types.Map
+	}{
+		"configured": {
+			policy:  NewTagPolicy(map[string]string{"team": "infra"}, nil, nil),
+			planned: configured,
+			want:    tagsValue(t, all),
+		},
+		"not configured, created": {
+			planned: configured,
+			want:    types.MapUnknown(types.StringType),
+		},
+		"not configured, tags unchanged": {
+			planned: configured,
+			state:   existing,
+			want:    tagsValue(t, all),
+		},
+		"not configured, tags changed": {
+			planned: map[string]string{"created-by": "someone"},
+			state:   existing,
+			want:    types.MapUnknown(types.StringType),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tagsAll := plannedTagsAll(t, test.policy, tagsValue(t, test.planned), test.state)
+
+			if !tagsAll.Equal(test.want) {
+				t.Errorf("got %v, want %v", tagsAll, test.want)
+			}
+		})
+	}
+}
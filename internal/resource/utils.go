@@ -1,21 +1,44 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math"
+	"slices"
 	"terraform-provider-eci/internal/api"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
+// Timeouts of operations that are not configured in the timeouts block.
+const (
+	defaultCreateTimeout = 20 * time.Minute
+	defaultUpdateTimeout = 20 * time.Minute
+	defaultDeleteTimeout = 20 * time.Minute
+)
+
+// deletedStatuses are the statuses of resources that are gone for good, as
+// the portal keeps deleted resources around for a while.
+var deletedStatuses = []string{"deleted", "terminated"}
+
 func addResourceError(
 	diags *diag.Diagnostics,
 	summary string,
 	resourceId string,
 	err error,
 ) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		summary = fmt.Sprintf("%s: operation timed out", summary)
+	} else if errors.Is(err, context.Canceled) {
+		summary = fmt.Sprintf("%s: operation cancelled", summary)
+	}
+
 	var detail string
 	if resourceId == "" {
 		detail = fmt.Sprintf("reason: %s", err.Error())
@@ -31,56 +54,73 @@ func isResourceDeleted(err error, resourceKey string, deletedStatus string) (str
 		return "successfully deleted", nil
 	}
 
-	var apiError *api.APIError
-	if !errors.As(err, &apiError) {
-		return "", err
-	}
-
-	switch apiError.HttpCode {
-	case 404:
+	if errors.Is(err, api.ErrNotFound) {
 		return "resource does not exist", nil
+	}
 
-	case 409:
-		if apiError.IsCode("unexpected_status") &&
-			apiError.Detail != nil {
-			resource, resourceExists := (*apiError.Detail)[resourceKey]
-
-			if resourceExists {
-				if resource.(map[string]interface{})["status"] == deletedStatus {
-					return "resource is already deleted", nil
-				}
-			}
-		}
+	var unexpectedStatus *api.ErrUnexpectedStatus
+	if errors.As(err, &unexpectedStatus) &&
+		unexpectedStatus.Resource == resourceKey &&
+		unexpectedStatus.Status == deletedStatus {
+		return "resource is already deleted", nil
 	}
 
 	return "", err
 }
 
-func waitStatus(
-	getStatus func() (*string, error), targetStatuses []string, maxRetry int,
-) (*string, diag.Diagnostics) {
-	diags := diag.Diagnostics{}
-
-	for retryIndex := 0; retryIndex < maxRetry; retryIndex++ {
-		status, err := getStatus()
-
-		if err != nil {
-			diags.Append(diag.NewWarningDiagnostic(
-				"failed to get status",
-				fmt.Sprintf("retry: %d (err: %s)", retryIndex, err.Error()),
-			))
-			continue
-		}
-
-		for _, targetStatus := range targetStatuses {
-			if targetStatus == *status {
-				return &targetStatus, diags
-			}
-		}
-
-		time.Sleep(time.Duration(min(0.5+math.Pow(2, float64(retryIndex)), 15)) * time.Second)
+func isDeletedStatus(status string) bool {
+	return slices.Contains(deletedStatuses, status)
+}
+
+// removeDeletedResource removes a resource that was deleted outside terraform
+// from the state, so that terraform plans to create it again.
+func removeDeletedResource(
+	ctx context.Context, resp *resource.ReadResponse, resourceId string, reason string,
+) {
+	tflog.Warn(ctx, fmt.Sprintf(
+		"removing resource from state as it was deleted outside terraform: %s (resource id: %s)",
+		reason, resourceId,
+	))
+	resp.State.RemoveResource(ctx)
+}
+
+// importStateById imports a resource by its id, leaving the other attributes
+// to Read.
+func importStateById(
+	ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse,
+) {
+	if _, err := uuid.Parse(req.ID); err != nil {
+		resp.Diagnostics.AddError(
+			"invalid import id",
+			fmt.Sprintf("expected the id (UUID) of the resource, got: %q (reason: %s)", req.ID, err),
+		)
+		return
+	}
+
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+func sleepWithContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
+}
 
-	diags.Append(diag.NewErrorDiagnostic("unexpected status", "reached maximum retry"))
-	return nil, diags
+// zoneIdOrDefault returns the zone that a resource is created in: the zone
+// that it sets, if any, or else the zone of the provider.
+func zoneIdOrDefault(
+	ctx context.Context,
+	zoneId types.String,
+	defaultZoneId func(ctx context.Context) (string, error),
+) (string, error) {
+	if zoneId.IsNull() || zoneId.IsUnknown() {
+		return defaultZoneId(ctx)
+	}
+	return zoneId.ValueString(), nil
 }
@@ -0,0 +1,59 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestSleepWithContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	startedAt := time.Now()
+	err := sleepWithContext(ctx, time.Hour)
+
+	if elapsed := time.Since(startedAt); elapsed > time.Second {
+		t.Errorf("returned %s after the cancellation", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAddResourceErrorSummary(t *testing.T) {
+	for name, test := range map[string]struct {
+		err     error
+		summary string
+	}{
+		"cancelled": {
+			err:     context.Canceled,
+			summary: "failed to create a subnet: operation cancelled",
+		},
+		"timed out": {
+			err:     context.DeadlineExceeded,
+			summary: "failed to create a subnet: operation timed out",
+		},
+		"other": {
+			err:     errors.New("connection reset"),
+			summary: "failed to create a subnet",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			diags := diag.Diagnostics{}
+
+			addResourceError(&diags, "failed to create a subnet", "id", test.err)
+
+			if summary := diags.Errors()[0].Summary(); summary != test.summary {
+				t.Errorf("summary: got %q, want %q", summary, test.summary)
+			}
+			if detail := diags.Errors()[0].Detail(); !strings.Contains(detail, "resource id: id") {
+				t.Errorf("detail does not mention the resource id: %s", detail)
+			}
+		})
+	}
+}
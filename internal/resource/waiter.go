@@ -0,0 +1,203 @@
+package resource
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"terraform-provider-eci/internal/api"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	defaultWaitMinInterval = 1 * time.Second
+	defaultWaitMaxInterval = 15 * time.Second
+
+	// defaultWaitMaxErrors is how many transient errors in a row are
+	// tolerated while waiting. The client already retries each request, so
+	// this only rides out longer outages.
+	defaultWaitMaxErrors = 3
+)
+
+// statusWaiter waits for a resource to reach a target status. The deadline
+// of the wait is the one of the context, which is set by the timeouts block
+// of the resource.
+type statusWaiter struct {
+	// resource names the resource in diagnostics, e.g. "block storage".
+	resource string
+
+	// pending are the statuses the resource goes through before reaching a
+	// target status. Any other status fails the wait. When empty, every
+	// status that is neither a target nor a failure is pending.
+	pending []string
+	// target are the statuses to wait for.
+	target []string
+	// failure are the statuses from which the resource never reaches a
+	// target status, e.g. `error`.
+	failure []string
+
+	// targetNotFound counts a resource that is not found as having reached
+	// a target status, e.g. when waiting for a resource to be deleted.
+	targetNotFound bool
+
+	// minSuccesses is how many times in a row a target status must be
+	// observed, for statuses that may flap. Zero means once.
+	minSuccesses int
+
+	// minInterval and maxInterval bound the backoff between polls. Zero
+	// means defaultWaitMinInterval and defaultWaitMaxInterval.
+	minInterval time.Duration
+	maxInterval time.Duration
+
+	// describe, when set, returns what is known of the resource, e.g. when
+	// it reached each status, to add to the diagnostics of a failed wait.
+	describe func() string
+}
+
+// wait polls getStatus until it returns a target status, which it returns.
+// It fails on a failure or unexpected status, on an error that is not
+// transient, or when ctx is done. The last observed status and the time spent
+// are reported in the diagnostics.
+func (w *statusWaiter) wait(
+	ctx context.Context, resourceId string, getStatus func() (string, error),
+) (string, diag.Diagnostics) {
+	diags := diag.Diagnostics{}
+	startedAt := time.Now()
+
+	minInterval := cmp.Or(w.minInterval, defaultWaitMinInterval)
+	maxInterval := cmp.Or(w.maxInterval, defaultWaitMaxInterval)
+	minSuccesses := max(w.minSuccesses, 1)
+
+	lastStatus := "unknown"
+	var lastErr error
+	errorCount := 0
+	successCount := 0
+	interval := minInterval
+
+	for ctx.Err() == nil {
+		status, err := getStatus()
+
+		switch {
+		case err != nil && w.targetNotFound && errors.Is(err, api.ErrNotFound):
+			tflog.Debug(ctx, fmt.Sprintf("%s (%s) is not found", w.resource, resourceId))
+			return "", diags
+		case err != nil && ctx.Err() != nil:
+			// The error is the deadline or the cancellation, reported below.
+		case err != nil:
+			if !isTransientError(err) || errorCount >= defaultWaitMaxErrors {
+				diags.AddError(
+					fmt.Sprintf("failed to wait for %s", w.resource),
+					w.withDescription(fmt.Sprintf(
+						"reason: %s (resource id: %s, last status: %s, elapsed: %s)",
+						err, resourceId, lastStatus, elapsedSince(startedAt),
+					)),
+				)
+				return "", diags
+			}
+
+			errorCount++
+			lastErr = err
+			tflog.Debug(ctx, fmt.Sprintf(
+				"failed to get status of %s (%s), retrying: %s", w.resource, resourceId, err,
+			))
+		default:
+			errorCount = 0
+			lastStatus = status
+
+			if slices.Contains(w.target, status) {
+				successCount++
+				if successCount >= minSuccesses {
+					return status, diags
+				}
+
+				// Confirm the status soon rather than after a long backoff.
+				interval = minInterval
+				break
+			}
+			successCount = 0
+
+			if slices.Contains(w.failure, status) ||
+				(len(w.pending) > 0 && !slices.Contains(w.pending, status)) {
+				diags.AddError(
+					fmt.Sprintf("unexpected status of %s", w.resource),
+					w.withDescription(fmt.Sprintf(
+						"%s reached status %s while waiting for %v "+
+							"(resource id: %s, elapsed: %s)",
+						w.resource, status, w.target, resourceId, elapsedSince(startedAt),
+					)),
+				)
+				return "", diags
+			}
+
+			tflog.Debug(ctx, fmt.Sprintf(
+				"%s (%s) is %s, waiting for %v", w.resource, resourceId, status, w.target,
+			))
+		}
+
+		if sleepWithContext(ctx, jitter(interval)) != nil {
+			break
+		}
+		interval = min(interval*2, maxInterval)
+	}
+
+	detail := fmt.Sprintf(
+		"stopped waiting for %s to reach %v (resource id: %s, last status: %s, elapsed: %s)",
+		w.resource, w.target, resourceId, lastStatus, elapsedSince(startedAt),
+	)
+	if lastErr != nil && errorCount > 0 {
+		detail = fmt.Sprintf("%s, last error: %s", detail, lastErr)
+	}
+	detail = w.withDescription(detail)
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		diags.AddError(
+			"operation timed out",
+			detail+". The timeout can be raised in the timeouts block of the resource.",
+		)
+		return "", diags
+	}
+
+	diags.AddError("operation cancelled", detail)
+	return "", diags
+}
+
+// withDescription returns detail followed by the description of the
+// resource, if any.
+func (w *statusWaiter) withDescription(detail string) string {
+	if w.describe == nil {
+		return detail
+	}
+
+	description := w.describe()
+	if description == "" {
+		return detail
+	}
+
+	return fmt.Sprintf("%s. %s", detail, description)
+}
+
+// isTransientError reports whether err may go away by itself, i.e. the portal
+// could not be reached or failed to serve the request.
+func isTransientError(err error) bool {
+	var apiError *api.APIError
+	if errors.As(err, &apiError) {
+		return apiError.HttpCode >= 500
+	}
+
+	return api.IsTransportError(err)
+}
+
+// jitter spreads interval by up to a quarter either way, so that waiters
+// started together do not poll together.
+func jitter(interval time.Duration) time.Duration {
+	return time.Duration(float64(interval) * (0.75 + rand.Float64()/2))
+}
+
+func elapsedSince(startedAt time.Time) time.Duration {
+	return time.Since(startedAt).Round(time.Second)
+}
@@ -0,0 +1,221 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"terraform-provider-eci/internal/api"
+	"testing"
+	"time"
+)
+
+// poll is a result of getStatus.
+type poll struct {
+	status string
+	err    error
+}
+
+// polls returns a getStatus that returns results in order, then the last
+// one forever, along with a counter of its calls.
+func polls(results ...poll) (func() (string, error), *int) {
+	calls := 0
+	return func() (string, error) {
+		result := results[min(calls, len(results)-1)]
+		calls++
+		return result.status, result.err
+	}, &calls
+}
+
+func newTestWaiter() statusWaiter {
+	return statusWaiter{
+		resource:    "block storage",
+		target:      []string{"prepared"},
+		failure:     []string{"error"},
+		minInterval: time.Millisecond,
+		maxInterval: time.Millisecond,
+	}
+}
+
+func TestStatusWaiterReachesTarget(t *testing.T) {
+	waiter := newTestWaiter()
+	getStatus, calls := polls(poll{status: "assigned"}, poll{status: "prepared"})
+
+	status, diags := waiter.wait(context.Background(), "id", getStatus)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if status != "prepared" {
+		t.Errorf("status: got %s, want prepared", status)
+	}
+	if *calls != 2 {
+		t.Errorf("calls: got %d, want 2", *calls)
+	}
+}
+
+func TestStatusWaiterFails(t *testing.T) {
+	serverError := api.NewAPIError(http.StatusInternalServerError, "internal", "", nil)
+	notFound := api.NewAPIError(http.StatusNotFound, "not_found", "", nil)
+
+	for name, test := range map[string]struct {
+		pending []string
+		polls   []poll
+		summary string
+		calls   int
+	}{
+		"failure status": {
+			polls:   []poll{{status: "assigned"}, {status: "error"}},
+			summary: "unexpected status of block storage",
+			calls:   2,
+		},
+		"status not pending": {
+			pending: []string{"assigned"},
+			polls:   []poll{{status: "assigned"}, {status: "deleting"}},
+			summary: "unexpected status of block storage",
+			calls:   2,
+		},
+		"error": {
+			polls:   []poll{{status: "assigned"}, {err: notFound}},
+			summary: "failed to wait for block storage",
+			calls:   2,
+		},
+		"transient errors": {
+			polls:   []poll{{err: serverError}},
+			summary: "failed to wait for block storage",
+			calls:   defaultWaitMaxErrors + 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			waiter := newTestWaiter()
+			waiter.pending = test.pending
+			getStatus, calls := polls(test.polls...)
+
+			_, diags := waiter.wait(context.Background(), "id", getStatus)
+
+			if !diags.HasError() {
+				t.Fatalf("expected an error")
+			}
+			if summary := diags.Errors()[0].Summary(); summary != test.summary {
+				t.Errorf("summary: got %q, want %q", summary, test.summary)
+			}
+			if *calls != test.calls {
+				t.Errorf("calls: got %d, want %d", *calls, test.calls)
+			}
+		})
+	}
+}
+
+func TestStatusWaiterRidesOutTransientErrors(t *testing.T) {
+	waiter := newTestWaiter()
+	getStatus, _ := polls(
+		poll{err: errors.New("connection reset")},
+		poll{err: api.NewAPIError(http.StatusBadGateway, "bad_gateway", "", nil)},
+		poll{status: "prepared"},
+	)
+
+	status, diags := waiter.wait(context.Background(), "id", getStatus)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if status != "prepared" {
+		t.Errorf("status: got %s, want prepared", status)
+	}
+}
+
+func TestStatusWaiterMinSuccesses(t *testing.T) {
+	waiter := newTestWaiter()
+	waiter.minSuccesses = 2
+	getStatus, calls := polls(
+		poll{status: "prepared"},
+		poll{status: "assigned"},
+		poll{status: "prepared"},
+		poll{status: "prepared"},
+	)
+
+	_, diags := waiter.wait(context.Background(), "id", getStatus)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+	if *calls != 4 {
+		t.Errorf("calls: got %d, want 4", *calls)
+	}
+}
+
+func TestStatusWaiterTargetNotFound(t *testing.T) {
+	waiter := newTestWaiter()
+	waiter.targetNotFound = true
+	getStatus, _ := polls(
+		poll{status: "deleting"},
+		poll{err: api.NewAPIError(http.StatusNotFound, "not_found", "", nil)},
+	)
+
+	_, diags := waiter.wait(context.Background(), "id", getStatus)
+
+	if diags.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+}
+
+func TestStatusWaiterTimesOut(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	waiter := newTestWaiter()
+	getStatus, _ := polls(poll{status: "assigned"})
+
+	_, diags := waiter.wait(ctx, "id", getStatus)
+
+	if !diags.HasError() {
+		t.Fatalf("expected an error")
+	}
+	if summary := diags.Errors()[0].Summary(); summary != "operation timed out" {
+		t.Errorf("summary: got %q, want %q", summary, "operation timed out")
+	}
+	if detail := diags.Errors()[0].Detail(); !strings.Contains(detail, "last status: assigned") {
+		t.Errorf("detail does not mention the last status: %s", detail)
+	}
+}
+
+func TestStatusWaiterDescribesResource(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	waiter := newTestWaiter()
+	waiter.describe = func() string { return "assigned at noon" }
+	getStatus, _ := polls(poll{status: "assigned"})
+
+	_, diags := waiter.wait(ctx, "id", getStatus)
+
+	if !diags.HasError() {
+		t.Fatalf("expected an error")
+	}
+	if detail := diags.Errors()[0].Detail(); !strings.Contains(detail, "assigned at noon") {
+		t.Errorf("detail does not describe the resource: %s", detail)
+	}
+}
+
+func TestStatusWaiterCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	waiter := newTestWaiter()
+	waiter.minInterval = time.Hour
+	waiter.maxInterval = time.Hour
+	getStatus, _ := polls(poll{status: "assigned"})
+
+	startedAt := time.Now()
+	_, diags := waiter.wait(ctx, "id", getStatus)
+
+	if elapsed := time.Since(startedAt); elapsed > time.Second {
+		t.Errorf("returned %s after the cancellation", elapsed)
+	}
+	if !diags.HasError() {
+		t.Fatalf("expected an error")
+	}
+	if summary := diags.Errors()[0].Summary(); summary != "operation cancelled" {
+		t.Errorf("summary: got %q, want %q", summary, "operation cancelled")
+	}
+}